@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/bundle"
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/logging"
+)
+
+// cacheExportOptions 为 `any-hub cache export` 的解析结果。
+type cacheExportOptions struct {
+	configPath string
+	hub        string
+	since      time.Time
+	output     string
+}
+
+func parseCacheExportFlags(args []string) (cacheExportOptions, error) {
+	fs := flag.NewFlagSet("any-hub cache export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		configFlag string
+		sinceFlag  string
+		opts       cacheExportOptions
+	)
+	fs.StringVar(&configFlag, "config", "", "配置文件路径（默认 ./config.toml，可被 ANY_HUB_CONFIG 覆盖）")
+	fs.StringVar(&opts.hub, "hub", "", "仅导出指定 Hub 的条目")
+	fs.StringVar(&sinceFlag, "since", "", "仅导出该时间之后取得或确认的条目（2006-01-02 或 RFC3339）")
+	fs.StringVar(&opts.output, "o", "", "输出文件路径（tar.zst）")
+
+	if err := fs.Parse(args); err != nil {
+		return cacheExportOptions{}, fmt.Errorf("解析参数失败: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return cacheExportOptions{}, fmt.Errorf("解析参数失败: 多余的参数 %v", fs.Args())
+	}
+	if opts.output == "" {
+		return cacheExportOptions{}, errors.New("用法: any-hub cache export [--config path] [--hub name] [--since 2026-01-01] -o bundle.tar.zst")
+	}
+	if sinceFlag != "" {
+		since, err := parseSince(sinceFlag)
+		if err != nil {
+			return cacheExportOptions{}, fmt.Errorf("解析参数失败: --since %w", err)
+		}
+		opts.since = since
+	}
+	opts.configPath = resolveConfigPath(configFlag)
+	return opts, nil
+}
+
+// parseSince 接受日期（按 UTC 零点）或 RFC3339 时间。
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无法解析时间 %q", value)
+	}
+	return t, nil
+}
+
+// runCacheExport 将磁盘缓存导出为 bundle；与 gc 相同，不打开服务持有的 index.db，可在服务运行时执行。
+func runCacheExport(opts cacheExportOptions) int {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	if cfg.Global.StorageBackend == config.StorageBackendS3 {
+		fmt.Fprintln(stdErr, "S3 后端暂不支持导出，请使用对象存储自身的复制工具")
+		return 1
+	}
+	if opts.hub != "" && !hubConfigured(cfg, opts.hub) {
+		fmt.Fprintf(stdErr, "未配置的 Hub: %s\n", opts.hub)
+		return 1
+	}
+
+	store, err := cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{Layouts: cfg.HubDiskLayouts()})
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
+		return 1
+	}
+	file, err := os.Create(opts.output)
+	if err != nil {
+		fmt.Fprintf(stdErr, "创建输出文件失败: %v\n", err)
+		return 1
+	}
+	manifest, err := bundle.Export(context.Background(), store, file, bundle.ExportOptions{
+		Hub:   opts.hub,
+		Since: opts.since,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(opts.output)
+		fmt.Fprintf(stdErr, "导出缓存失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdOut, "cache export: entries=%d bytes=%d -> %s\n", len(manifest.Entries), manifest.TotalBytes(), opts.output)
+	return 0
+}
+
+// cacheImportOptions 为 `any-hub cache import` 的解析结果。
+type cacheImportOptions struct {
+	configPath string
+	verifyOnly bool
+	input      string
+}
+
+func parseCacheImportFlags(args []string) (cacheImportOptions, error) {
+	fs := flag.NewFlagSet("any-hub cache import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		configFlag string
+		opts       cacheImportOptions
+	)
+	fs.StringVar(&configFlag, "config", "", "配置文件路径（默认 ./config.toml，可被 ANY_HUB_CONFIG 覆盖）")
+	fs.BoolVar(&opts.verifyOnly, "verify-only", false, "仅校验 bundle 与清单是否一致，不写入缓存")
+
+	if err := fs.Parse(args); err != nil {
+		return cacheImportOptions{}, fmt.Errorf("解析参数失败: %w", err)
+	}
+	if fs.NArg() != 1 {
+		return cacheImportOptions{}, errors.New("用法: any-hub cache import [--config path] [--verify-only] bundle.tar.zst")
+	}
+	opts.input = fs.Arg(0)
+	opts.configPath = resolveConfigPath(configFlag)
+	return opts, nil
+}
+
+// runCacheImport 先完整校验 bundle，全部通过后再逐条经 Store.Put 写入；任何条目校验失败都不会导入。
+func runCacheImport(opts cacheImportOptions) int {
+	file, err := os.Open(opts.input)
+	if err != nil {
+		fmt.Fprintf(stdErr, "打开 bundle 失败: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	manifest, err := bundle.Verify(file)
+	if err != nil {
+		fmt.Fprintf(stdErr, "bundle 校验失败: %v\n", err)
+		return 1
+	}
+	if opts.verifyOnly {
+		fmt.Fprintf(stdOut, "cache import (verify-only): entries=%d bytes=%d ok\n", len(manifest.Entries), manifest.TotalBytes())
+		return 0
+	}
+
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	if missing := unconfiguredHubs(cfg, manifest); len(missing) > 0 {
+		fmt.Fprintf(stdErr, "bundle 包含未配置的 Hub: %v\n", missing)
+		return 1
+	}
+	logger, err := logging.InitLogger(cfg.Global)
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+	store, err := openCommandStore(cfg, logger, "cache_import")
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
+		return 1
+	}
+	if indexed, ok := store.(cache.Indexed); ok && indexed.Index() != nil {
+		defer indexed.Index().Close()
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		fmt.Fprintf(stdErr, "读取 bundle 失败: %v\n", err)
+		return 1
+	}
+	summary, err := bundle.Import(context.Background(), store, file, manifest)
+	if err != nil {
+		fmt.Fprintf(stdErr, "导入缓存失败（已导入 %d 条）: %v\n", summary.Entries, err)
+		return 1
+	}
+	fmt.Fprintf(stdOut, "cache import: entries=%d bytes=%d\n", summary.Entries, summary.Bytes)
+	return 0
+}
+
+func hubConfigured(cfg *config.Config, name string) bool {
+	for _, hub := range cfg.Hubs {
+		if hub.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unconfiguredHubs 返回 bundle 中出现但当前配置没有声明的 Hub 名称。
+func unconfiguredHubs(cfg *config.Config, manifest bundle.Manifest) []string {
+	seen := make(map[string]struct{})
+	var missing []string
+	for _, entry := range manifest.Entries {
+		if _, ok := seen[entry.Hub]; ok {
+			continue
+		}
+		seen[entry.Hub] = struct{}{}
+		if !hubConfigured(cfg, entry.Hub) {
+			missing = append(missing, entry.Hub)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/hubmodule"
+	"github.com/any-hub/any-hub/internal/logging"
+)
+
+// runCacheCommand 处理 `any-hub cache <操作>`。
+func runCacheCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stdErr, "用法: any-hub cache gc|export|import|migrate [选项]")
+		return 2
+	}
+	switch args[0] {
+	case "gc":
+		opts, err := parseCacheGCFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(stdErr, err.Error())
+			return 2
+		}
+		return runCacheGC(opts)
+	case "export":
+		opts, err := parseCacheExportFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(stdErr, err.Error())
+			return 2
+		}
+		return runCacheExport(opts)
+	case "import":
+		opts, err := parseCacheImportFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(stdErr, err.Error())
+			return 2
+		}
+		return runCacheImport(opts)
+	case "migrate":
+		opts, err := parseCacheMigrateFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(stdErr, err.Error())
+			return 2
+		}
+		return runCacheMigrate(opts)
+	default:
+		fmt.Fprintf(stdErr, "未知的 cache 子命令: %s\n", args[0])
+		return 2
+	}
+}
+
+// cacheGCOptions 为 `any-hub cache gc` 的解析结果。
+type cacheGCOptions struct {
+	configPath    string
+	dryRun        bool
+	tempMaxAge    time.Duration
+	partialMaxAge time.Duration
+}
+
+func parseCacheGCFlags(args []string) (cacheGCOptions, error) {
+	fs := flag.NewFlagSet("any-hub cache gc", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		configFlag string
+		opts       cacheGCOptions
+	)
+	fs.StringVar(&configFlag, "config", "", "配置文件路径（默认 ./config.toml，可被 ANY_HUB_CONFIG 覆盖）")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "仅列出将被清理的文件，不做删除")
+	fs.DurationVar(&opts.tempMaxAge, "temp-max-age", time.Hour, "临时文件超过该时长才视为遗留")
+	fs.DurationVar(&opts.partialMaxAge, "partial-max-age", 24*time.Hour, "中断下载保留的前缀文件超过该时长后删除，不再等待续传")
+
+	if err := fs.Parse(args); err != nil {
+		return cacheGCOptions{}, fmt.Errorf("解析参数失败: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return cacheGCOptions{}, fmt.Errorf("解析参数失败: 多余的参数 %v", fs.Args())
+	}
+	opts.configPath = resolveConfigPath(configFlag)
+	return opts, nil
+}
+
+// runCacheGC 对 StoragePath 执行一次回收并在 stdout 输出汇总；可与运行中的服务并行执行，
+// 因此不打开 index.db（其文件锁由服务进程持有）。
+func runCacheGC(opts cacheGCOptions) int {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	if cfg.Global.StorageBackend == config.StorageBackendS3 {
+		fmt.Fprintln(stdErr, "S3 后端没有本地缓存文件，无需回收")
+		return 1
+	}
+
+	logger, err := logging.InitLogger(cfg.Global)
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+
+	store, err := cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{Layouts: cfg.HubDiskLayouts()})
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
+		return 1
+	}
+	collector, err := cache.NewCollector(store, cache.GCOptions{
+		TempMaxAge:    opts.tempMaxAge,
+		PartialMaxAge: opts.partialMaxAge,
+		DryRun:        opts.dryRun,
+	}, logger)
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存回收器失败: %v\n", err)
+		return 1
+	}
+
+	report, err := collector.Collect(context.Background())
+	if err != nil {
+		fmt.Fprintf(stdErr, "缓存回收失败: %v\n", err)
+		return 1
+	}
+	mode := "removed"
+	if opts.dryRun {
+		mode = "dry-run"
+	}
+	fmt.Fprintf(stdOut, "cache gc (%s): %s\n", mode, report)
+	return 0
+}
+
+// cacheMigrateOptions 为 `any-hub cache migrate` 的解析结果。
+type cacheMigrateOptions struct {
+	configPath string
+	hub        string
+	dryRun     bool
+}
+
+func parseCacheMigrateFlags(args []string) (cacheMigrateOptions, error) {
+	fs := flag.NewFlagSet("any-hub cache migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		configFlag string
+		opts       cacheMigrateOptions
+	)
+	fs.StringVar(&configFlag, "config", "", "配置文件路径（默认 ./config.toml，可被 ANY_HUB_CONFIG 覆盖）")
+	fs.StringVar(&opts.hub, "hub", "", "仅迁移指定 Hub（默认迁移所有 DiskLayout=hashed 的 Hub）")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "仅列出将被迁移的条目，不移动文件")
+
+	if err := fs.Parse(args); err != nil {
+		return cacheMigrateOptions{}, fmt.Errorf("解析参数失败: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return cacheMigrateOptions{}, fmt.Errorf("解析参数失败: 多余的参数 %v", fs.Args())
+	}
+	opts.configPath = resolveConfigPath(configFlag)
+	return opts, nil
+}
+
+// runCacheMigrate 将 DiskLayout=hashed 的 Hub 下残留的 raw_path 条目移动到 hashed 路径。服务切换到 hashed 布局后
+// 会回退读取旧路径，因此迁移可与运行中的服务并行执行；与 gc 相同，不打开服务持有的 index.db。
+func runCacheMigrate(opts cacheMigrateOptions) int {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	if cfg.Global.StorageBackend == config.StorageBackendS3 {
+		fmt.Fprintln(stdErr, "S3 后端不使用磁盘布局，无需迁移")
+		return 1
+	}
+	if opts.hub != "" {
+		if !hubConfigured(cfg, opts.hub) {
+			fmt.Fprintf(stdErr, "未配置的 Hub: %s\n", opts.hub)
+			return 1
+		}
+		if cfg.HubDiskLayouts()[opts.hub] != hubmodule.DiskLayoutHashed {
+			fmt.Fprintf(stdErr, "Hub %s 未配置 DiskLayout = \"hashed\"，请先修改配置并重启服务\n", opts.hub)
+			return 1
+		}
+	}
+
+	logger, err := logging.InitLogger(cfg.Global)
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+	store, err := cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{Layouts: cfg.HubDiskLayouts()})
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
+		return 1
+	}
+
+	report, err := cache.MigrateLayout(context.Background(), store, cache.MigrateOptions{
+		Hub:    opts.hub,
+		DryRun: opts.dryRun,
+	}, logger)
+	if err != nil {
+		fmt.Fprintf(stdErr, "缓存迁移失败: %v\n", err)
+		return 1
+	}
+	mode := "moved"
+	if opts.dryRun {
+		mode = "dry-run"
+	}
+	fmt.Fprintf(stdOut, "cache migrate (%s): %s\n", mode, report)
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+func TestExportVerifyImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newStore(t)
+	old := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Now().UTC()
+	putEntry(t, source, "docker", "/v2/library/demo/manifests/1.0", "manifest", cache.ResponseMetadata{
+		ETag:        `"m1"`,
+		ContentType: "application/vnd.docker.distribution.manifest.v2+json",
+		ValidatedAt: recent,
+	})
+	putEntry(t, source, "docker", "/v2/library/demo/blobs/sha256:old", "old-layer", cache.ResponseMetadata{ValidatedAt: old})
+	putEntry(t, source, "npm", "/lodash", "packument", cache.ResponseMetadata{ValidatedAt: recent})
+
+	var buf bytes.Buffer
+	manifest, err := Export(ctx, source, &buf, ExportOptions{Hub: "docker", Since: recent.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+	if len(manifest.Entries) != 1 || manifest.Entries[0].Path != "/v2/library/demo/manifests/1.0" {
+		t.Fatalf("expected only the recent docker entry, got %+v", manifest.Entries)
+	}
+
+	verified, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	target := newStore(t)
+	summary, err := Import(ctx, target, bytes.NewReader(buf.Bytes()), verified)
+	if err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	if summary.Entries != 1 || summary.Bytes != int64(len("manifest")) {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	result, err := target.Get(ctx, cache.Locator{HubName: "docker", Path: "/v2/library/demo/manifests/1.0"})
+	if err != nil {
+		t.Fatalf("imported entry missing: %v", err)
+	}
+	defer result.Reader.Close()
+	body, _ := io.ReadAll(result.Reader)
+	if string(body) != "manifest" || result.Entry.Response.ETag != `"m1"` ||
+		!result.Entry.Response.ValidatedAt.Equal(recent) {
+		t.Fatalf("imported entry lost body or metadata: %q %+v", body, result.Entry.Response)
+	}
+}
+
+func TestVerifyDetectsTamperedBody(t *testing.T) {
+	ctx := context.Background()
+	source := newStore(t)
+	putEntry(t, source, "npm", "/lodash", "packument", cache.ResponseMetadata{})
+
+	var buf bytes.Buffer
+	if _, err := Export(ctx, source, &buf, ExportOptions{}); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+	tampered := rewriteBundle(t, buf.Bytes(), func(name string, data []byte) []byte {
+		if name == "entries/000001" {
+			return []byte("PACKUMENT")
+		}
+		return data
+	})
+	if _, err := Verify(bytes.NewReader(tampered)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	// 跳过 Verify 直接导入时，Put 的校验器同样拒绝被篡改的正文。
+	manifest, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	target := newStore(t)
+	if _, err := Import(ctx, target, bytes.NewReader(tampered), manifest); !errors.Is(err, cache.ErrIntegrityMismatch) {
+		t.Fatalf("expected integrity mismatch on import, got %v", err)
+	}
+	if _, err := target.Get(ctx, cache.Locator{HubName: "npm", Path: "/lodash"}); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("tampered entry must not be committed: %v", err)
+	}
+}
+
+func TestImportRejectsEscapingHubName(t *testing.T) {
+	manifest := Manifest{Version: FormatVersion, Entries: []ManifestEntry{{Name: "entries/000001", Hub: "..", Path: "/x"}}}
+	if _, err := Import(context.Background(), newStore(t), bytes.NewReader(nil), manifest); err == nil {
+		t.Fatalf("expected invalid hub name to be rejected")
+	}
+}
+
+func TestImportRejectsReservedHubNames(t *testing.T) {
+	for _, hub := range []string{cache.BlobsDirName, cache.IndexFileName} {
+		manifest := Manifest{Version: FormatVersion, Entries: []ManifestEntry{{Name: "entries/000001", Hub: hub, Path: "/x"}}}
+		if _, err := Import(context.Background(), newStore(t), bytes.NewReader(nil), manifest); err == nil {
+			t.Fatalf("expected reserved hub name %q to be rejected", hub)
+		}
+	}
+}
+
+func newStore(t *testing.T) cache.Store {
+	t.Helper()
+	store, err := cache.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	return store
+}
+
+func putEntry(t *testing.T, store cache.Store, hub, path, body string, response cache.ResponseMetadata) {
+	t.Helper()
+	_, err := store.Put(context.Background(), cache.Locator{HubName: hub, Path: path}, strings.NewReader(body), cache.PutOptions{Response: response})
+	if err != nil {
+		t.Fatalf("put %s%s: %v", hub, path, err)
+	}
+}
+
+// rewriteBundle 解开 bundle 并按 fn 替换成员内容后重新打包（大小随之更新）。
+func rewriteBundle(t *testing.T, data []byte, fn func(name string, data []byte) []byte) []byte {
+	t.Helper()
+	decoder, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("zstd reader: %v", err)
+	}
+	defer decoder.Close()
+	var out bytes.Buffer
+	encoder, _ := zstd.NewWriter(&out)
+	reader := tar.NewReader(decoder)
+	writer := tar.NewWriter(encoder)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		content = fn(header.Name, content)
+		header.Size = int64(len(content))
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		writer.Write(content)
+	}
+	writer.Close()
+	encoder.Close()
+	return out.Bytes()
+}
@@ -0,0 +1,186 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+const (
+	// FormatVersion 为当前 bundle 格式版本，Import 拒绝更高版本。
+	FormatVersion = 1
+	// ManifestName 为 bundle 末尾的清单文件名。
+	ManifestName = "manifest.json"
+
+	entriesDir   = "entries/"
+	sidecarExt   = ".json"
+	archiveMode  = 0o644
+	entryNameFmt = entriesDir + "%06d"
+)
+
+// Manifest 描述 bundle 的来源与每个条目的校验和。
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Hub/Since 记录导出时的筛选条件，仅供查看。
+	Hub     string          `json:"hub,omitempty"`
+	Since   time.Time       `json:"since,omitzero"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry 对应 bundle 中的一个缓存条目：<Name> 为正文，<Name>.json 为元数据旁路文件。
+type ManifestEntry struct {
+	Name          string `json:"name"`
+	Hub           string `json:"hub"`
+	Path          string `json:"path"`
+	SizeBytes     int64  `json:"size_bytes"`
+	SHA256        string `json:"sha256"`
+	SidecarSHA256 string `json:"sidecar_sha256"`
+}
+
+// TotalBytes 返回清单中全部正文的字节数。
+func (m Manifest) TotalBytes() int64 {
+	var total int64
+	for _, entry := range m.Entries {
+		total += entry.SizeBytes
+	}
+	return total
+}
+
+// ExportOptions 控制导出范围。
+type ExportOptions struct {
+	// Hub 非空时只导出该 Hub 的条目。
+	Hub string
+	// Since 非零时只导出最近一次从上游取得或确认时间不早于该时刻的条目。
+	Since time.Time
+}
+
+// Export 将 store 中符合条件的条目写入 w（tar + zstd），返回写入的清单。
+// 导出期间被删除的条目会被跳过；正文通过 Get 打开的句柄读取，并发覆盖写入不会产生半截内容。
+func Export(ctx context.Context, store cache.Store, w io.Writer, opts ExportOptions) (Manifest, error) {
+	var locators []cache.Locator
+	err := cache.Walk(ctx, store, opts.Hub, func(locator cache.Locator) error {
+		locators = append(locators, locator)
+		return nil
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	encoder, err := zstd.NewWriter(w)
+	if err != nil {
+		return Manifest{}, err
+	}
+	// 出错提前返回时释放编码器的后台 goroutine；正常路径下方显式 Close 以检查错误。
+	defer encoder.Close()
+	archive := tar.NewWriter(encoder)
+	manifest := Manifest{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Hub:       opts.Hub,
+		Since:     opts.Since,
+		Entries:   []ManifestEntry{},
+	}
+	for _, locator := range locators {
+		if err := ctx.Err(); err != nil {
+			return Manifest{}, err
+		}
+		entry, ok, err := exportEntry(ctx, store, archive, locator, opts.Since, len(manifest.Entries)+1)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("export %s%s: %w", locator.HubName, locator.Path, err)
+		}
+		if ok {
+			manifest.Entries = append(manifest.Entries, entry)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := writeFile(archive, ManifestName, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return Manifest{}, err
+	}
+	if err := archive.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if err := encoder.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// exportEntry 写入单个条目的旁路文件与正文；条目已不存在或早于 since 时返回 ok=false。
+func exportEntry(ctx context.Context, store cache.Store, archive *tar.Writer, locator cache.Locator, since time.Time, seq int) (ManifestEntry, bool, error) {
+	result, err := store.Get(ctx, locator)
+	if errors.Is(err, cache.ErrNotFound) {
+		return ManifestEntry{}, false, nil
+	}
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	defer result.Reader.Close()
+	entry := result.Entry
+	if !since.IsZero() && entry.LastValidated().Before(since) {
+		return ManifestEntry{}, false, nil
+	}
+
+	sidecar := entry
+	sidecar.FilePath = ""
+	meta, err := json.Marshal(sidecar)
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	name := fmt.Sprintf(entryNameFmt, seq)
+	modTime := entry.ModTime
+	sidecarSum, err := writeFile(archive, name+sidecarExt, int64(len(meta)), modTime, bytes.NewReader(meta))
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	bodySum, err := writeFile(archive, name, entry.SizeBytes, modTime, result.Reader)
+	if err != nil {
+		return ManifestEntry{}, false, err
+	}
+	return ManifestEntry{
+		Name:          name,
+		Hub:           locator.HubName,
+		Path:          locator.Path,
+		SizeBytes:     entry.SizeBytes,
+		SHA256:        bodySum,
+		SidecarSHA256: sidecarSum,
+	}, true, nil
+}
+
+// writeFile 写入一个 tar 成员并返回其 sha256（hex）；实际长度与 size 不符时报错。
+func writeFile(archive *tar.Writer, name string, size int64, modTime time.Time, body io.Reader) (string, error) {
+	if err := archive.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     archiveMode,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	written, err := io.Copy(archive, io.TeeReader(io.LimitReader(body, size), hash))
+	if err != nil {
+		return "", err
+	}
+	if written != size {
+		return "", fmt.Errorf("short body: wrote %d of %d bytes", written, size)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,212 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+// maxSidecarSize 限制清单与旁路文件的读取大小，避免损坏的 bundle 占满内存。
+const maxSidecarSize = 64 << 20
+
+// ImportSummary 汇总一次导入。
+type ImportSummary struct {
+	Entries int
+	Bytes   int64
+}
+
+// Verify 完整读取 bundle，核对每个成员的大小与 sha256 是否与清单一致、是否存在缺失或多余的成员，
+// 通过时返回清单。不会写入任何 Store。
+func Verify(r io.Reader) (Manifest, error) {
+	archive, closeArchive, err := openArchive(r)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer closeArchive()
+
+	type member struct {
+		size int64
+		sum  string
+	}
+	members := make(map[string]member)
+	var (
+		manifest     Manifest
+		haveManifest bool
+	)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("read bundle: %w", err)
+		}
+		if header.Name == ManifestName {
+			if manifest, err = readManifest(archive); err != nil {
+				return Manifest{}, err
+			}
+			haveManifest = true
+			continue
+		}
+		hash := sha256.New()
+		size, err := io.Copy(hash, archive)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("read %s: %w", header.Name, err)
+		}
+		members[header.Name] = member{size: size, sum: hex.EncodeToString(hash.Sum(nil))}
+	}
+	if !haveManifest {
+		return Manifest{}, fmt.Errorf("bundle has no %s", ManifestName)
+	}
+
+	var problems []error
+	for _, entry := range manifest.Entries {
+		body, ok := members[entry.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Errorf("%s (%s%s): body missing", entry.Name, entry.Hub, entry.Path))
+		case body.size != entry.SizeBytes || body.sum != entry.SHA256:
+			problems = append(problems, fmt.Errorf("%s (%s%s): checksum mismatch", entry.Name, entry.Hub, entry.Path))
+		}
+		sidecar, ok := members[entry.Name+sidecarExt]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Errorf("%s (%s%s): sidecar missing", entry.Name, entry.Hub, entry.Path))
+		case sidecar.sum != entry.SidecarSHA256:
+			problems = append(problems, fmt.Errorf("%s (%s%s): sidecar checksum mismatch", entry.Name, entry.Hub, entry.Path))
+		}
+		delete(members, entry.Name)
+		delete(members, entry.Name+sidecarExt)
+	}
+	for name := range members {
+		problems = append(problems, fmt.Errorf("%s: not listed in manifest", name))
+	}
+	if len(problems) > 0 {
+		return Manifest{}, errors.Join(problems...)
+	}
+	return manifest, nil
+}
+
+// Import 将 bundle 中的条目逐个经 store.Put 写入，沿用 Store 的原子提交与条目锁；正文在写入时按清单中的
+// sha256 校验，不一致的条目不会提交。manifest 应来自对同一 bundle 的 Verify。
+func Import(ctx context.Context, store cache.Store, r io.Reader, manifest Manifest) (ImportSummary, error) {
+	expected := make(map[string]ManifestEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		if err := validateHubName(entry.Hub); err != nil {
+			return ImportSummary{}, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		expected[entry.Name] = entry
+	}
+
+	archive, closeArchive, err := openArchive(r)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	defer closeArchive()
+
+	var summary ImportSummary
+	sidecars := make(map[string]cache.Entry)
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, fmt.Errorf("read bundle: %w", err)
+		}
+		if header.Name == ManifestName {
+			continue
+		}
+		if name, ok := strings.CutSuffix(header.Name, sidecarExt); ok {
+			if _, listed := expected[name]; !listed {
+				return summary, fmt.Errorf("%s: not listed in manifest", header.Name)
+			}
+			var sidecar cache.Entry
+			if err := json.NewDecoder(io.LimitReader(archive, maxSidecarSize)).Decode(&sidecar); err != nil {
+				return summary, fmt.Errorf("decode %s: %w", header.Name, err)
+			}
+			sidecars[name] = sidecar
+			continue
+		}
+
+		entry, ok := expected[header.Name]
+		if !ok {
+			return summary, fmt.Errorf("%s: not listed in manifest", header.Name)
+		}
+		sidecar, ok := sidecars[header.Name]
+		if !ok {
+			return summary, fmt.Errorf("%s: sidecar must precede body", header.Name)
+		}
+		delete(sidecars, header.Name)
+		if err := importEntry(ctx, store, archive, entry, sidecar); err != nil {
+			return summary, fmt.Errorf("import %s%s: %w", entry.Hub, entry.Path, err)
+		}
+		summary.Entries++
+		summary.Bytes += entry.SizeBytes
+	}
+}
+
+func importEntry(ctx context.Context, store cache.Store, body io.Reader, entry ManifestEntry, sidecar cache.Entry) error {
+	verifier, err := cache.NewVerifier(cache.IntegritySHA256, entry.SHA256)
+	if err != nil {
+		return err
+	}
+	locator := cache.Locator{HubName: entry.Hub, Path: entry.Path}
+	written, err := store.Put(ctx, locator, body, cache.PutOptions{
+		ModTime:               sidecar.ModTime,
+		EffectiveUpstreamPath: sidecar.EffectiveUpstreamPath,
+		Digest:                sidecar.Digest,
+		Verifier:              verifier,
+		Response:              sidecar.Response,
+	})
+	if err != nil {
+		return err
+	}
+	if written.SizeBytes != entry.SizeBytes {
+		return fmt.Errorf("size mismatch: wrote %d of %d bytes", written.SizeBytes, entry.SizeBytes)
+	}
+	return nil
+}
+
+func openArchive(r io.Reader) (*tar.Reader, func(), error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open bundle: %w", err)
+	}
+	return tar.NewReader(decoder), decoder.Close, nil
+}
+
+func readManifest(r io.Reader) (Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(r, maxSidecarSize)).Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("decode %s: %w", ManifestName, err)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return Manifest{}, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// validateHubName 拒绝可能逃逸出缓存根目录或与共享目录、索引文件同名的 Hub 名称。
+func validateHubName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid hub name %q", name)
+	}
+	if cache.ReservedHubName(name) {
+		return fmt.Errorf("hub name %q is reserved by the cache layout", name)
+	}
+	return nil
+}
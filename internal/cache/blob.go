@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobsDirName 是 StoragePath 下的内容寻址目录名，布局为 blobs/sha256/<hex>。
+// Hub 目录与其同级，因此 Hub 名称不得使用该值。
+const BlobsDirName = "blobs"
+
+// ReservedHubName 报告 name 是否与 StoragePath 下的共享目录或索引文件同名，此类名称不能作为 Hub 名称。
+func ReservedHubName(name string) bool {
+	return name == BlobsDirName || name == IndexFileName
+}
+
+// inodeKey 唯一标识一个物理文件，硬链接到同一 blob 的条目共享该值。
+type inodeKey struct {
+	dev uint64
+	ino uint64
+}
+
+// parseSHA256Digest 解析 "sha256:<hex>" 形式的摘要，返回小写 hex；格式不符时返回 false。
+func parseSHA256Digest(digest string) (string, bool) {
+	algo, hexValue, ok := strings.Cut(strings.TrimSpace(digest), ":")
+	if !ok || !strings.EqualFold(algo, "sha256") || len(hexValue) != 64 {
+		return "", false
+	}
+	hexValue = strings.ToLower(hexValue)
+	for _, r := range hexValue {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", false
+		}
+	}
+	return hexValue, true
+}
+
+func (s *fileStore) blobPath(hexValue string) string {
+	return filepath.Join(s.basePath, BlobsDirName, "sha256", hexValue)
+}
+
+// blobLocator 为 blob 生成独立的锁键，与 Hub 条目的锁互不干扰。
+func blobLocator(hexValue string) Locator {
+	return Locator{HubName: BlobsDirName, Path: hexValue}
+}
+
+// commitBlob 将 tempName 提交为共享 blob（已存在时直接复用），再以硬链接的形式
+// 原子地出现在 filePath。调用方需持有 filePath 对应条目的锁。
+func (s *fileStore) commitBlob(tempName, hexValue, filePath string) error {
+	unlock, err := s.lockEntry(blobLocator(hexValue))
+	if err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	defer unlock()
+
+	blob := s.blobPath(hexValue)
+	if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	switch _, err := os.Stat(blob); {
+	case err == nil:
+		// 相同摘要的正文已被其他 Hub 写入，丢弃本次下载的副本。
+		_ = os.Remove(tempName)
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.Rename(tempName, blob); err != nil {
+			_ = os.Remove(tempName)
+			return err
+		}
+	default:
+		_ = os.Remove(tempName)
+		return err
+	}
+	return linkInto(blob, filePath)
+}
+
+// releaseBlob 在没有任何 Hub 条目引用 blob（硬链接数仅剩自身）时删除它。
+func (s *fileStore) releaseBlob(hexValue string) error {
+	unlock, err := s.lockEntry(blobLocator(hexValue))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	blob := s.blobPath(hexValue)
+	info, err := os.Stat(blob)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if _, links, ok := fileIdentity(info); !ok || links > 1 {
+		return nil
+	}
+	if err := os.Remove(blob); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// linkInto 通过“临时硬链接 + rename”让 dst 原子地指向 src；文件系统不支持硬链接时退化为复制。
+func linkInto(src, dst string) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(dst), ".cache-link-*")
+	if err != nil {
+		return err
+	}
+	tempName := tempFile.Name()
+	tempFile.Close()
+	if err := os.Remove(tempName); err != nil {
+		return err
+	}
+	if err := os.Link(src, tempName); err != nil {
+		if err := copyFile(src, tempName); err != nil {
+			_ = os.Remove(tempName)
+			return err
+		}
+	}
+	if err := os.Rename(tempName, dst); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
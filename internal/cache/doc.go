@@ -6,5 +6,17 @@
 // with safe semantics (temp file + rename) and surfaces file info (size, modtime)
 // for higher layers to implement conditional revalidation. Proxy handlers depend
 // on this package to stream cached responses or trigger upstream fetches without
-// duplicating filesystem logic.
+// duplicating filesystem logic. Immutable bodies with a known sha256 digest are
+// stored once under StoragePath/blobs/sha256/<hex> and hard-linked into each hub
+// path; the link count doubles as the reference count when entries are removed.
+// PutOptions.Verifier checks the body while it streams to the temp file, and
+// entries whose digest does not match are discarded instead of committed.
+// NewS3Store provides the same Store contract on an S3-compatible bucket so that
+// several instances can share one cache; multipart uploads only become visible
+// on completion, and entry metadata travels in the object's user metadata.
+// With StoreOptions.Index the disk store also keeps a bbolt index (index.db)
+// in step with Put/Remove, so hits, eviction and stats skip stat/.meta reads.
+// Hubs listed as hashed in StoreOptions.Layouts store bodies at
+// <hub>/.h/<aa>/<bb>/<sha256(path)> and record the original path in .meta;
+// MigrateLayout moves leftover raw_path entries there while reads fall back.
 package cache
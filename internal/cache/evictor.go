@@ -0,0 +1,400 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLowWatermark     = 0.9
+	defaultEvictionInterval = time.Minute
+)
+
+// EvictionOptions 描述磁盘配额与淘汰节奏。上限均以字节计，0 表示不限制。
+type EvictionOptions struct {
+	// GlobalLimit 约束整个 StoragePath 的用量。
+	GlobalLimit int64
+	// HubLimits 约束单个 Hub 的用量，未出现的 Hub 仅受 GlobalLimit 约束。
+	HubLimits map[string]int64
+	// LowWatermark 为超限后需要回落到的比例（相对上限），默认 0.9，避免频繁抖动。
+	LowWatermark float64
+	// Interval 为后台扫描周期，默认 1 分钟。
+	Interval time.Duration
+	// Retention 中 Pinned 规则匹配的条目与已固定的条目不会被淘汰。
+	Retention *RetentionPolicy
+}
+
+// EvictionReport 汇总一次扫描的结果，便于日志输出与测试断言。
+type EvictionReport struct {
+	UsageBytes int64
+	Evicted    int
+	FreedBytes int64
+	// Skipped 记录因正在写入或扫描后被再次访问而跳过的条目数。
+	Skipped int
+}
+
+// Evictor 周期性扫描磁盘缓存，在超出配额时先删除中断下载保留的前缀文件，再按最近最少使用顺序删除正文与 .meta。
+// 删除前通过 entryLock 的 TryLock 确认条目未被写入，绝不删除正在 Put 的文件；固定的条目始终保留。
+type Evictor struct {
+	store *fileStore
+	// memory 为叠加在磁盘之上的内存层，淘汰正文后同步使其副本失效。
+	memory *memoryStore
+	opts   EvictionOptions
+	logger *logrus.Logger
+}
+
+// diskEntry 是扫描磁盘得到的条目快照。
+type diskEntry struct {
+	locator    Locator
+	filePath   string
+	size       int64
+	accessTime time.Time
+	modTime    time.Time
+	// cachedAt 为 .meta 记录的写入时间，旧条目为零值。
+	cachedAt time.Time
+	// fileModTime/fileID 为正文文件自身的属性，重建索引时写入记录，见 IndexRecord.matchesFile。
+	fileModTime time.Time
+	fileID      string
+	// identity/shared 标识硬链接到共享 blob 的条目，全局用量按物理文件去重计算。
+	identity string
+	shared   bool
+	// indexed 表示快照来自索引而非目录扫描，淘汰前以索引记录确认条目未被再次访问。
+	indexed bool
+	// partial 表示中断下载保留的前缀文件（filePath 指向 .partial-*），计入用量并最先淘汰。
+	partial bool
+}
+
+// NewEvictor 基于磁盘 Store 构建淘汰器；其他 Store 实现不支持按目录扫描。
+func NewEvictor(store Store, opts EvictionOptions, logger *logrus.Logger) (*Evictor, error) {
+	memory, layered := store.(*memoryStore)
+	if layered {
+		store = memory.backend
+	}
+	fsStore, ok := store.(*fileStore)
+	if !ok {
+		return nil, errors.New("evictor requires filesystem store")
+	}
+	if opts.LowWatermark <= 0 || opts.LowWatermark > 1 {
+		opts.LowWatermark = defaultLowWatermark
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultEvictionInterval
+	}
+	return &Evictor{store: fsStore, memory: memory, opts: opts, logger: logger}, nil
+}
+
+// Enabled 表示是否配置了任何磁盘上限。
+func (e *Evictor) Enabled() bool {
+	if e == nil {
+		return false
+	}
+	if e.opts.GlobalLimit > 0 {
+		return true
+	}
+	for _, limit := range e.opts.HubLimits {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Run 立即执行一次扫描，之后按 Interval 周期执行，直到 ctx 结束。
+func (e *Evictor) Run(ctx context.Context) {
+	if !e.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+	for {
+		e.sweepAndLog(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Evictor) sweepAndLog(ctx context.Context) {
+	report, err := e.Sweep(ctx)
+	if e.logger == nil {
+		return
+	}
+	fields := logrus.Fields{
+		"action":      "cache_evict",
+		"usage_bytes": report.UsageBytes,
+		"evicted":     report.Evicted,
+		"freed_bytes": report.FreedBytes,
+		"skipped":     report.Skipped,
+	}
+	if err != nil {
+		e.logger.WithFields(fields).WithError(err).Warn("cache_evict_failed")
+		return
+	}
+	if report.Evicted > 0 || report.Skipped > 0 {
+		e.logger.WithFields(fields).Info("cache_evict_complete")
+	}
+}
+
+// Sweep 扫描一次磁盘：先处理超限的 Hub，再处理全局上限，均回落到低水位后停止。
+func (e *Evictor) Sweep(ctx context.Context) (EvictionReport, error) {
+	var report EvictionReport
+	var entries []diskEntry
+	var err error
+	if e.store.index != nil {
+		entries, err = e.store.indexEntries(ctx)
+	} else {
+		entries, err = e.store.scanEntries(ctx)
+	}
+	if err != nil {
+		return report, err
+	}
+	partials, err := e.store.partialEntries(ctx)
+	if err != nil {
+		return report, err
+	}
+	entries = append(entries, partials...)
+	// 前缀文件只在同一条目再次回源时才有用，先于任何完整正文淘汰。
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].partial != entries[j].partial {
+			return entries[i].partial
+		}
+		return entries[i].accessTime.Before(entries[j].accessTime)
+	})
+
+	// Hub 用量按各自引用的正文计算；全局用量按物理文件计算，共享 blob 只计一次，
+	// 且只有最后一个引用被淘汰时才真正释放空间。
+	hubUsage := make(map[string]int64)
+	inodeRefs := make(map[string]int)
+	var total int64
+	for _, entry := range entries {
+		hubUsage[entry.locator.HubName] += entry.size
+		if entry.shared {
+			inodeRefs[entry.identity]++
+			if inodeRefs[entry.identity] > 1 {
+				continue
+			}
+		}
+		total += entry.size
+	}
+	release := func(entry diskEntry) {
+		if entry.shared {
+			inodeRefs[entry.identity]--
+			if inodeRefs[entry.identity] > 0 {
+				return
+			}
+		}
+		total -= entry.size
+	}
+
+	removed := make([]bool, len(entries))
+	for hub, limit := range e.opts.HubLimits {
+		if limit <= 0 || hubUsage[hub] <= limit {
+			continue
+		}
+		target := e.lowWater(limit)
+		for i, entry := range entries {
+			if hubUsage[hub] <= target {
+				break
+			}
+			if removed[i] || entry.locator.HubName != hub {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				report.UsageBytes = total
+				return report, err
+			}
+			if e.evict(entry, &report) {
+				removed[i] = true
+				hubUsage[hub] -= entry.size
+				release(entry)
+			}
+		}
+	}
+
+	if limit := e.opts.GlobalLimit; limit > 0 && total > limit {
+		target := e.lowWater(limit)
+		for i, entry := range entries {
+			if total <= target {
+				break
+			}
+			if removed[i] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				report.UsageBytes = total
+				return report, err
+			}
+			if e.evict(entry, &report) {
+				removed[i] = true
+				release(entry)
+			}
+		}
+	}
+
+	report.UsageBytes = total
+	return report, nil
+}
+
+func (e *Evictor) lowWater(limit int64) int64 {
+	return int64(float64(limit) * e.opts.LowWatermark)
+}
+
+// evict 在持有条目锁的前提下删除文件；扫描后被再次访问的条目视为热点，予以保留。
+func (e *Evictor) evict(entry diskEntry, report *EvictionReport) bool {
+	if entry.partial {
+		removed, _ := e.store.removeStalePartial(entry.locator, entry.filePath, entry.modTime)
+		if !removed {
+			report.Skipped++
+			return false
+		}
+		report.Evicted++
+		report.FreedBytes += entry.size
+		return true
+	}
+	unlock, ok := e.store.tryLockEntry(entry.locator)
+	if !ok {
+		report.Skipped++
+		return false
+	}
+	defer unlock()
+
+	if e.opts.Retention.Pinned(entry.locator) {
+		return false
+	}
+	if entry.indexed {
+		record, err := e.store.index.Lookup(entry.locator)
+		if err != nil || record.Pinned {
+			return false
+		}
+		if record.LastAccess.After(entry.accessTime) || !record.ModTime.Equal(entry.modTime) {
+			report.Skipped++
+			return false
+		}
+	} else {
+		info, err := os.Stat(entry.filePath)
+		if err != nil {
+			return false
+		}
+		metadata, _ := e.store.readMetadata(entry.filePath)
+		if metadata.Pinned {
+			return false
+		}
+		if modTime, lastAccess := entryTimes(info, metadata); lastAccess.After(entry.accessTime) || !modTime.Equal(entry.modTime) {
+			report.Skipped++
+			return false
+		}
+	}
+	if err := e.store.removeEntry(entry.locator); err != nil {
+		return false
+	}
+	if e.memory != nil {
+		e.memory.invalidate(entry.locator)
+	}
+	report.Evicted++
+	report.FreedBytes += entry.size
+	return true
+}
+
+// scanEntries 遍历 <basePath>/<hub>/ 下的正文文件，忽略临时文件、.meta 旁路文件与共享 blob 目录。
+func (s *fileStore) scanEntries(ctx context.Context) ([]diskEntry, error) {
+	hubs, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, err
+	}
+	var entries []diskEntry
+	for _, hubDir := range hubs {
+		if !hubDir.IsDir() || hubDir.Name() == BlobsDirName {
+			continue
+		}
+		hubName := hubDir.Name()
+		hubRoot := filepath.Join(s.basePath, hubName)
+		err := filepath.WalkDir(hubRoot, func(filePath string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				if errors.Is(walkErr, fs.ErrNotExist) {
+					return nil
+				}
+				return walkErr
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() || !isEntryFileName(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			locator, ok := s.locatorForFile(filePath)
+			if !ok {
+				return nil
+			}
+			inode, links, known := fileIdentity(info)
+			metadata, _ := s.readMetadata(filePath)
+			modTime, lastAccess := entryTimes(info, metadata)
+			fileModTime, fileID := fileStamp(info)
+			entries = append(entries, diskEntry{
+				locator:     locator,
+				filePath:    filePath,
+				size:        info.Size(),
+				accessTime:  lastAccess,
+				modTime:     modTime,
+				cachedAt:    metadata.CachedAt,
+				fileModTime: fileModTime,
+				fileID:      fileID,
+				identity:    fmt.Sprintf("%d:%d", inode.dev, inode.ino),
+				shared:      known && links > 1,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// indexEntries 从索引生成淘汰快照，无需遍历目录；共享 blob 以摘要识别同一物理文件。
+func (s *fileStore) indexEntries(ctx context.Context) ([]diskEntry, error) {
+	var entries []diskEntry
+	err := s.index.List(ctx, "", func(record IndexRecord) error {
+		filePath, err := s.entryPath(record.Locator)
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, diskEntry{
+			locator:    record.Locator,
+			filePath:   filePath,
+			size:       record.SizeBytes,
+			accessTime: record.LastAccess,
+			modTime:    record.ModTime,
+			identity:   record.Digest,
+			shared:     record.Digest != "",
+			indexed:    true,
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// isEntryFileName 判断文件名是否为缓存正文（排除 .cache-* 临时文件与 .meta 旁路文件）。
+func isEntryFileName(name string) bool {
+	if strings.HasPrefix(name, ".cache-") || strings.HasPrefix(name, partialPrefix) {
+		return false
+	}
+	return !strings.HasSuffix(name, ".meta")
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvictorRemovesLeastRecentlyUsedEntries(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now().Add(-time.Hour)
+	oldest := putWithAccessTime(t, store, Locator{HubName: "npm", Path: "/a.tgz"}, "aaaa", base)
+	putWithAccessTime(t, store, Locator{HubName: "npm", Path: "/b.tgz"}, "bbbb", base.Add(time.Minute))
+	putWithAccessTime(t, store, Locator{HubName: "npm", Path: "/c.tgz"}, "cccc", base.Add(2*time.Minute))
+
+	// 命中 a 后它应成为最新访问的条目。
+	if err := store.(AccessRecorder).Touch(context.Background(), oldest); err != nil {
+		t.Fatalf("touch error: %v", err)
+	}
+
+	evictor, err := NewEvictor(store, EvictionOptions{HubLimits: map[string]int64{"npm": 10}}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	report, err := evictor.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if report.Evicted != 1 || report.FreedBytes != 4 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := store.Get(context.Background(), Locator{HubName: "npm", Path: "/b.tgz"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected least recently used entry to be evicted, got %v", err)
+	}
+	for _, p := range []string{"/a.tgz", "/c.tgz"} {
+		result, err := store.Get(context.Background(), Locator{HubName: "npm", Path: p})
+		if err != nil {
+			t.Fatalf("expected %s to survive eviction: %v", p, err)
+		}
+		result.Reader.Close()
+	}
+}
+
+func TestEvictorRemovesMetadataSidecar(t *testing.T) {
+	store := newTestStore(t)
+	locator := Locator{HubName: "docker", Path: "/v2/demo/manifests/latest"}
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("manifest"), PutOptions{
+		EffectiveUpstreamPath: "/v2/library/demo/manifests/latest",
+	}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	filePath, _ := store.(*fileStore).entryPath(locator)
+
+	evictor, err := NewEvictor(store, EvictionOptions{GlobalLimit: 1}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	if _, err := evictor.Sweep(context.Background()); err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if _, err := os.Stat(metadataPath(filePath)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected metadata sidecar removed, got %v", err)
+	}
+}
+
+func TestEvictorSkipsEntriesBeingWritten(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now().Add(-time.Hour)
+	busy := putWithAccessTime(t, store, Locator{HubName: "go", Path: "/busy.zip"}, "busy", base)
+	putWithAccessTime(t, store, Locator{HubName: "go", Path: "/idle.zip"}, "idle", base.Add(time.Minute))
+
+	unlock, err := store.(*fileStore).lockEntry(busy)
+	if err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+	defer unlock()
+
+	evictor, err := NewEvictor(store, EvictionOptions{GlobalLimit: 5}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	report, err := evictor.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if report.Skipped != 1 || report.Evicted != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	filePath, _ := store.(*fileStore).entryPath(busy)
+	if _, err := os.Stat(filePath); err != nil {
+		t.Fatalf("locked entry must not be removed: %v", err)
+	}
+}
+
+func TestEvictorCountsSharedBlobsOnce(t *testing.T) {
+	store := newTestStore(t)
+	digest := sha256Digest("12345678")
+	for _, hub := range []string{"docker", "quay"} {
+		if _, err := store.Put(context.Background(), Locator{HubName: hub, Path: "/v2/x/blobs/" + digest}, strings.NewReader("12345678"), PutOptions{Digest: digest}); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+	}
+
+	evictor, err := NewEvictor(store, EvictionOptions{GlobalLimit: 10}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	report, err := evictor.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if report.Evicted != 0 || report.UsageBytes != 8 {
+		t.Fatalf("shared blob should count once against the global limit: %+v", report)
+	}
+}
+
+func TestEvictorDisabledWithoutLimits(t *testing.T) {
+	evictor, err := NewEvictor(newTestStore(t), EvictionOptions{}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	if evictor.Enabled() {
+		t.Fatalf("evictor without limits should be disabled")
+	}
+}
+
+// putWithAccessTime 写入条目并将其 atime 固定为 at，便于构造确定的 LRU 顺序。
+func TestEvictorKeepsPinnedEntries(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now().Add(-time.Hour)
+	pinned := putWithAccessTime(t, store, Locator{HubName: "docker", Path: "/golang.tar"}, "aaaa", base)
+	ruled := putWithAccessTime(t, store, Locator{HubName: "docker", Path: "/base/alpine.tar"}, "bbbb", base.Add(time.Minute))
+	putWithAccessTime(t, store, Locator{HubName: "docker", Path: "/nightly.tar"}, "cccc", base.Add(2*time.Minute))
+	if err := store.(Pinner).Pin(context.Background(), pinned, true); err != nil {
+		t.Fatalf("pin error: %v", err)
+	}
+
+	evictor, err := NewEvictor(store, EvictionOptions{
+		GlobalLimit: 4,
+		Retention:   NewRetentionPolicy([]RetentionRule{{Hub: "docker", Match: "/base/*", Pinned: true}}),
+	}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	report, err := evictor.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if report.Evicted != 1 {
+		t.Fatalf("only the unpinned entry should be evicted: %+v", report)
+	}
+	for _, locator := range []Locator{pinned, ruled} {
+		result, err := store.Get(context.Background(), locator)
+		if err != nil {
+			t.Fatalf("pinned %s should survive eviction: %v", locator.Path, err)
+		}
+		result.Reader.Close()
+	}
+}
+
+func putWithAccessTime(t *testing.T, store Store, locator Locator, body string, at time.Time) Locator {
+	t.Helper()
+	entry, err := store.Put(context.Background(), locator, strings.NewReader(body), PutOptions{ModTime: at})
+	if err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if err := os.Chtimes(entry.FilePath, at, at); err != nil {
+		t.Fatalf("chtimes error: %v", err)
+	}
+	return locator
+}
+
+func TestEvictorCountsAndEvictsPartialsFirst(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		store := newTestStore(t)
+		if indexed {
+			store = newIndexedStore(t, t.TempDir())
+		}
+		ctx := context.Background()
+		complete := putWithAccessTime(t, store, Locator{HubName: "docker", Path: "/v2/x/blobs/old"}, "aaaa", time.Now().Add(-time.Hour))
+		interrupted := Locator{HubName: "docker", Path: "/v2/x/blobs/layer"}
+		broken := io.MultiReader(strings.NewReader("01234567"), iotestErrReader{})
+		if _, err := store.Put(ctx, interrupted, broken, PutOptions{KeepPartial: true}); err == nil {
+			t.Fatalf("expected interrupted put to fail")
+		}
+
+		evictor, err := NewEvictor(store, EvictionOptions{HubLimits: map[string]int64{"docker": 10}}, nil)
+		if err != nil {
+			t.Fatalf("evictor error: %v", err)
+		}
+		report, err := evictor.Sweep(ctx)
+		if err != nil {
+			t.Fatalf("sweep error: %v", err)
+		}
+		if report.Evicted != 1 || report.FreedBytes != 8 || report.UsageBytes != 4 {
+			t.Fatalf("indexed=%v: partial should count toward usage and be evicted first, got %+v", indexed, report)
+		}
+		if _, err := store.(PartialStore).Partial(ctx, interrupted); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("indexed=%v: expected partial to be evicted, got %v", indexed, err)
+		}
+		result, err := store.Get(ctx, complete)
+		if err != nil {
+			t.Fatalf("indexed=%v: complete entry should survive: %v", indexed, err)
+		}
+		result.Reader.Close()
+		if partials, _ := store.(*fileStore).partialEntries(ctx); len(partials) != 0 {
+			t.Fatalf("indexed=%v: expected no partial left, got %+v", indexed, partials)
+		}
+	}
+}
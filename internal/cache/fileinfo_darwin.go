@@ -0,0 +1,25 @@
+//go:build darwin
+
+package cache
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime 读取文件 atime；Touch 通过 Chtimes 显式写入，因此不依赖挂载参数。
+func accessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
+	}
+	return info.ModTime()
+}
+
+// fileIdentity 返回 (设备, inode) 标识与硬链接数，用于识别共享同一 blob 的条目。
+func fileIdentity(info os.FileInfo) (inodeKey, uint64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return inodeKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, uint64(stat.Nlink), true
+	}
+	return inodeKey{}, 0, false
+}
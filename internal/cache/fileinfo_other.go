@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package cache
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime 在无法读取 atime 的平台上退化为 ModTime。
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+// fileIdentity 在无法读取 inode 的平台上返回 false，调用方按独立文件处理。
+func fileIdentity(info os.FileInfo) (inodeKey, uint64, bool) {
+	return inodeKey{}, 0, false
+}
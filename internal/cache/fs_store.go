@@ -18,6 +18,20 @@ import (
 
 // NewStore 以 basePath 为根目录构建磁盘缓存，整站复用一份实例。
 func NewStore(basePath string) (Store, error) {
+	return NewStoreWithOptions(basePath, StoreOptions{})
+}
+
+// StoreOptions 控制磁盘缓存的可选能力。
+type StoreOptions struct {
+	// Index 为 true 时在 <basePath>/index.db 维护嵌入式索引：Get 直接从索引取得大小与元数据，
+	// 淘汰、列举与统计无需扫描目录。新建的索引会先从磁盘重建一次。
+	Index bool
+	// Layouts 按 Hub 名称指定磁盘布局（hubmodule.DiskLayoutRawPath/DiskLayoutHashed），未列出的 Hub 使用 raw_path。
+	Layouts map[string]string
+}
+
+// NewStoreWithOptions 与 NewStore 相同，但允许启用索引等可选能力。
+func NewStoreWithOptions(basePath string, opts StoreOptions) (Store, error) {
 	if basePath == "" {
 		return nil, errors.New("storage path required")
 	}
@@ -31,15 +45,37 @@ func NewStore(basePath string) (Store, error) {
 		return nil, fmt.Errorf("create storage path: %w", err)
 	}
 
-	return &fileStore{
+	store := &fileStore{
 		basePath: abs,
+		layouts:  make(map[string]string, len(opts.Layouts)),
 		locks:    make(map[string]*entryLock),
-	}, nil
+	}
+	for hub, layout := range opts.Layouts {
+		store.layouts[hub] = layout
+	}
+	if opts.Index {
+		index, err := OpenIndex(filepath.Join(abs, IndexFileName))
+		if err != nil {
+			return nil, err
+		}
+		store.index = index
+		if !index.Built() {
+			if _, err := RebuildIndex(context.Background(), store); err != nil {
+				index.Close()
+				return nil, fmt.Errorf("rebuild cache index: %w", err)
+			}
+		}
+	}
+	return store, nil
 }
 
 // fileStore 通过 entryLock 避免同一 Locator 并发写入，同时复用 basePath。
 type fileStore struct {
 	basePath string
+	// index 为可选的嵌入式索引，与正文、.meta 同步更新。
+	index *Index
+	// layouts 记录各 Hub 的磁盘布局，见 StoreOptions.Layouts。
+	layouts map[string]string
 
 	mu    sync.Mutex
 	locks map[string]*entryLock
@@ -51,7 +87,50 @@ type entryLock struct {
 }
 
 type entryMetadata struct {
-	EffectiveUpstreamPath string `json:"effective_upstream_path,omitempty"`
+	// Path 为 hashed 布局下条目的原始 Locator 路径，扫描目录时据此还原 Locator。
+	Path                  string            `json:"path,omitempty"`
+	EffectiveUpstreamPath string            `json:"effective_upstream_path,omitempty"`
+	Digest                string            `json:"digest,omitempty"`
+	Response              *ResponseMetadata `json:"response,omitempty"`
+	Pinned                bool              `json:"pinned,omitempty"`
+	// ModTime 为条目的修改时间（上游 Last-Modified 语义）。共享 blob 的 inode 被多个 Hub 引用，
+	// 其时间戳不属于任何单个条目，因此按条目记录在 .meta 中；缺失时（旧条目）回退到文件 mtime。
+	ModTime time.Time `json:"mod_time,omitzero"`
+	// LastAccess 为未启用索引时共享 blob 条目的最近访问时间，见 Touch。
+	LastAccess time.Time `json:"last_access,omitzero"`
+	// CachedAt 为正文写入缓存的时间，供保留策略的 MaxAge/MaxVersions 使用；
+	// 文件 mtime 被设置为上游 Last-Modified，不能用来近似。
+	CachedAt time.Time `json:"cached_at,omitzero"`
+}
+
+func (m entryMetadata) isZero() bool {
+	return m.Path == "" && m.EffectiveUpstreamPath == "" && m.Digest == "" && (m.Response == nil || m.Response.IsZero()) && !m.Pinned &&
+		m.ModTime.IsZero() && m.LastAccess.IsZero() && m.CachedAt.IsZero()
+}
+
+// shared 表示正文硬链接到 blobs/sha256 下的共享 blob（Put 只为共享正文记录 Digest）。
+func (m entryMetadata) shared() bool {
+	return m.Digest != ""
+}
+
+// entryTimes 返回条目自身的修改时间与最近访问时间：优先取 .meta 中按条目记录的值，旧条目回退到文件属性。
+// 共享 blob 的 inode 时间被所有引用方共用，不用于任何单个条目的访问时间。
+func entryTimes(info os.FileInfo, metadata entryMetadata) (time.Time, time.Time) {
+	modTime := info.ModTime()
+	if !metadata.ModTime.IsZero() {
+		modTime = metadata.ModTime
+	}
+	lastAccess := accessTime(info)
+	if metadata.shared() {
+		lastAccess = metadata.LastAccess
+		if lastAccess.IsZero() {
+			lastAccess = metadata.CachedAt
+		}
+		if lastAccess.IsZero() {
+			lastAccess = modTime
+		}
+	}
+	return modTime, lastAccess
 }
 
 func (s *fileStore) Get(ctx context.Context, locator Locator) (*ReadResult, error) {
@@ -61,43 +140,97 @@ func (s *fileStore) Get(ctx context.Context, locator Locator) (*ReadResult, erro
 	default:
 	}
 
-	filePath, err := s.entryPath(locator)
+	filePath, err := s.locate(locator)
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := os.Stat(filePath)
+	if s.index != nil {
+		if result, err := s.getIndexed(locator, filePath); !errors.Is(err, ErrNotFound) {
+			return result, err
+		}
+	}
+
+	return s.readFromDisk(locator, filePath)
+}
+
+// readFromDisk 打开正文并按文件属性与 .meta 组装条目；启用索引时以此补录或刷新记录。
+func (s *fileStore) readFromDisk(locator Locator, filePath string) (*ReadResult, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) || isNotDirError(err) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
 	if info.IsDir() {
+		file.Close()
 		return nil, ErrNotFound
 	}
 
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		file.Close()
+		return nil, err
+	}
+	modTime, lastAccess := entryTimes(info, metadata)
+	entry := Entry{
+		Locator:               locator,
+		FilePath:              filePath,
+		SizeBytes:             info.Size(),
+		ModTime:               modTime,
+		EffectiveUpstreamPath: metadata.EffectiveUpstreamPath,
+		Digest:                metadata.Digest,
+		Pinned:                metadata.Pinned,
+		CachedAt:              metadata.CachedAt,
+	}
+	if metadata.Response != nil {
+		entry.Response = *metadata.Response
+	}
+	if s.index != nil {
+		// 索引缺失该条目（例如写入索引前进程退出）或记录已过期：按磁盘状态补录。
+		_ = s.index.put(indexRecordFromEntry(entry, lastAccess, info))
+	}
+	return &ReadResult{Entry: entry, Reader: file}, nil
+}
+
+// getIndexed 依据索引记录直接打开正文，省去 .meta 读取；记录存在但正文已不在磁盘时清理记录并返回
+// ErrNotFound，由调用方回退到磁盘路径。打开的文件与记录的大小、mtime、inode 不一致时记录作废：
+// 正文被未持有索引的命令（cache import、prefetch、cache migrate）改写，或正处于 Put 重命名正文
+// 与更新记录之间。此时等待进行中的写入结束，再按文件与 .meta 重建记录。
+func (s *fileStore) getIndexed(locator Locator, filePath string) (*ReadResult, error) {
+	record, err := s.index.Lookup(locator)
+	if err != nil {
+		return nil, err
+	}
 	file, err := os.Open(filePath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) || isNotDirError(err) {
+			_ = s.index.remove(locator)
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	if info, err := file.Stat(); err == nil && record.matchesFile(info) {
+		return &ReadResult{Entry: record.entry(filePath), Reader: file}, nil
+	}
+	file.Close()
 
-	entry := Entry{
-		Locator:   locator,
-		FilePath:  filePath,
-		SizeBytes: info.Size(),
-		ModTime:   info.ModTime(),
-	}
-	if metadata, err := s.readMetadata(filePath); err == nil {
-		entry.EffectiveUpstreamPath = metadata.EffectiveUpstreamPath
-	} else if !errors.Is(err, fs.ErrNotExist) {
-		file.Close()
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
 		return nil, err
 	}
-	return &ReadResult{Entry: entry, Reader: file}, nil
+	defer unlock()
+	result, err := s.readFromDisk(locator, filePath)
+	if errors.Is(err, ErrNotFound) {
+		_ = s.index.remove(locator)
+	}
+	return result, err
 }
 
 func (s *fileStore) Put(ctx context.Context, locator Locator, body io.Reader, opts PutOptions) (*Entry, error) {
@@ -116,23 +249,65 @@ func (s *fileStore) Put(ctx context.Context, locator Locator, body io.Reader, op
 		return nil, err
 	}
 
-	tempFile, err := os.CreateTemp(filepath.Dir(filePath), ".cache-*")
+	var tempFile *os.File
+	if opts.ResumeFrom > 0 {
+		tempFile, err = s.reopenPartial(locator, filePath, opts.ResumeFrom)
+	} else {
+		tempFile, err = os.CreateTemp(filepath.Dir(filePath), ".cache-*")
+	}
 	if err != nil {
 		return nil, err
 	}
 	tempName := tempFile.Name()
 
-	written, err := copyWithContext(ctx, tempFile, body)
+	digestHex, shared := parseSHA256Digest(opts.Digest)
+	verifier := opts.Verifier
+	if verifier == nil && shared {
+		verifier, _ = NewVerifier(IntegritySHA256, digestHex)
+	}
+	if err := feedPartial(tempFile, opts.ResumeFrom, verifier); err != nil {
+		tempFile.Close()
+		_ = os.Remove(tempName)
+		return nil, err
+	}
+	var dst io.Writer = tempFile
+	if verifier != nil {
+		dst = io.MultiWriter(tempFile, verifier)
+	}
+
+	copied, err := copyWithContext(ctx, dst, body)
+	written := opts.ResumeFrom + copied
 	closeErr := tempFile.Close()
 	if err == nil {
 		err = closeErr
 	}
+	if err != nil && opts.KeepPartial && written > 0 {
+		// 回源中断：保留已下载的前缀，下次回源从断点续传。
+		if keepErr := s.keepPartial(locator, tempName, filePath, opts.Response); keepErr != nil {
+			_ = os.Remove(tempName)
+		}
+		return nil, err
+	}
+	if err == nil && verifier != nil {
+		err = verifier.Verify(tempName)
+	}
 	if err != nil {
 		_ = os.Remove(tempName)
 		return nil, err
 	}
+	if err := removePartial(filePath); err != nil {
+		_ = os.Remove(tempName)
+		return nil, err
+	}
+	s.forgetPartial(locator)
 
-	if err := os.Rename(tempName, filePath); err != nil {
+	previous, _ := s.readMetadata(filePath)
+	if shared {
+		// 已知摘要的不可变正文写入 blobs/sha256/<hex>，Hub 路径以硬链接引用，跨 Hub 去重。
+		if err := s.commitBlob(tempName, digestHex, filePath); err != nil {
+			return nil, err
+		}
+	} else if err := os.Rename(tempName, filePath); err != nil {
 		_ = os.Remove(tempName)
 		return nil, err
 	}
@@ -141,12 +316,59 @@ func (s *fileStore) Put(ctx context.Context, locator Locator, body io.Reader, op
 	if modTime.IsZero() {
 		modTime = time.Now().UTC()
 	}
-	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
-		return nil, err
+	if !shared {
+		// 共享 blob 的 inode 属于所有引用方，不能用本条目的时间覆盖；其时间只记录在 .meta 中。
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			return nil, err
+		}
 	}
-	if err := s.writeMetadata(filePath, opts.EffectiveUpstreamPath); err != nil {
+	now := time.Now().UTC()
+	metadata := entryMetadata{EffectiveUpstreamPath: opts.EffectiveUpstreamPath, ModTime: modTime, CachedAt: now}
+	if s.hashed(locator.HubName) {
+		metadata.Path = canonicalPath(locator.Path)
+	}
+	// 固定状态属于条目而不是某次回源结果，覆盖写入时沿用。
+	metadata.Pinned = previous.Pinned
+	if !opts.Response.IsZero() {
+		response := opts.Response
+		metadata.Response = &response
+	}
+	if shared {
+		metadata.Digest = "sha256:" + digestHex
+	}
+	if err := s.writeMetadata(filePath, metadata); err != nil {
 		return nil, err
 	}
+	if s.index != nil {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, err
+		}
+		record := IndexRecord{
+			Locator:    locator,
+			SizeBytes:  written,
+			ModTime:    modTime,
+			CreatedAt:  now,
+			LastAccess: now,
+		}
+		record.FileModTime, record.FileID = fileStamp(info)
+		record.applyMetadata(metadata)
+		if err := s.index.put(record); err != nil {
+			return nil, err
+		}
+	}
+	// 覆盖写入后旧正文可能是某个 blob 的最后一个引用，需要同步释放。
+	if oldHex, ok := parseSHA256Digest(previous.Digest); ok && oldHex != digestHex {
+		if err := s.releaseBlob(oldHex); err != nil {
+			return nil, err
+		}
+	}
+	// 迁移到 hashed 布局前写入的旧副本已被新正文取代。
+	if legacy := s.legacyPath(locator); legacy != "" {
+		if err := s.removeFiles(legacy); err != nil {
+			return nil, err
+		}
+	}
 
 	entry := Entry{
 		Locator:               locator,
@@ -154,6 +376,10 @@ func (s *fileStore) Put(ctx context.Context, locator Locator, body io.Reader, op
 		SizeBytes:             written,
 		ModTime:               modTime,
 		EffectiveUpstreamPath: opts.EffectiveUpstreamPath,
+		Digest:                metadata.Digest,
+		Response:              opts.Response,
+		Pinned:                metadata.Pinned,
+		CachedAt:              now,
 	}
 	return &entry, nil
 }
@@ -165,16 +391,196 @@ func (s *fileStore) Remove(ctx context.Context, locator Locator) error {
 	}
 	defer unlock()
 
+	return s.removeEntry(locator)
+}
+
+// RemoveUnpinned 与 Remove 相同，但先在条目锁内读取 .meta 的固定状态，避免与并发的 Pin 交错。
+func (s *fileStore) RemoveUnpinned(ctx context.Context, locator Locator) error {
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := s.locate(locator)
+	if err != nil {
+		return err
+	}
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if metadata.Pinned {
+		return ErrPinned
+	}
+	return s.removeEntry(locator)
+}
+
+// UpdateResponse 仅改写条目的响应元数据，正文与其他元数据保持不变。
+func (s *fileStore) UpdateResponse(ctx context.Context, locator Locator, response ResponseMetadata) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := s.locate(locator)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return ErrNotFound
+	}
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	metadata.Response = nil
+	if !response.IsZero() {
+		metadata.Response = &response
+	}
+	if err := s.writeMetadata(filePath, metadata); err != nil {
+		return err
+	}
+	if s.index != nil {
+		err := s.index.update(locator, func(record *IndexRecord) {
+			record.Response = metadata.Response
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pin 改写条目 .meta 中的固定状态，并同步到索引。
+func (s *fileStore) Pin(ctx context.Context, locator Locator, pinned bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := s.locate(locator)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return ErrNotFound
+	}
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	metadata.Pinned = pinned
+	if err := s.writeMetadata(filePath, metadata); err != nil {
+		return err
+	}
+	if s.index != nil {
+		err := s.index.update(locator, func(record *IndexRecord) {
+			record.Pinned = pinned
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataTouchInterval 为共享 blob 条目在 .meta 中刷新访问时间的最小间隔，避免每次命中都重写 .meta。
+const metadataTouchInterval = time.Minute
+
+// Touch 将条目的访问时间更新为当前时间，同时保留 ModTime（其语义为上游 Last-Modified）。
+// 启用索引时只在索引中记录访问时间与命中次数，不再改写文件 atime；
+// 未启用索引时，共享 blob 条目的访问时间写入自己的 .meta，其余条目改写文件 atime。
+func (s *fileStore) Touch(ctx context.Context, locator Locator) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if s.index != nil {
+		s.index.touch(locator, now)
+		return nil
+	}
+	filePath, err := s.locate(locator)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || isNotDirError(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if !metadata.shared() {
+		return os.Chtimes(filePath, now, info.ModTime())
+	}
+	if now.Sub(metadata.LastAccess) < metadataTouchInterval {
+		return nil
+	}
+	// 条目正被写入时跳过：新的写入本身就会刷新条目。
+	unlock, ok := s.tryLockEntry(locator)
+	if !ok {
+		return nil
+	}
+	defer unlock()
+	metadata, err = s.readMetadata(filePath)
+	if err != nil {
+		return err
+	}
+	metadata.LastAccess = now
+	return s.writeMetadata(filePath, metadata)
+}
+
+// Index 返回磁盘缓存的嵌入式索引，未启用时为 nil。
+func (s *fileStore) Index() *Index {
+	return s.index
+}
+
+// removeEntry 删除条目在当前布局（以及迁移前 raw_path 布局）下的正文与 .meta 旁路文件，
+// 并在正文引用共享 blob 时释放引用。调用方需持有对应条目的锁。
+func (s *fileStore) removeEntry(locator Locator) error {
+	if s.index != nil {
+		if err := s.index.remove(locator); err != nil {
+			return err
+		}
+	}
 	filePath, err := s.entryPath(locator)
 	if err != nil {
 		return err
 	}
-	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+	if err := s.removeFiles(filePath); err != nil {
 		return err
 	}
-	if err := os.Remove(metadataPath(filePath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+	if legacy := s.legacyPath(locator); legacy != "" {
+		return s.removeFiles(legacy)
+	}
+	return nil
+}
+
+// removeFiles 删除 filePath 处的正文与 .meta，正文引用共享 blob 时释放引用。
+func (s *fileStore) removeFiles(filePath string) error {
+	metadata, _ := s.readMetadata(filePath)
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) && !isNotDirError(err) {
 		return err
 	}
+	if err := os.Remove(metadataPath(filePath)); err != nil && !errors.Is(err, fs.ErrNotExist) && !isNotDirError(err) {
+		return err
+	}
+	if digestHex, ok := parseSHA256Digest(metadata.Digest); ok {
+		return s.releaseBlob(digestHex)
+	}
 	return nil
 }
 
@@ -190,15 +596,15 @@ func (s *fileStore) readMetadata(filePath string) (entryMetadata, error) {
 	return metadata, nil
 }
 
-func (s *fileStore) writeMetadata(filePath string, effectiveUpstreamPath string) error {
+func (s *fileStore) writeMetadata(filePath string, metadata entryMetadata) error {
 	metaFilePath := metadataPath(filePath)
-	if effectiveUpstreamPath == "" {
+	if metadata.isZero() {
 		if err := os.Remove(metaFilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 		return nil
 	}
-	data, err := json.Marshal(entryMetadata{EffectiveUpstreamPath: effectiveUpstreamPath})
+	data, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
@@ -246,20 +652,48 @@ func (s *fileStore) lockEntry(locator Locator) (func(), error) {
 	}, nil
 }
 
+// tryLockEntry 与 lockEntry 相同，但条目正被写入时立即返回 false，供后台任务跳过忙碌条目。
+func (s *fileStore) tryLockEntry(locator Locator) (func(), bool) {
+	key := locatorKey(locator)
+	s.mu.Lock()
+	lock := s.locks[key]
+	if lock == nil {
+		lock = &entryLock{}
+		s.locks[key] = lock
+	}
+	if !lock.mu.TryLock() {
+		s.mu.Unlock()
+		return nil, false
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	return func() {
+		lock.mu.Unlock()
+		s.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.locks, key)
+		}
+		s.mu.Unlock()
+	}, true
+}
+
+// entryPath 返回条目在所属 Hub 布局下的正文路径。
 func (s *fileStore) entryPath(locator Locator) (string, error) {
+	if s.hashed(locator.HubName) {
+		return s.hashedPath(locator)
+	}
+	return s.rawPath(locator)
+}
+
+// rawPath 返回 raw_path 布局下的正文路径：<basePath>/<hub>/<Locator.Path>。
+func (s *fileStore) rawPath(locator Locator) (string, error) {
 	if locator.HubName == "" {
 		return "", errors.New("hub name required")
 	}
 
-	rel := locator.Path
-	if rel == "" || rel == "/" {
-		rel = "root"
-	}
-	rel = path.Clean("/" + rel)
-	rel = strings.TrimPrefix(rel, "/")
-	if rel == "" {
-		rel = "root"
-	}
+	rel := strings.TrimPrefix(canonicalPath(locator.Path), "/")
 
 	hubRoot := filepath.Join(s.basePath, locator.HubName)
 	filePath := filepath.Join(hubRoot, filepath.FromSlash(rel))
@@ -270,6 +704,19 @@ func (s *fileStore) entryPath(locator Locator) (string, error) {
 	return filePath, nil
 }
 
+// canonicalPath 规范化 Locator.Path：空路径与根路径映射为 /root，其余按 path.Clean 清理，
+// 保证同一磁盘文件只对应一个索引键。
+func canonicalPath(p string) string {
+	if p == "" || p == "/" {
+		return "/root"
+	}
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return "/root"
+	}
+	return cleaned
+}
+
 func isNotDirError(err error) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,273 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultGCTempMaxAge    = time.Hour
+	defaultGCPartialMaxAge = 24 * time.Hour
+	defaultGCInterval      = time.Hour
+)
+
+// GCOptions 控制孤立文件回收。
+type GCOptions struct {
+	// TempMaxAge 为临时文件（.cache-*）的最小存活时间，超过后视为崩溃或取消遗留，默认 1 小时。
+	// 正在写入的临时文件会持续更新 mtime，因此不会被误删。
+	TempMaxAge time.Duration
+	// PartialMaxAge 为中断下载保留的前缀文件（.partial-*）的最长保留时间，超过后不再等待续传，默认 24 小时。
+	PartialMaxAge time.Duration
+	// Interval 为后台回收周期，默认 1 小时。
+	Interval time.Duration
+	// DryRun 为 true 时只统计与记录日志，不删除任何文件。
+	DryRun bool
+}
+
+// GCReport 汇总一次回收的结果。
+type GCReport struct {
+	// StaleTemps 为超过 TempMaxAge 的 .cache-*/.cache-meta-*/.cache-link-* 临时文件数。
+	StaleTemps int
+	// StalePartials 为超过 PartialMaxAge 的 .partial-* 前缀文件数（连同其 .meta 一并删除）。
+	StalePartials int
+	// OrphanedSidecars 为正文已不存在的 .meta 旁路文件数。
+	OrphanedSidecars int
+	// OrphanedBlobs 为已无任何 Hub 引用的共享 blob 数。
+	OrphanedBlobs int
+	// Collisions 为正文路径被目录占用（文件/目录冲突）的条目数，仅报告不删除。
+	Collisions int
+	// FreedBytes 为删除（或 dry-run 时将删除）的字节数。
+	FreedBytes int64
+}
+
+// Collector 回收 fileStore 遗留的临时文件、孤立旁路文件与无引用 blob，
+// 所有动作以 action=cache_gc 记录日志。
+type Collector struct {
+	store  *fileStore
+	opts   GCOptions
+	logger *logrus.Logger
+}
+
+// NewCollector 基于磁盘 Store 构建回收器；其他 Store 实现没有可回收的本地文件。
+func NewCollector(store Store, opts GCOptions, logger *logrus.Logger) (*Collector, error) {
+	if memory, ok := store.(*memoryStore); ok {
+		store = memory.backend
+	}
+	fsStore, ok := store.(*fileStore)
+	if !ok {
+		return nil, errors.New("collector requires filesystem store")
+	}
+	if opts.TempMaxAge <= 0 {
+		opts.TempMaxAge = defaultGCTempMaxAge
+	}
+	if opts.PartialMaxAge <= 0 {
+		opts.PartialMaxAge = defaultGCPartialMaxAge
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultGCInterval
+	}
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	return &Collector{store: fsStore, opts: opts, logger: logger}, nil
+}
+
+// Run 立即执行一次回收，之后按 Interval 周期执行，直到 ctx 结束。
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		c.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect 遍历 StoragePath 执行一次回收并输出汇总日志。
+func (c *Collector) Collect(ctx context.Context) (GCReport, error) {
+	var report GCReport
+	cutoff := time.Now().Add(-c.opts.TempMaxAge)
+	partialCutoff := time.Now().Add(-c.opts.PartialMaxAge)
+	err := filepath.WalkDir(c.store.basePath, func(filePath string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if filePath == filepath.Join(c.store.basePath, IndexFileName) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		switch {
+		case strings.HasPrefix(name, ".cache-"):
+			if info.ModTime().Before(cutoff) {
+				report.StaleTemps++
+				c.remove(filePath, info, "stale_temp", &report)
+			}
+		case isPartialFileName(name):
+			if info.ModTime().Before(partialCutoff) {
+				c.checkPartial(filePath, info, &report)
+			}
+		case strings.HasSuffix(name, ".meta"):
+			c.checkSidecar(filePath, info, &report)
+		case c.isBlob(filePath):
+			c.checkBlob(filePath, info, cutoff, &report)
+		}
+		return nil
+	})
+	c.log(report, err)
+	return report, err
+}
+
+// checkSidecar 处理正文缺失的 .meta：正文位置是目录时报告冲突，正文不存在时删除旁路文件。
+func (c *Collector) checkSidecar(filePath string, info fs.FileInfo, report *GCReport) {
+	bodyPath := strings.TrimSuffix(filePath, ".meta")
+	bodyInfo, err := os.Stat(bodyPath)
+	switch {
+	case err == nil && bodyInfo.IsDir():
+		report.Collisions++
+		c.entry("collision", bodyPath, 0).Warn("cache_gc_collision")
+	case err == nil:
+		return
+	case errors.Is(err, fs.ErrNotExist):
+		locator, ok := c.store.locatorForFile(bodyPath)
+		if !ok {
+			return
+		}
+		// 与 Put/Remove 互斥：二者在正文与 .meta 之间存在短暂的不一致窗口。
+		unlock, locked := c.store.tryLockEntry(locator)
+		if !locked {
+			return
+		}
+		defer unlock()
+		if _, err := os.Stat(bodyPath); !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		report.OrphanedSidecars++
+		c.remove(filePath, info, "orphaned_sidecar", report)
+	}
+}
+
+// checkPartial 删除过期的前缀文件及其 .meta；正在续传的条目持有条目锁，予以跳过。
+func (c *Collector) checkPartial(filePath string, info fs.FileInfo, report *GCReport) {
+	locator, ok := c.store.partialLocator(filePath)
+	if !ok {
+		return
+	}
+	size := info.Size()
+	if sidecar, err := os.Stat(metadataPath(filePath)); err == nil {
+		size += sidecar.Size()
+	}
+	entry := c.entry("stale_partial", filePath, size)
+	if c.opts.DryRun {
+		report.StalePartials++
+		report.FreedBytes += size
+		entry.Info("cache_gc_candidate")
+		return
+	}
+	removed, err := c.store.removeStalePartial(locator, filePath, info.ModTime())
+	if err != nil {
+		entry.WithError(err).Warn("cache_gc_remove_failed")
+		return
+	}
+	if !removed {
+		return
+	}
+	report.StalePartials++
+	report.FreedBytes += size
+	entry.Info("cache_gc_removed")
+}
+
+// checkBlob 删除链接数为 1（只剩 blobs 目录自身引用）且已超过临时文件阈值的共享 blob。
+func (c *Collector) checkBlob(filePath string, info fs.FileInfo, cutoff time.Time, report *GCReport) {
+	_, links, known := fileIdentity(info)
+	if !known || links > 1 || !info.ModTime().Before(cutoff) {
+		return
+	}
+	unlock, locked := c.store.tryLockEntry(blobLocator(filepath.Base(filePath)))
+	if !locked {
+		return
+	}
+	defer unlock()
+	report.OrphanedBlobs++
+	c.remove(filePath, info, "orphaned_blob", report)
+}
+
+func (c *Collector) isBlob(filePath string) bool {
+	return filepath.Dir(filePath) == filepath.Join(c.store.basePath, BlobsDirName, "sha256")
+}
+
+func (c *Collector) remove(filePath string, info fs.FileInfo, kind string, report *GCReport) {
+	report.FreedBytes += info.Size()
+	entry := c.entry(kind, filePath, info.Size())
+	if c.opts.DryRun {
+		entry.Info("cache_gc_candidate")
+		return
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		entry.WithError(err).Warn("cache_gc_remove_failed")
+		return
+	}
+	entry.Info("cache_gc_removed")
+}
+
+func (c *Collector) entry(kind, filePath string, size int64) *logrus.Entry {
+	return c.logger.WithFields(logrus.Fields{
+		"action":  "cache_gc",
+		"kind":    kind,
+		"path":    filePath,
+		"bytes":   size,
+		"dry_run": c.opts.DryRun,
+	})
+}
+
+func (c *Collector) log(report GCReport, err error) {
+	fields := logrus.Fields{
+		"action":            "cache_gc",
+		"dry_run":           c.opts.DryRun,
+		"stale_temps":       report.StaleTemps,
+		"stale_partials":    report.StalePartials,
+		"orphaned_sidecars": report.OrphanedSidecars,
+		"orphaned_blobs":    report.OrphanedBlobs,
+		"collisions":        report.Collisions,
+		"freed_bytes":       report.FreedBytes,
+	}
+	if err != nil {
+		c.logger.WithFields(fields).WithError(err).Warn("cache_gc_failed")
+		return
+	}
+	c.logger.WithFields(fields).Info("cache_gc_complete")
+}
+
+// String 便于 CLI 输出一行汇总。
+func (r GCReport) String() string {
+	return fmt.Sprintf("stale_temps=%d stale_partials=%d orphaned_sidecars=%d orphaned_blobs=%d collisions=%d freed_bytes=%d",
+		r.StaleTemps, r.StalePartials, r.OrphanedSidecars, r.OrphanedBlobs, r.Collisions, r.FreedBytes)
+}
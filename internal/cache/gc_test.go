@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectorRemovesStaleTempsAndOrphanedSidecars(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	ctx := context.Background()
+
+	kept := Locator{HubName: "npm", Path: "/lodash"}
+	if _, err := store.Put(ctx, kept, strings.NewReader("packument"), PutOptions{EffectiveUpstreamPath: "/lodash"}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	orphan := Locator{HubName: "npm", Path: "/react"}
+	if _, err := store.Put(ctx, orphan, strings.NewReader("packument"), PutOptions{EffectiveUpstreamPath: "/react"}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	orphanPath, _ := fsStore.entryPath(orphan)
+	if err := os.Remove(orphanPath); err != nil {
+		t.Fatalf("remove body: %v", err)
+	}
+
+	hubDir := filepath.Join(fsStore.basePath, "npm")
+	staleTemp := writeAgedFile(t, filepath.Join(hubDir, ".cache-123"), "partial", 2*time.Hour)
+	staleMetaTemp := writeAgedFile(t, filepath.Join(hubDir, ".cache-meta-456"), "{}", 2*time.Hour)
+	freshTemp := writeAgedFile(t, filepath.Join(hubDir, ".cache-789"), "writing", 0)
+
+	collector, err := NewCollector(store, GCOptions{TempMaxAge: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("collector error: %v", err)
+	}
+	report, err := collector.Collect(ctx)
+	if err != nil {
+		t.Fatalf("collect error: %v", err)
+	}
+	if report.StaleTemps != 2 || report.OrphanedSidecars != 1 || report.Collisions != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, p := range []string{staleTemp, staleMetaTemp, metadataPath(orphanPath)} {
+		if _, err := os.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expected %s to be removed, got %v", p, err)
+		}
+	}
+	if _, err := os.Stat(freshTemp); err != nil {
+		t.Fatalf("fresh temp file should survive: %v", err)
+	}
+	result, err := store.Get(ctx, kept)
+	if err != nil {
+		t.Fatalf("expected live entry to survive: %v", err)
+	}
+	result.Reader.Close()
+	if result.Entry.EffectiveUpstreamPath != "/lodash" {
+		t.Fatalf("live sidecar should survive, got %+v", result.Entry)
+	}
+}
+
+func TestCollectorDryRunKeepsFiles(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	stale := writeAgedFile(t, filepath.Join(fsStore.basePath, "pypi", ".cache-1"), "partial", 2*time.Hour)
+
+	collector, err := NewCollector(store, GCOptions{DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("collector error: %v", err)
+	}
+	report, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect error: %v", err)
+	}
+	if report.StaleTemps != 1 || report.FreedBytes != int64(len("partial")) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("dry-run must not delete files: %v", err)
+	}
+}
+
+func TestCollectorRemovesStalePartials(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	ctx := context.Background()
+	keep := func(locator Locator) string {
+		broken := io.MultiReader(strings.NewReader("prefix"), iotestErrReader{})
+		if _, err := store.Put(ctx, locator, broken, PutOptions{KeepPartial: true, Response: ResponseMetadata{ETag: `"v1"`}}); err == nil {
+			t.Fatalf("expected interrupted put to fail")
+		}
+		filePath, _ := fsStore.entryPath(locator)
+		return partialPath(filePath)
+	}
+	stale := keep(Locator{HubName: "docker", Path: "/v2/x/blobs/old"})
+	when := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(stale, when, when); err != nil {
+		t.Fatalf("chtimes error: %v", err)
+	}
+	fresh := keep(Locator{HubName: "docker", Path: "/v2/x/blobs/new"})
+
+	collector, err := NewCollector(store, GCOptions{PartialMaxAge: 24 * time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("collector error: %v", err)
+	}
+	report, err := collector.Collect(ctx)
+	if err != nil {
+		t.Fatalf("collect error: %v", err)
+	}
+	if report.StalePartials != 1 || report.OrphanedSidecars != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, p := range []string{stale, metadataPath(stale)} {
+		if _, err := os.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expected %s to be removed, got %v", p, err)
+		}
+	}
+	for _, p := range []string{fresh, metadataPath(fresh)} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("fresh partial should survive: %v", err)
+		}
+	}
+}
+
+func TestCollectorReportsCollisions(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	ctx := context.Background()
+
+	// /v2 先以文件写入，随后被当作目录使用：模拟条目与子路径冲突后遗留的 .meta。
+	if _, err := store.Put(ctx, Locator{HubName: "docker", Path: "/v2/demo"}, strings.NewReader("x"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	sidecar := filepath.Join(fsStore.basePath, "docker", "v2.meta")
+	writeAgedFile(t, sidecar, "{}", 0)
+
+	collector, err := NewCollector(store, GCOptions{}, nil)
+	if err != nil {
+		t.Fatalf("collector error: %v", err)
+	}
+	report, err := collector.Collect(ctx)
+	if err != nil {
+		t.Fatalf("collect error: %v", err)
+	}
+	if report.Collisions != 1 || report.OrphanedSidecars != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(sidecar); err != nil {
+		t.Fatalf("collisions are only reported: %v", err)
+	}
+}
+
+func TestCollectorRemovesUnreferencedBlobs(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	orphan := writeAgedFile(t, fsStore.blobPath(strings.Repeat("a", 64)), "blob", 2*time.Hour)
+	linked := writeAgedFile(t, fsStore.blobPath(strings.Repeat("b", 64)), "blob", 2*time.Hour)
+	entryPath := filepath.Join(fsStore.basePath, "docker", "layer")
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0o755); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+	if err := os.Link(linked, entryPath); err != nil {
+		t.Fatalf("link error: %v", err)
+	}
+
+	collector, err := NewCollector(store, GCOptions{}, nil)
+	if err != nil {
+		t.Fatalf("collector error: %v", err)
+	}
+	report, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect error: %v", err)
+	}
+	if report.OrphanedBlobs != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(orphan); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected unreferenced blob to be removed, got %v", err)
+	}
+	if _, err := os.Stat(linked); err != nil {
+		t.Fatalf("linked blob should survive: %v", err)
+	}
+}
+
+func writeAgedFile(t *testing.T, filePath, content string, age time.Duration) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	when := time.Now().Add(-age)
+	if err := os.Chtimes(filePath, when, when); err != nil {
+		t.Fatalf("chtimes error: %v", err)
+	}
+	return filePath
+}
@@ -0,0 +1,524 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IndexFileName 是 StoragePath 根目录下的索引数据库文件名，Hub 名称不得与其相同。
+const IndexFileName = "index.db"
+
+var (
+	indexEntriesBucket  = []byte("entries")
+	indexPartialsBucket = []byte("partials")
+	indexMetaBucket     = []byte("meta")
+	indexBuiltKey       = []byte("built_at")
+)
+
+// IndexRecord 为索引中一个缓存条目的记录，字段与 .meta 旁路文件及文件属性保持一致，
+// 命中时无需 stat 与读取 .meta。
+type IndexRecord struct {
+	Locator               Locator           `json:"-"`
+	SizeBytes             int64             `json:"size_bytes"`
+	ModTime               time.Time         `json:"mod_time"`
+	Digest                string            `json:"digest,omitempty"`
+	EffectiveUpstreamPath string            `json:"effective_upstream_path,omitempty"`
+	Response              *ResponseMetadata `json:"response,omitempty"`
+	// CreatedAt 为正文写入缓存的时间（.meta 的 cached_at），未记录时为零值。
+	CreatedAt  time.Time `json:"created_at"`
+	LastAccess time.Time `json:"last_access"`
+	Hits       int64     `json:"hits"`
+	Pinned     bool      `json:"pinned,omitempty"`
+	// FileModTime/FileID 为写入记录时正文文件的 mtime 与 (设备, inode) 标识，连同 SizeBytes
+	// 在命中时与打开的文件比对，识别绕过索引被替换的正文。
+	FileModTime time.Time `json:"file_mod_time,omitzero"`
+	FileID      string    `json:"file_id,omitempty"`
+}
+
+// partialRecord 为 partials/<hub>/<path> 下的记录，描述中断下载保留的前缀文件，供淘汰计入用量。
+type partialRecord struct {
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+// HubStats 汇总单个 Hub 在索引中的条目数与字节数。
+type HubStats struct {
+	Entries   int   `json:"entries"`
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// Index 是基于 bbolt 的嵌入式缓存索引：entries/<hub>/<path> -> IndexRecord(JSON)。
+// 由 fileStore 在 Put/Remove/UpdateResponse/Touch 时同步维护，供淘汰、列举与统计直接查询。
+type Index struct {
+	db *bolt.DB
+
+	// 命中产生的访问记录先在内存中累积，定期或查询前批量写入，避免每次命中都触发一次 fsync。
+	mu      sync.Mutex
+	pending map[Locator]pendingTouch
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type pendingTouch struct {
+	lastAccess time.Time
+	hits       int64
+}
+
+// indexFlushInterval 为访问记录的批量写入周期。
+const indexFlushInterval = 5 * time.Second
+
+// Indexed 由带索引的 Store 实现，调用方据此获取索引进行查询。
+type Indexed interface {
+	Index() *Index
+}
+
+// OpenIndex 打开（必要时创建）索引数据库。同一文件只能被一个进程打开，
+// 等待 1 秒仍被占用时返回错误。
+func OpenIndex(path string) (*Index, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open cache index: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(indexEntriesBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(indexPartialsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(indexMetaBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init cache index: %w", err)
+	}
+	idx := &Index{
+		db:      db,
+		pending: make(map[Locator]pendingTouch),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go idx.flushLoop()
+	return idx, nil
+}
+
+// Close 写入尚未落盘的访问记录并关闭索引数据库。
+func (idx *Index) Close() error {
+	close(idx.stop)
+	<-idx.done
+	flushErr := idx.flush()
+	if err := idx.db.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+func (idx *Index) flushLoop() {
+	defer close(idx.done)
+	ticker := time.NewTicker(indexFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-idx.stop:
+			return
+		case <-ticker.C:
+			_ = idx.flush()
+		}
+	}
+}
+
+// touch 记录一次命中，实际写入由 flush 批量完成。
+func (idx *Index) touch(locator Locator, at time.Time) {
+	locator.Path = canonicalPath(locator.Path)
+	idx.mu.Lock()
+	current := idx.pending[locator]
+	current.lastAccess = at
+	current.hits++
+	idx.pending[locator] = current
+	idx.mu.Unlock()
+}
+
+// flush 将累积的访问记录写入索引，已被删除的条目直接忽略。
+func (idx *Index) flush() error {
+	idx.mu.Lock()
+	pending := idx.pending
+	idx.pending = make(map[Locator]pendingTouch)
+	idx.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(indexEntriesBucket)
+		for locator, touch := range pending {
+			hub := entries.Bucket([]byte(locator.HubName))
+			if hub == nil {
+				continue
+			}
+			key := indexKey(locator)
+			raw := hub.Get(key)
+			if raw == nil {
+				continue
+			}
+			var record IndexRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return err
+			}
+			if touch.lastAccess.After(record.LastAccess) {
+				record.LastAccess = touch.lastAccess
+			}
+			record.Hits += touch.hits
+			if err := putIndexRecord(hub, key, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Built 表示索引是否已完成过一次从磁盘的重建，新建的索引需要先 Rebuild。
+func (idx *Index) Built() bool {
+	built := false
+	_ = idx.db.View(func(tx *bolt.Tx) error {
+		built = tx.Bucket(indexMetaBucket).Get(indexBuiltKey) != nil
+		return nil
+	})
+	return built
+}
+
+// Lookup 返回 Locator 的索引记录，不存在时返回 ErrNotFound。
+func (idx *Index) Lookup(locator Locator) (IndexRecord, error) {
+	var record IndexRecord
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		hub := tx.Bucket(indexEntriesBucket).Bucket([]byte(locator.HubName))
+		if hub == nil {
+			return ErrNotFound
+		}
+		raw := hub.Get(indexKey(locator))
+		if raw == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(raw, &record)
+	})
+	if err != nil {
+		return IndexRecord{}, err
+	}
+	record.Locator = Locator{HubName: locator.HubName, Path: canonicalPath(locator.Path)}
+	locator = record.Locator
+	idx.mu.Lock()
+	if touch, ok := idx.pending[locator]; ok {
+		if touch.lastAccess.After(record.LastAccess) {
+			record.LastAccess = touch.lastAccess
+		}
+		record.Hits += touch.hits
+	}
+	idx.mu.Unlock()
+	return record, nil
+}
+
+// List 按路径顺序遍历 Hub 的记录；hub 为空时遍历全部 Hub。fn 返回错误时停止遍历。
+func (idx *Index) List(ctx context.Context, hub string, fn func(IndexRecord) error) error {
+	if err := idx.flush(); err != nil {
+		return err
+	}
+	return idx.db.View(func(tx *bolt.Tx) error {
+		entries := tx.Bucket(indexEntriesBucket)
+		visit := func(hubName []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(key, raw []byte) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				var record IndexRecord
+				if err := json.Unmarshal(raw, &record); err != nil {
+					return err
+				}
+				record.Locator = Locator{HubName: string(hubName), Path: string(key)}
+				return fn(record)
+			})
+		}
+		if hub != "" {
+			bucket := entries.Bucket([]byte(hub))
+			if bucket == nil {
+				return nil
+			}
+			return visit([]byte(hub), bucket)
+		}
+		return entries.ForEachBucket(func(name []byte) error {
+			return visit(name, entries.Bucket(name))
+		})
+	})
+}
+
+// Stats 汇总每个 Hub 的条目数与字节数（按引用计算，共享 blob 在各 Hub 中分别计入）。
+func (idx *Index) Stats(ctx context.Context) (map[string]HubStats, error) {
+	stats := make(map[string]HubStats)
+	err := idx.List(ctx, "", func(record IndexRecord) error {
+		current := stats[record.Locator.HubName]
+		current.Entries++
+		current.SizeBytes += record.SizeBytes
+		stats[record.Locator.HubName] = current
+		return nil
+	})
+	return stats, err
+}
+
+// put 写入记录；已存在的记录保留命中次数。
+func (idx *Index) put(record IndexRecord) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		hub, err := tx.Bucket(indexEntriesBucket).CreateBucketIfNotExists([]byte(record.Locator.HubName))
+		if err != nil {
+			return err
+		}
+		key := indexKey(record.Locator)
+		if raw := hub.Get(key); raw != nil {
+			var previous IndexRecord
+			if json.Unmarshal(raw, &previous) == nil {
+				record.Hits = previous.Hits
+			}
+		}
+		return putIndexRecord(hub, key, record)
+	})
+}
+
+// update 在记录存在时以 fn 修改并写回，不存在时返回 ErrNotFound。
+func (idx *Index) update(locator Locator, fn func(*IndexRecord)) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		hub := tx.Bucket(indexEntriesBucket).Bucket([]byte(locator.HubName))
+		if hub == nil {
+			return ErrNotFound
+		}
+		key := indexKey(locator)
+		raw := hub.Get(key)
+		if raw == nil {
+			return ErrNotFound
+		}
+		var record IndexRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		fn(&record)
+		return putIndexRecord(hub, key, record)
+	})
+}
+
+func (idx *Index) remove(locator Locator) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		hub := tx.Bucket(indexEntriesBucket).Bucket([]byte(locator.HubName))
+		if hub == nil {
+			return nil
+		}
+		return hub.Delete(indexKey(locator))
+	})
+}
+
+// putPartial 记录 Locator 保留的前缀文件。
+func (idx *Index) putPartial(locator Locator, record partialRecord) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		hub, err := tx.Bucket(indexPartialsBucket).CreateBucketIfNotExists([]byte(locator.HubName))
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return hub.Put(indexKey(locator), raw)
+	})
+}
+
+func (idx *Index) removePartial(locator Locator) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		hub := tx.Bucket(indexPartialsBucket).Bucket([]byte(locator.HubName))
+		if hub == nil {
+			return nil
+		}
+		return hub.Delete(indexKey(locator))
+	})
+}
+
+// listPartials 遍历全部前缀文件记录。
+func (idx *Index) listPartials(ctx context.Context, fn func(Locator, partialRecord) error) error {
+	var locators []Locator
+	var records []partialRecord
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		partials := tx.Bucket(indexPartialsBucket)
+		return partials.ForEachBucket(func(name []byte) error {
+			return partials.Bucket(name).ForEach(func(key, raw []byte) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				var record partialRecord
+				if err := json.Unmarshal(raw, &record); err != nil {
+					return err
+				}
+				locators = append(locators, Locator{HubName: string(name), Path: string(key)})
+				records = append(records, record)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	// 在事务外回调，fn 可以修改索引（例如清理已不存在的前缀文件）。
+	for i, locator := range locators {
+		if err := fn(locator, records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexKey(locator Locator) []byte {
+	return []byte(canonicalPath(locator.Path))
+}
+
+func putIndexRecord(bucket *bolt.Bucket, key []byte, record IndexRecord) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, raw)
+}
+
+// RebuildIndex 清空索引并按磁盘上的正文与 .meta 重新生成，用于首次启用索引或索引损坏时。
+// 返回写入的条目数。
+func RebuildIndex(ctx context.Context, store Store) (int, error) {
+	if memory, ok := store.(*memoryStore); ok {
+		store = memory.backend
+	}
+	fsStore, ok := store.(*fileStore)
+	if !ok || fsStore.index == nil {
+		return 0, errors.New("index rebuild requires an indexed filesystem store")
+	}
+	entries, err := fsStore.scanEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	partials, err := fsStore.scanPartials(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	err = fsStore.index.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(indexPartialsBucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		partialRoot, err := tx.CreateBucket(indexPartialsBucket)
+		if err != nil {
+			return err
+		}
+		for _, partial := range partials {
+			hub, err := partialRoot.CreateBucketIfNotExists([]byte(partial.locator.HubName))
+			if err != nil {
+				return err
+			}
+			raw, err := json.Marshal(partialRecord{SizeBytes: partial.size, ModTime: partial.modTime})
+			if err != nil {
+				return err
+			}
+			if err := hub.Put(indexKey(partial.locator), raw); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteBucket(indexEntriesBucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		root, err := tx.CreateBucket(indexEntriesBucket)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			hub, err := root.CreateBucketIfNotExists([]byte(entry.locator.HubName))
+			if err != nil {
+				return err
+			}
+			record := IndexRecord{
+				SizeBytes:   entry.size,
+				ModTime:     entry.modTime,
+				CreatedAt:   entry.cachedAt,
+				LastAccess:  entry.accessTime,
+				FileModTime: entry.fileModTime,
+				FileID:      entry.fileID,
+			}
+			if metadata, err := fsStore.readMetadata(entry.filePath); err == nil {
+				record.applyMetadata(metadata)
+			}
+			if err := putIndexRecord(hub, indexKey(entry.locator), record); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(indexMetaBucket).Put(indexBuiltKey, []byte(now.Format(time.RFC3339)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (r *IndexRecord) applyMetadata(metadata entryMetadata) {
+	r.Digest = metadata.Digest
+	r.EffectiveUpstreamPath = metadata.EffectiveUpstreamPath
+	r.Response = metadata.Response
+	r.Pinned = metadata.Pinned
+}
+
+func indexRecordFromEntry(entry Entry, lastAccess time.Time, info os.FileInfo) IndexRecord {
+	record := IndexRecord{
+		Locator:               entry.Locator,
+		SizeBytes:             entry.SizeBytes,
+		ModTime:               entry.ModTime,
+		Digest:                entry.Digest,
+		EffectiveUpstreamPath: entry.EffectiveUpstreamPath,
+		CreatedAt:             entry.CachedAt,
+		LastAccess:            lastAccess,
+		Pinned:                entry.Pinned,
+	}
+	record.FileModTime, record.FileID = fileStamp(info)
+	if !entry.Response.IsZero() {
+		response := entry.Response
+		record.Response = &response
+	}
+	return record
+}
+
+// fileStamp 返回正文文件的 mtime 与 (设备, inode) 标识；无法读取 inode 的平台标识为空。
+func fileStamp(info os.FileInfo) (time.Time, string) {
+	id := ""
+	if inode, _, ok := fileIdentity(info); ok {
+		id = fmt.Sprintf("%d:%d", inode.dev, inode.ino)
+	}
+	return info.ModTime(), id
+}
+
+// matchesFile 判断记录是否仍描述 info 对应的文件：大小、mtime 与 inode 均须一致。
+// 早于这些字段写入的记录一律视为不一致，命中一次后即按磁盘状态刷新。
+func (r IndexRecord) matchesFile(info os.FileInfo) bool {
+	modTime, id := fileStamp(info)
+	return r.SizeBytes == info.Size() && r.FileModTime.Equal(modTime) && r.FileID == id
+}
+
+// entry 将记录转换为 Get 返回的 Entry。
+func (r IndexRecord) entry(filePath string) Entry {
+	entry := Entry{
+		Locator:               r.Locator,
+		FilePath:              filePath,
+		SizeBytes:             r.SizeBytes,
+		ModTime:               r.ModTime,
+		EffectiveUpstreamPath: r.EffectiveUpstreamPath,
+		Digest:                r.Digest,
+		Pinned:                r.Pinned,
+		CachedAt:              r.CreatedAt,
+	}
+	if r.Response != nil {
+		entry.Response = *r.Response
+	}
+	return entry
+}
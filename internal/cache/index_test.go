@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexTracksStoreOperations(t *testing.T) {
+	store := newIndexedStore(t, t.TempDir())
+	index := store.(Indexed).Index()
+	ctx := context.Background()
+
+	packument := Locator{HubName: "npm", Path: "/lodash"}
+	put := func(locator Locator, body string, opts PutOptions) {
+		if _, err := store.Put(ctx, locator, strings.NewReader(body), opts); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+	}
+	put(packument, "{}", PutOptions{Response: ResponseMetadata{ETag: `"v1"`}})
+	put(Locator{HubName: "npm", Path: "/tarballs/lodash-4.17.21.tgz"}, "tarball", PutOptions{})
+	put(Locator{HubName: "docker", Path: "/v2/library/demo/manifests/latest"}, "manifest", PutOptions{EffectiveUpstreamPath: "/v2/library/demo/manifests/latest"})
+
+	for i := 0; i < 2; i++ {
+		if err := store.(AccessRecorder).Touch(ctx, packument); err != nil {
+			t.Fatalf("touch error: %v", err)
+		}
+	}
+	record, err := index.Lookup(packument)
+	if err != nil {
+		t.Fatalf("lookup error: %v", err)
+	}
+	if record.Hits != 2 || record.SizeBytes != 2 || !record.LastAccess.After(record.CreatedAt) {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+
+	// 命中直接使用索引中的元数据，不再读取 .meta。
+	filePath, _ := store.(*fileStore).entryPath(packument)
+	if err := os.Remove(metadataPath(filePath)); err != nil {
+		t.Fatalf("remove meta: %v", err)
+	}
+	result, err := store.Get(ctx, packument)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	result.Reader.Close()
+	if result.Entry.Response.ETag != `"v1"` || result.Entry.SizeBytes != 2 {
+		t.Fatalf("expected entry from index, got %+v", result.Entry)
+	}
+
+	stats, err := index.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats error: %v", err)
+	}
+	if stats["npm"].Entries != 2 || stats["npm"].SizeBytes != 9 || stats["docker"].Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	if err := store.Remove(ctx, packument); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+	if _, err := index.Lookup(packument); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected record removed, got %v", err)
+	}
+}
+
+func TestIndexRebuildsFromExistingStorage(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	digest := sha256Digest("layer")
+	ctx := context.Background()
+	if _, err := plain.Put(ctx, Locator{HubName: "docker", Path: "/v2/x/blobs/" + digest}, strings.NewReader("layer"), PutOptions{Digest: digest}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	published := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := plain.Put(ctx, Locator{HubName: "pypi", Path: "/simple/demo/"}, strings.NewReader("<html>"), PutOptions{ModTime: published}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	store := newIndexedStore(t, dir)
+	var records []IndexRecord
+	if err := store.(Indexed).Index().List(ctx, "", func(record IndexRecord) error {
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("list error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 rebuilt records, got %+v", records)
+	}
+	if records[0].Locator.HubName != "docker" || records[0].Digest != digest {
+		t.Fatalf("unexpected docker record: %+v", records[0])
+	}
+	if records[1].Locator.Path != "/simple/demo" || records[1].SizeBytes != 6 {
+		t.Fatalf("unexpected pypi record: %+v", records[1])
+	}
+	if !records[1].ModTime.Equal(published) || time.Since(records[1].CreatedAt) > time.Minute {
+		t.Fatalf("rebuilt record must keep the write time separate from Last-Modified: %+v", records[1])
+	}
+	if _, err := store.(Indexed).Index().Lookup(Locator{HubName: "pypi", Path: "/simple/demo/"}); err != nil {
+		t.Fatalf("lookup should canonicalize trailing slash: %v", err)
+	}
+}
+
+func TestEvictorUsesIndexAccessOrder(t *testing.T) {
+	store := newIndexedStore(t, t.TempDir())
+	ctx := context.Background()
+	locators := []Locator{
+		{HubName: "npm", Path: "/a.tgz"},
+		{HubName: "npm", Path: "/b.tgz"},
+		{HubName: "npm", Path: "/c.tgz"},
+	}
+	for _, locator := range locators {
+		if _, err := store.Put(ctx, locator, strings.NewReader("xxxx"), PutOptions{}); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+	}
+	if err := store.(AccessRecorder).Touch(ctx, locators[0]); err != nil {
+		t.Fatalf("touch error: %v", err)
+	}
+
+	evictor, err := NewEvictor(store, EvictionOptions{GlobalLimit: 10}, nil)
+	if err != nil {
+		t.Fatalf("evictor error: %v", err)
+	}
+	report, err := evictor.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("sweep error: %v", err)
+	}
+	if report.Evicted != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := store.Get(ctx, locators[1]); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected least recently used entry evicted, got %v", err)
+	}
+	if _, err := store.(Indexed).Index().Lookup(locators[1]); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("evicted entry must leave the index, got %v", err)
+	}
+}
+
+// newIndexedStore 返回启用索引的磁盘 Store，测试结束时关闭索引。
+func newIndexedStore(t *testing.T, dir string) Store {
+	t.Helper()
+	store, err := NewStoreWithOptions(dir, StoreOptions{Index: true})
+	if err != nil {
+		t.Fatalf("failed to create indexed store: %v", err)
+	}
+	t.Cleanup(func() { store.(Indexed).Index().Close() })
+	return store
+}
+
+func TestIndexRefreshesRecordsForFilesReplacedOutsideIndex(t *testing.T) {
+	dir := t.TempDir()
+	store := newIndexedStore(t, dir)
+	ctx := context.Background()
+	locator := Locator{HubName: "npm", Path: "/lodash"}
+	if _, err := store.Put(ctx, locator, strings.NewReader("{}"), PutOptions{Response: ResponseMetadata{ETag: `"v1"`}}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	// cache import/prefetch 等命令不持有索引，直接改写正文与 .meta。
+	plain, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	if _, err := plain.Put(ctx, locator, strings.NewReader(`{"v":2}`), PutOptions{Response: ResponseMetadata{ETag: `"v2"`}}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	result, err := store.Get(ctx, locator)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	body, _ := io.ReadAll(result.Reader)
+	result.Reader.Close()
+	if string(body) != `{"v":2}` || result.Entry.SizeBytes != int64(len(body)) || result.Entry.Response.ETag != `"v2"` {
+		t.Fatalf("expected entry rebuilt from the replaced file, got %+v body=%q", result.Entry, body)
+	}
+	record, err := store.(Indexed).Index().Lookup(locator)
+	if err != nil {
+		t.Fatalf("lookup error: %v", err)
+	}
+	if record.SizeBytes != int64(len(body)) || record.Response == nil || record.Response.ETag != `"v2"` {
+		t.Fatalf("expected stale record refreshed, got %+v", record)
+	}
+}
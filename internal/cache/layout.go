@@ -0,0 +1,365 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/hubmodule"
+)
+
+// hashedDirName 是 hashed 布局正文所在的 Hub 子目录名。分片目录与迁移前残留的 raw_path 文件分处不同前缀，
+// 即使旧副本恰好名为 <aa>，创建分片目录也不会遇到 ENOTDIR。
+const hashedDirName = ".h"
+
+// hashed 判断 Hub 是否使用 hashed 布局。
+func (s *fileStore) hashed(hub string) bool {
+	return s.layouts[hub] == hubmodule.DiskLayoutHashed
+}
+
+// hashedPath 返回 hashed 布局下的正文路径：<basePath>/<hub>/.h/<aa>/<bb>/<sha256(path)>，
+// 与路径内容无关，不会出现文件/目录冲突或超长文件名。
+func (s *fileStore) hashedPath(locator Locator) (string, error) {
+	if locator.HubName == "" {
+		return "", errors.New("hub name required")
+	}
+	if strings.ContainsAny(locator.HubName, `/\`) || locator.HubName == "." || locator.HubName == ".." {
+		return "", errors.New("invalid cache path")
+	}
+	sum := sha256.Sum256([]byte(canonicalPath(locator.Path)))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.basePath, locator.HubName, hashedDirName, name[:2], name[2:4], name), nil
+}
+
+// legacyPath 返回 hashed 布局的 Hub 在迁移前可能残留的 raw_path 副本路径；raw_path Hub 返回空串。
+func (s *fileStore) legacyPath(locator Locator) string {
+	if !s.hashed(locator.HubName) {
+		return ""
+	}
+	filePath, err := s.rawPath(locator)
+	if err != nil {
+		return ""
+	}
+	return filePath
+}
+
+// locate 返回读取条目时应使用的正文路径：hashed 布局的正文尚不存在、而迁移前的 raw_path 副本仍在时
+// 返回旧路径，使切换布局后无需停机即可继续命中旧缓存。
+func (s *fileStore) locate(locator Locator) (string, error) {
+	filePath, err := s.entryPath(locator)
+	if err != nil {
+		return "", err
+	}
+	legacy := s.legacyPath(locator)
+	if legacy == "" {
+		return filePath, nil
+	}
+	if _, err := os.Stat(filePath); err == nil {
+		return filePath, nil
+	}
+	if info, err := os.Stat(legacy); err == nil && !info.IsDir() {
+		return legacy, nil
+	}
+	return filePath, nil
+}
+
+// locatorForFile 将 <basePath>/<hub>/ 下的正文路径还原为 Locator：hashed 布局的正文从 .meta 读取原始路径，
+// 其余按相对路径还原。
+func (s *fileStore) locatorForFile(filePath string) (Locator, bool) {
+	rel, err := filepath.Rel(s.basePath, filePath)
+	if err != nil {
+		return Locator{}, false
+	}
+	hub, rest, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok || hub == BlobsDirName || hub == ".." {
+		return Locator{}, false
+	}
+	if isHashedRel(rest) {
+		if metadata, err := s.readMetadata(filePath); err == nil && metadata.Path != "" {
+			return Locator{HubName: hub, Path: metadata.Path}, true
+		}
+	}
+	return Locator{HubName: hub, Path: "/" + rest}, true
+}
+
+// isHashedRel 判断 Hub 目录下的相对路径是否为 hashed 布局的 .h/<aa>/<bb>/<64 位十六进制摘要>。
+func isHashedRel(rel string) bool {
+	parts := strings.Split(rel, "/")
+	if len(parts) != 4 || parts[0] != hashedDirName || len(parts[3]) != sha256.Size*2 {
+		return false
+	}
+	name := parts[3]
+	for _, r := range name {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return parts[1] == name[:2] && parts[2] == name[2:4]
+}
+
+// MigrateOptions 控制 raw_path 到 hashed 布局的迁移。
+type MigrateOptions struct {
+	// Hub 非空时只迁移该 Hub，否则迁移所有配置为 hashed 布局的 Hub。
+	Hub string
+	// DryRun 为 true 时只统计与记录日志，不移动任何文件。
+	DryRun bool
+}
+
+// MigrateReport 汇总一次迁移的结果。
+type MigrateReport struct {
+	// Migrated 为移动到 hashed 路径的条目数。
+	Migrated int
+	// Superseded 为 hashed 路径已有更新正文、直接删除的旧副本数。
+	Superseded int
+	// DiscardedPartials 为删除的旧路径下断点续传前缀数，hashed 布局不会再使用它们。
+	DiscardedPartials int
+	// Failed 为迁移失败、保留在原位的条目数，可再次执行迁移重试。
+	Failed int
+	// Bytes 为迁移（或 dry-run 时将迁移）的正文字节数。
+	Bytes int64
+}
+
+// String 便于 CLI 输出一行汇总。
+func (r MigrateReport) String() string {
+	return fmt.Sprintf("migrated=%d superseded=%d discarded_partials=%d failed=%d bytes=%d",
+		r.Migrated, r.Superseded, r.DiscardedPartials, r.Failed, r.Bytes)
+}
+
+// MigrateLayout 将配置为 hashed 布局的 Hub 下残留的 raw_path 条目移动到 hashed 路径，动作以 action=cache_migrate
+// 记录日志。正文以硬链接挂到新路径，不覆盖已存在的文件，可与已切换到 hashed 布局的服务并行执行：
+// 服务在迁移完成前回退读取旧路径，新写入直接落在 hashed 路径。
+func MigrateLayout(ctx context.Context, store Store, opts MigrateOptions, logger *logrus.Logger) (MigrateReport, error) {
+	if memory, ok := store.(*memoryStore); ok {
+		store = memory.backend
+	}
+	fsStore, ok := store.(*fileStore)
+	if !ok {
+		return MigrateReport{}, errors.New("layout migration requires filesystem store")
+	}
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	if opts.Hub != "" && !fsStore.hashed(opts.Hub) {
+		return MigrateReport{}, fmt.Errorf("hub %s is not configured with the %s layout", opts.Hub, hubmodule.DiskLayoutHashed)
+	}
+
+	var report MigrateReport
+	for hub := range fsStore.layouts {
+		if !fsStore.hashed(hub) || (opts.Hub != "" && hub != opts.Hub) {
+			continue
+		}
+		if err := fsStore.migrateHub(ctx, hub, opts.DryRun, logger, &report); err != nil {
+			logMigrate(logger, opts.DryRun, report, err)
+			return report, err
+		}
+	}
+	logMigrate(logger, opts.DryRun, report, nil)
+	return report, nil
+}
+
+// migrateHub 先收集 Hub 目录下的 raw_path 正文与前缀文件，再逐个迁移，避免边遍历边改动目录。
+func (s *fileStore) migrateHub(ctx context.Context, hub string, dryRun bool, logger *logrus.Logger, report *MigrateReport) error {
+	hubRoot := filepath.Join(s.basePath, hub)
+	var bodies, partials []string
+	err := filepath.WalkDir(hubRoot, func(filePath string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := d.Name()
+		switch {
+		case d.IsDir():
+			// hashed 路径下的正文与前缀文件已属于新布局，可能正被服务写入，整棵子树跳过。
+			if filePath == filepath.Join(hubRoot, hashedDirName) {
+				return fs.SkipDir
+			}
+		case strings.HasPrefix(name, partialPrefix) && !strings.HasSuffix(name, ".meta"):
+			partials = append(partials, filePath)
+		case isEntryFileName(name):
+			bodies = append(bodies, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range bodies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		locator, _ := s.locatorForFile(filePath)
+		s.migrateEntry(locator, filePath, dryRun, logger, report)
+		if !dryRun {
+			pruneEmptyDirs(filepath.Dir(filePath), hubRoot)
+		}
+	}
+	for _, partial := range partials {
+		report.DiscardedPartials++
+		if dryRun {
+			continue
+		}
+		if err := removePartial(partialBodyPath(partial)); err != nil {
+			logger.WithFields(logrus.Fields{"action": "cache_migrate", "hub": hub, "path": partial}).
+				WithError(err).Warn("cache_migrate_failed")
+			continue
+		}
+		pruneEmptyDirs(filepath.Dir(partial), hubRoot)
+	}
+	return nil
+}
+
+// migrateEntry 将单个 raw_path 条目迁移到 hashed 路径：先以不覆盖的方式挂上带原始路径的 .meta，再硬链接正文，
+// 最后删除旧路径。任一步发现 hashed 路径已被写入时，说明服务已回源取得更新的正文，旧副本直接作废。
+func (s *fileStore) migrateEntry(locator Locator, filePath string, dryRun bool, logger *logrus.Logger, report *MigrateReport) {
+	fields := logrus.Fields{"action": "cache_migrate", "hub": locator.HubName, "path": locator.Path, "dry_run": dryRun}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+	if dryRun {
+		report.Migrated++
+		report.Bytes += info.Size()
+		logger.WithFields(fields).WithField("bytes", info.Size()).Info("cache_migrate_candidate")
+		return
+	}
+
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	target, err := s.hashedPath(locator)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(target), 0o755)
+	}
+	if err != nil {
+		report.Failed++
+		logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+		return
+	}
+
+	superseded := false
+	metadata, err := s.readMetadata(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		report.Failed++
+		logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+		return
+	}
+	metadata.Path = canonicalPath(locator.Path)
+	linkedMeta := false
+	if err := linkMetadata(target, metadata); err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			report.Failed++
+			logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+			return
+		}
+		superseded = true
+	} else {
+		linkedMeta = true
+	}
+	if !superseded {
+		if err := os.Link(filePath, target); err != nil {
+			if !errors.Is(err, fs.ErrExist) {
+				if linkedMeta {
+					_ = os.Remove(metadataPath(target))
+				}
+				report.Failed++
+				logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+				return
+			}
+			superseded = true
+		}
+	}
+
+	if superseded {
+		// 新正文已经持有自己的 blob 引用，旧副本按普通条目删除并释放其引用。
+		if err := s.removeFiles(filePath); err != nil {
+			report.Failed++
+			logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+			return
+		}
+		report.Superseded++
+		logger.WithFields(fields).Info("cache_migrate_superseded")
+		return
+	}
+	// 正文以硬链接迁移，共享 blob 的引用数不变，只需删除旧路径。
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		report.Failed++
+		logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+		return
+	}
+	if err := os.Remove(metadataPath(filePath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+	}
+	report.Migrated++
+	report.Bytes += info.Size()
+	logger.WithFields(fields).WithField("bytes", info.Size()).Info("cache_migrate_moved")
+}
+
+// linkMetadata 与 writeMetadata 相同，但目标 .meta 已存在时返回 fs.ErrExist 而不是覆盖。
+func linkMetadata(filePath string, metadata entryMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	metaFilePath := metadataPath(filePath)
+	tempFile, err := os.CreateTemp(filepath.Dir(metaFilePath), ".cache-meta-*")
+	if err != nil {
+		return err
+	}
+	tempName := tempFile.Name()
+	defer os.Remove(tempName)
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Link(tempName, metaFilePath)
+}
+
+// pruneEmptyDirs 自 dir 向上删除迁移后变空的目录，到 Hub 根目录为止；非空目录删除失败即停止。
+func pruneEmptyDirs(dir, hubRoot string) {
+	for dir != hubRoot && strings.HasPrefix(dir, hubRoot+string(os.PathSeparator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func logMigrate(logger *logrus.Logger, dryRun bool, report MigrateReport, err error) {
+	fields := logrus.Fields{
+		"action":             "cache_migrate",
+		"dry_run":            dryRun,
+		"migrated":           report.Migrated,
+		"superseded":         report.Superseded,
+		"discarded_partials": report.DiscardedPartials,
+		"failed":             report.Failed,
+		"bytes":              report.Bytes,
+	}
+	if err != nil {
+		logger.WithFields(fields).WithError(err).Warn("cache_migrate_failed")
+		return
+	}
+	logger.WithFields(fields).Info("cache_migrate_complete")
+}
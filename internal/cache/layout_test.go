@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/any-hub/any-hub/internal/hubmodule"
+)
+
+func newHashedStore(t *testing.T, basePath string) *fileStore {
+	t.Helper()
+	store, err := NewStoreWithOptions(basePath, StoreOptions{
+		Layouts: map[string]string{"docker": hubmodule.DiskLayoutHashed},
+	})
+	if err != nil {
+		t.Fatalf("store init error: %v", err)
+	}
+	return store.(*fileStore)
+}
+
+func TestHashedLayoutAvoidsFileDirectoryCollisions(t *testing.T) {
+	store := newHashedStore(t, t.TempDir())
+	ctx := context.Background()
+
+	parent := Locator{HubName: "docker", Path: "/v2/library/nginx/manifests/latest"}
+	child := Locator{HubName: "docker", Path: "/v2/library/nginx/manifests/latest/sha256:abc"}
+	for _, locator := range []Locator{parent, child} {
+		if _, err := store.Put(ctx, locator, strings.NewReader(locator.Path), PutOptions{}); err != nil {
+			t.Fatalf("put %s error: %v", locator.Path, err)
+		}
+	}
+	for _, locator := range []Locator{parent, child} {
+		result, err := store.Get(ctx, locator)
+		if err != nil {
+			t.Fatalf("get %s error: %v", locator.Path, err)
+		}
+		body, _ := io.ReadAll(result.Reader)
+		result.Reader.Close()
+		if string(body) != locator.Path {
+			t.Fatalf("unexpected body for %s: %q", locator.Path, body)
+		}
+		if strings.Contains(result.Entry.FilePath, ":") {
+			t.Fatalf("hashed path should not carry the locator path: %s", result.Entry.FilePath)
+		}
+	}
+
+	var walked []string
+	err := Walk(ctx, store, "docker", func(locator Locator) error {
+		walked = append(walked, locator.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk error: %v", err)
+	}
+	if len(walked) != 2 || walked[0] != parent.Path || walked[1] != child.Path {
+		t.Fatalf("walk should recover original paths from metadata: %v", walked)
+	}
+}
+
+func TestHashedLayoutIgnoresRawFilesNamedLikeShards(t *testing.T) {
+	base := t.TempDir()
+	store := newHashedStore(t, base)
+	ctx := context.Background()
+	locator := Locator{HubName: "docker", Path: "/v2/library/nginx/manifests/latest"}
+
+	// 迁移前残留的 raw_path 文件恰好与分片目录同名，不能挡住 hashed 正文的写入或迁移。
+	target, _ := store.hashedPath(locator)
+	shard := filepath.Base(filepath.Dir(filepath.Dir(target)))
+	if err := os.MkdirAll(filepath.Join(base, "docker"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "docker", shard), []byte("raw"), 0o644); err != nil {
+		t.Fatalf("write raw file: %v", err)
+	}
+	if _, err := store.Put(ctx, locator, strings.NewReader("manifest"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if rel, _ := filepath.Rel(filepath.Join(base, "docker"), target); !isHashedRel(filepath.ToSlash(rel)) {
+		t.Fatalf("hashed bodies should live under %s: %s", hashedDirName, rel)
+	}
+
+	report, err := MigrateLayout(ctx, store, MigrateOptions{}, nil)
+	if err != nil || report.Migrated != 1 || report.Failed != 0 {
+		t.Fatalf("migrate raw file: report=%+v err=%v", report, err)
+	}
+	if body := readBody(t, store, locator); body != "manifest" {
+		t.Fatalf("migration must not touch hashed bodies, got %q", body)
+	}
+	if body := readBody(t, store, Locator{HubName: "docker", Path: "/" + shard}); body != "raw" {
+		t.Fatalf("raw file should be migrated under its own path, got %q", body)
+	}
+}
+
+func TestHashedLayoutFallsBackToRawPathUntilMigrated(t *testing.T) {
+	basePath := t.TempDir()
+	ctx := context.Background()
+	raw, err := NewStore(basePath)
+	if err != nil {
+		t.Fatalf("store init error: %v", err)
+	}
+	kept := Locator{HubName: "docker", Path: "/v2/library/alpine/manifests/3.20"}
+	refreshed := Locator{HubName: "docker", Path: "/v2/library/alpine/manifests/edge"}
+	for _, locator := range []Locator{kept, refreshed} {
+		if _, err := raw.Put(ctx, locator, strings.NewReader("old"), PutOptions{EffectiveUpstreamPath: locator.Path}); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+	}
+	rawKept, _ := raw.(*fileStore).entryPath(kept)
+	rawRefreshed, _ := raw.(*fileStore).entryPath(refreshed)
+
+	store := newHashedStore(t, basePath)
+	result, err := store.Get(ctx, kept)
+	if err != nil {
+		t.Fatalf("hashed store should read the raw_path copy: %v", err)
+	}
+	result.Reader.Close()
+	if result.Entry.FilePath != rawKept || result.Entry.EffectiveUpstreamPath != kept.Path {
+		t.Fatalf("unexpected fallback entry: %+v", result.Entry)
+	}
+
+	// 迁移期间与上游同步得到的新正文直接写入 hashed 路径，旧副本随之删除。
+	if _, err := store.Put(ctx, refreshed, strings.NewReader("new"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if _, err := os.Stat(rawRefreshed); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("raw copy should be dropped after a hashed write, got %v", err)
+	}
+
+	report, err := MigrateLayout(ctx, store, MigrateOptions{}, nil)
+	if err != nil {
+		t.Fatalf("migrate error: %v", err)
+	}
+	if report.Migrated != 1 || report.Failed != 0 || report.Bytes != int64(len("old")) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "docker", "v2")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("empty raw directories should be pruned, got %v", err)
+	}
+
+	hashedKept, _ := store.entryPath(kept)
+	result, err = store.Get(ctx, kept)
+	if err != nil {
+		t.Fatalf("get after migrate error: %v", err)
+	}
+	body, _ := io.ReadAll(result.Reader)
+	result.Reader.Close()
+	if string(body) != "old" || result.Entry.FilePath != hashedKept || result.Entry.EffectiveUpstreamPath != kept.Path {
+		t.Fatalf("unexpected migrated entry: %+v body=%q", result.Entry, body)
+	}
+	if locator, ok := store.locatorForFile(hashedKept); !ok || locator != kept {
+		t.Fatalf("metadata should record the original path, got %+v", locator)
+	}
+
+	again, err := MigrateLayout(ctx, store, MigrateOptions{}, nil)
+	if err != nil || again.Migrated != 0 {
+		t.Fatalf("second migration should be a no-op: %+v %v", again, err)
+	}
+}
+
+func TestMigrateLayoutDropsSupersededCopies(t *testing.T) {
+	basePath := t.TempDir()
+	ctx := context.Background()
+	locator := Locator{HubName: "docker", Path: "/v2/library/redis/manifests/7"}
+	raw, err := NewStore(basePath)
+	if err != nil {
+		t.Fatalf("store init error: %v", err)
+	}
+	if _, err := raw.Put(ctx, locator, strings.NewReader("old"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	rawPath, _ := raw.(*fileStore).entryPath(locator)
+
+	// 模拟另一进程（运行中的服务）已把新正文写入 hashed 路径，但尚未清理旧副本。
+	writer := newHashedStore(t, basePath)
+	hashedPath, _ := writer.entryPath(locator)
+	if err := os.MkdirAll(filepath.Dir(hashedPath), 0o755); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+	if err := os.WriteFile(hashedPath, []byte("new"), 0o644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	report, err := MigrateLayout(ctx, writer, MigrateOptions{Hub: "docker"}, nil)
+	if err != nil {
+		t.Fatalf("migrate error: %v", err)
+	}
+	if report.Superseded != 1 || report.Migrated != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(rawPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("superseded raw copy should be removed, got %v", err)
+	}
+	if body, _ := os.ReadFile(hashedPath); string(body) != "new" {
+		t.Fatalf("migration must not overwrite the newer body, got %q", body)
+	}
+
+	if _, err := MigrateLayout(ctx, writer, MigrateOptions{Hub: "npm"}, nil); err == nil {
+		t.Fatalf("migrating a raw_path hub should be rejected")
+	}
+}
+
+func readBody(t *testing.T, store Store, locator Locator) string {
+	t.Helper()
+	result, err := store.Get(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("get %s error: %v", locator.Path, err)
+	}
+	defer result.Reader.Close()
+	body, err := io.ReadAll(result.Reader)
+	if err != nil {
+		t.Fatalf("read %s error: %v", locator.Path, err)
+	}
+	return string(body)
+}
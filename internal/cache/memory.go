@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMemoryEntryLimit 为可进入内存层的单个正文上限，足以容纳绝大多数
+	// npm packument、PyPI simple 页面、Debian InRelease 与 Docker manifest。
+	defaultMemoryEntryLimit = 4 << 20
+	// memoryTouchInterval 内存命中时向下层转发 Touch 的最小间隔，
+	// 保证磁盘淘汰器仍能看到热点条目，同时避免每次命中都 stat + chtimes。
+	memoryTouchInterval = time.Minute
+	// memoryGhostEntries 为记录“已读取过一次”的键数上限，超出后遗忘最早的记录。
+	memoryGhostEntries = 16384
+)
+
+type revalidationKey struct{}
+
+// WithRevalidation 标记本次读取的条目需要与上游再验证（模块 CachePolicy 的 RequireRevalidate），
+// 内存层在首次读取时即接纳此类元数据；其余正文需被读取两次才进入内存，一次性下载的制品不会挤出热点。
+func WithRevalidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidationKey{}, true)
+}
+
+func requiresRevalidation(ctx context.Context) bool {
+	revalidate, _ := ctx.Value(revalidationKey{}).(bool)
+	return revalidate
+}
+
+// MemoryOptions 描述内存热点层的容量。
+type MemoryOptions struct {
+	// MaxBytes 为内存层正文总字节数上限（Global.MaxMemoryCacheSize），<= 0 时不启用内存层。
+	MaxBytes int64
+	// MaxEntryBytes 为单个正文可进入内存层的上限，默认 4MiB 且不超过 MaxBytes。
+	MaxEntryBytes int64
+}
+
+// NewMemoryStore 在 backend 前叠加按字节计量的 LRU 内存层：需要再验证的小正文首次读取后、其余小正文
+// 第二次读取后常驻内存，后续命中不再访问文件系统；Put/Remove/UpdateResponse 会先使对应条目失效再委托 backend。
+// backend 支持的 AccessRecorder/MetadataUpdater/PartialStore/Pinner 能力保持可用。
+func NewMemoryStore(backend Store, opts MemoryOptions) Store {
+	if opts.MaxBytes <= 0 {
+		return backend
+	}
+	if opts.MaxEntryBytes <= 0 {
+		opts.MaxEntryBytes = defaultMemoryEntryLimit
+	}
+	if opts.MaxEntryBytes > opts.MaxBytes {
+		opts.MaxEntryBytes = opts.MaxBytes
+	}
+	return &memoryStore{
+		backend: backend,
+		opts:    opts,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+		ghosts:  list.New(),
+		seen:    make(map[string]*list.Element),
+		loads:   make(map[string]*memoryLoad),
+	}
+}
+
+type memoryStore struct {
+	backend Store
+	opts    MemoryOptions
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+	used  int64
+	// ghosts/seen 记录只读取过一次、尚未进入内存层的键（按 LRU 限制数量）。
+	ghosts *list.List
+	seen   map[string]*list.Element
+	// loads 记录正在从 backend 加载的键，失效时递增其 generation，避免并发加载把旧正文写回内存层。
+	loads map[string]*memoryLoad
+}
+
+type memoryLoad struct {
+	generation uint64
+	active     int
+}
+
+type memoryItem struct {
+	key       string
+	entry     Entry
+	body      []byte
+	lastTouch time.Time
+}
+
+// memoryReader 为内存正文提供 io.ReadSeekCloser，供代理层按 Range 切分。
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error { return nil }
+
+func (s *memoryStore) Get(ctx context.Context, locator Locator) (*ReadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := locatorKey(locator)
+	if item, ok := s.lookup(key); ok {
+		return &ReadResult{Entry: item.entry, Reader: memoryReader{bytes.NewReader(item.body)}}, nil
+	}
+
+	generation := s.beginLoad(key)
+	defer s.endLoad(key)
+
+	result, err := s.backend.Get(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	if result.Entry.SizeBytes > s.opts.MaxEntryBytes || !s.admit(key, requiresRevalidation(ctx)) {
+		return result, nil
+	}
+	body, err := io.ReadAll(result.Reader)
+	result.Reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := result.Entry
+	entry.SizeBytes = int64(len(body))
+	s.store(key, generation, entry, body)
+	return &ReadResult{Entry: entry, Reader: memoryReader{bytes.NewReader(body)}}, nil
+}
+
+// admit 判断本次读取的正文是否进入内存层：需要再验证的元数据直接进入，其余正文第二次读取时进入。
+func (s *memoryStore) admit(key string, revalidate bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.seen[key]; ok {
+		s.ghosts.Remove(elem)
+		delete(s.seen, key)
+		return true
+	}
+	if revalidate {
+		return true
+	}
+	s.seen[key] = s.ghosts.PushFront(key)
+	for s.ghosts.Len() > memoryGhostEntries {
+		oldest := s.ghosts.Back()
+		s.ghosts.Remove(oldest)
+		delete(s.seen, oldest.Value.(string))
+	}
+	return false
+}
+
+func (s *memoryStore) beginLoad(key string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	load, ok := s.loads[key]
+	if !ok {
+		load = &memoryLoad{}
+		s.loads[key] = load
+	}
+	load.active++
+	return load.generation
+}
+
+func (s *memoryStore) endLoad(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if load := s.loads[key]; load != nil {
+		load.active--
+		if load.active == 0 {
+			delete(s.loads, key)
+		}
+	}
+}
+
+func (s *memoryStore) Put(ctx context.Context, locator Locator, body io.Reader, opts PutOptions) (*Entry, error) {
+	s.invalidate(locator)
+	entry, err := s.backend.Put(ctx, locator, body, opts)
+	// 写入期间可能有并发读取把旧正文放回内存层，提交后再失效一次。
+	s.invalidate(locator)
+	return entry, err
+}
+
+func (s *memoryStore) Remove(ctx context.Context, locator Locator) error {
+	s.invalidate(locator)
+	return s.backend.Remove(ctx, locator)
+}
+
+// UpdateResponse 委托 backend 并使内存副本失效，下次读取时加载新的元数据。
+func (s *memoryStore) UpdateResponse(ctx context.Context, locator Locator, response ResponseMetadata) error {
+	updater, ok := s.backend.(MetadataUpdater)
+	if !ok {
+		return nil
+	}
+	err := updater.UpdateResponse(ctx, locator, response)
+	s.invalidate(locator)
+	return err
+}
+
+// Pin 委托 backend 并使内存副本失效，下次读取时加载新的固定状态。
+func (s *memoryStore) Pin(ctx context.Context, locator Locator, pinned bool) error {
+	pinner, ok := s.backend.(Pinner)
+	if !ok {
+		return ErrPinUnsupported
+	}
+	err := pinner.Pin(ctx, locator, pinned)
+	s.invalidate(locator)
+	return err
+}
+
+// RemoveUnpinned 委托 backend 并使内存副本失效；backend 不支持固定时等同 Remove。
+func (s *memoryStore) RemoveUnpinned(ctx context.Context, locator Locator) error {
+	s.invalidate(locator)
+	pinner, ok := s.backend.(Pinner)
+	if !ok {
+		return s.backend.Remove(ctx, locator)
+	}
+	return pinner.RemoveUnpinned(ctx, locator)
+}
+
+// Touch 对内存命中做节流转发：同一条目每分钟至多向 backend 记录一次访问。
+// backend 带索引时访问记录只在内存中累积，开销很小，因此每次都转发以保留准确的命中次数。
+func (s *memoryStore) Touch(ctx context.Context, locator Locator) error {
+	recorder, ok := s.backend.(AccessRecorder)
+	if !ok {
+		return nil
+	}
+	if s.Index() != nil {
+		return recorder.Touch(ctx, locator)
+	}
+	key := locatorKey(locator)
+	now := time.Now()
+	s.mu.Lock()
+	if elem, found := s.items[key]; found {
+		item := elem.Value.(*memoryItem)
+		if now.Sub(item.lastTouch) < memoryTouchInterval {
+			s.mu.Unlock()
+			return nil
+		}
+		item.lastTouch = now
+	}
+	s.mu.Unlock()
+	return recorder.Touch(ctx, locator)
+}
+
+// Index 返回 backend 的索引，backend 未启用索引时为 nil。
+func (s *memoryStore) Index() *Index {
+	if indexed, ok := s.backend.(Indexed); ok {
+		return indexed.Index()
+	}
+	return nil
+}
+
+func (s *memoryStore) Partial(ctx context.Context, locator Locator) (*PartialEntry, error) {
+	partials, ok := s.backend.(PartialStore)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return partials.Partial(ctx, locator)
+}
+
+func (s *memoryStore) DiscardPartial(ctx context.Context, locator Locator) error {
+	partials, ok := s.backend.(PartialStore)
+	if !ok {
+		return nil
+	}
+	return partials.DiscardPartial(ctx, locator)
+}
+
+func (s *memoryStore) lookup(key string) (*memoryItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*memoryItem), true
+}
+
+func (s *memoryStore) store(key string, generation uint64, entry Entry, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if load := s.loads[key]; load == nil || load.generation != generation {
+		return
+	}
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+	s.items[key] = s.order.PushFront(&memoryItem{key: key, entry: entry, body: body, lastTouch: time.Now()})
+	s.used += int64(len(body))
+	for s.used > s.opts.MaxBytes && s.order.Len() > 0 {
+		s.removeElement(s.order.Back())
+	}
+}
+
+// invalidate 丢弃条目的内存副本，磁盘淘汰器删除正文后也会调用。
+func (s *memoryStore) invalidate(locator Locator) {
+	key := locatorKey(locator)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if load := s.loads[key]; load != nil {
+		load.generation++
+	}
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+}
+
+func (s *memoryStore) removeElement(elem *list.Element) {
+	item := elem.Value.(*memoryItem)
+	s.order.Remove(elem)
+	delete(s.items, item.key)
+	s.used -= int64(len(item.body))
+}
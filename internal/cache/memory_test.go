@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStoreServesHotEntriesWithoutDisk(t *testing.T) {
+	backend := newTestStore(t)
+	store := NewMemoryStore(backend, MemoryOptions{MaxBytes: 1024})
+	locator := Locator{HubName: "npm", Path: "/lodash"}
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("v1"), PutOptions{Response: ResponseMetadata{ETag: `"v1"`}}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if body := readMemoryEntry(t, store, locator); body != "v1" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+
+	// 删除磁盘文件后仍能命中，说明读取未访问文件系统。
+	filePath, _ := backend.(*fileStore).entryPath(locator)
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("remove file: %v", err)
+	}
+	result, err := store.Get(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("expected memory hit, got %v", err)
+	}
+	result.Reader.Close()
+	if result.Entry.Response.ETag != `"v1"` {
+		t.Fatalf("memory hit lost metadata: %+v", result.Entry)
+	}
+
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("v2"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if body := readMemoryEntry(t, store, locator); body != "v2" {
+		t.Fatalf("put must invalidate the memory copy, got %q", body)
+	}
+	if err := store.Remove(context.Background(), locator); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+	if _, err := store.Get(context.Background(), locator); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("remove must invalidate the memory copy, got %v", err)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsedBySize(t *testing.T) {
+	backend := newTestStore(t)
+	store := NewMemoryStore(backend, MemoryOptions{MaxBytes: 10, MaxEntryBytes: 6}).(*memoryStore)
+	put := func(path, body string) Locator {
+		locator := Locator{HubName: "pypi", Path: path}
+		if _, err := store.Put(context.Background(), locator, strings.NewReader(body), PutOptions{}); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+		readMemoryEntry(t, store, locator)
+		return locator
+	}
+	a := put("/simple/a/", "aaaa")
+	b := put("/simple/b/", "bbbb")
+	readMemoryEntry(t, store, a)
+	put("/simple/c/", "cccc")
+	large := put("/simple/large/", "0123456789")
+
+	if _, ok := store.lookup(locatorKey(b)); ok {
+		t.Fatalf("least recently used entry should be evicted")
+	}
+	if _, ok := store.lookup(locatorKey(a)); !ok {
+		t.Fatalf("recently read entry should stay in memory")
+	}
+	if _, ok := store.lookup(locatorKey(large)); ok {
+		t.Fatalf("entries above MaxEntryBytes must bypass memory")
+	}
+	if store.used > 10 {
+		t.Fatalf("memory usage exceeds limit: %d", store.used)
+	}
+}
+
+func TestMemoryStoreAdmitsArtifactsOnSecondRead(t *testing.T) {
+	backend := newTestStore(t)
+	store := NewMemoryStore(backend, MemoryOptions{MaxBytes: 1024}).(*memoryStore)
+	locator := Locator{HubName: "pypi", Path: "/files/pkg-1.0.whl"}
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("wheel"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	readMemoryEntryWith(t, context.Background(), store, locator)
+	if _, ok := store.lookup(locatorKey(locator)); ok {
+		t.Fatalf("one-off downloads must not enter memory")
+	}
+	if body := readMemoryEntryWith(t, context.Background(), store, locator); body != "wheel" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if _, ok := store.lookup(locatorKey(locator)); !ok {
+		t.Fatalf("entries read twice should enter memory")
+	}
+}
+
+func TestMemoryStoreInvalidationOfOtherKeysKeepsAdmission(t *testing.T) {
+	backend := newTestStore(t)
+	store := NewMemoryStore(backend, MemoryOptions{MaxBytes: 1024}).(*memoryStore)
+	hot := Locator{HubName: "npm", Path: "/lodash"}
+	other := Locator{HubName: "npm", Path: "/react"}
+	if _, err := store.Put(context.Background(), hot, strings.NewReader("v1"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	key := locatorKey(hot)
+	generation := store.beginLoad(key)
+	if _, err := store.Put(context.Background(), other, strings.NewReader("other"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	store.store(key, generation, Entry{Locator: hot, SizeBytes: 2}, []byte("v1"))
+	store.endLoad(key)
+	if _, ok := store.lookup(key); !ok {
+		t.Fatalf("writes to other keys must not block admission")
+	}
+
+	generation = store.beginLoad(key)
+	store.invalidate(hot)
+	store.store(key, generation, Entry{Locator: hot, SizeBytes: 2}, []byte("v1"))
+	store.endLoad(key)
+	if _, ok := store.lookup(key); ok {
+		t.Fatalf("loads racing an invalidation of the same key must be dropped")
+	}
+	if len(store.loads) != 0 {
+		t.Fatalf("finished loads should be released: %d", len(store.loads))
+	}
+}
+
+func TestEvictorAcceptsMemoryLayeredStore(t *testing.T) {
+	store := NewMemoryStore(newTestStore(t), MemoryOptions{MaxBytes: 1024})
+	evictor, err := NewEvictor(store, EvictionOptions{GlobalLimit: 1}, nil)
+	if err != nil || evictor.memory == nil {
+		t.Fatalf("evictor should unwrap the memory layer: %v", err)
+	}
+}
+
+// readMemoryEntry 以需要再验证的方式读取，使条目首次读取即进入内存层。
+func readMemoryEntry(t *testing.T, store Store, locator Locator) string {
+	t.Helper()
+	return readMemoryEntryWith(t, WithRevalidation(context.Background()), store, locator)
+}
+
+func readMemoryEntryWith(t *testing.T, ctx context.Context, store Store, locator Locator) string {
+	t.Helper()
+	result, err := store.Get(ctx, locator)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	defer result.Reader.Close()
+	body, err := io.ReadAll(result.Reader)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	return string(body)
+}
@@ -0,0 +1,275 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// partialPrefix 标识中断下载保留的前缀文件，与正文位于同一目录：<dir>/.partial-<name>。
+const partialPrefix = ".partial-"
+
+// ErrPartialMismatch 表示续传时磁盘上的部分文件已不存在或大小与预期不符，调用方应从头下载。
+var ErrPartialMismatch = errors.New("partial download mismatch")
+
+// Partial 返回 Locator 中断下载时保留的前缀，不存在时返回 ErrNotFound。
+func (s *fileStore) Partial(ctx context.Context, locator Locator) (*PartialEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath, err := s.entryPath(locator)
+	if err != nil {
+		return nil, err
+	}
+	partial := partialPath(filePath)
+	info, err := os.Stat(partial)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || isNotDirError(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if info.IsDir() || info.Size() == 0 {
+		return nil, ErrNotFound
+	}
+	entry := &PartialEntry{Locator: locator, SizeBytes: info.Size()}
+	if metadata, err := s.readMetadata(partial); err == nil && metadata.Response != nil {
+		entry.Response = *metadata.Response
+	}
+	return entry, nil
+}
+
+// DiscardPartial 删除 Locator 保留的前缀文件，例如上游内容已变更无法续传时。
+func (s *fileStore) DiscardPartial(ctx context.Context, locator Locator) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := s.lockEntry(locator)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := s.entryPath(locator)
+	if err != nil {
+		return err
+	}
+	if err := removePartial(filePath); err != nil {
+		return err
+	}
+	s.forgetPartial(locator)
+	return nil
+}
+
+// reopenPartial 将前缀文件改名为新的临时文件并以追加模式打开，续传的正文直接写在其后。
+func (s *fileStore) reopenPartial(locator Locator, filePath string, size int64) (*os.File, error) {
+	partial := partialPath(filePath)
+	info, err := os.Stat(partial)
+	if err != nil || info.Size() != size {
+		return nil, fmt.Errorf("%w: expected %d bytes", ErrPartialMismatch, size)
+	}
+	placeholder, err := os.CreateTemp(filepath.Dir(filePath), ".cache-*")
+	if err != nil {
+		return nil, err
+	}
+	tempName := placeholder.Name()
+	placeholder.Close()
+	if err := os.Rename(partial, tempName); err != nil {
+		_ = os.Remove(tempName)
+		return nil, err
+	}
+	_ = os.Remove(metadataPath(partial))
+	s.forgetPartial(locator)
+	file, err := os.OpenFile(tempName, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		_ = os.Remove(tempName)
+		return nil, err
+	}
+	return file, nil
+}
+
+// keepPartial 在回源中断时把已写入的临时文件保留为前缀文件，并记录响应元数据供续传时校验。
+func (s *fileStore) keepPartial(locator Locator, tempName, filePath string, response ResponseMetadata) error {
+	partial := partialPath(filePath)
+	if err := os.Rename(tempName, partial); err != nil {
+		return err
+	}
+	metadata := entryMetadata{}
+	if !response.IsZero() {
+		metadata.Response = &response
+	}
+	if s.hashed(locator.HubName) {
+		metadata.Path = canonicalPath(locator.Path)
+	}
+	if err := s.writeMetadata(partial, metadata); err != nil {
+		return err
+	}
+	if s.index != nil {
+		info, err := os.Stat(partial)
+		if err != nil {
+			return err
+		}
+		return s.index.putPartial(locator, partialRecord{SizeBytes: info.Size(), ModTime: info.ModTime()})
+	}
+	return nil
+}
+
+// forgetPartial 在前缀文件被续传、删除或回收后清理其索引记录。
+func (s *fileStore) forgetPartial(locator Locator) {
+	if s.index != nil {
+		_ = s.index.removePartial(locator)
+	}
+}
+
+// partialEntries 返回全部前缀文件的淘汰快照：启用索引时读取 partials 记录并确认文件仍在
+// （cache gc 等命令不持有索引，删除的前缀文件在此清理），否则遍历目录。
+func (s *fileStore) partialEntries(ctx context.Context) ([]diskEntry, error) {
+	if s.index == nil {
+		return s.scanPartials(ctx)
+	}
+	var entries []diskEntry
+	err := s.index.listPartials(ctx, func(locator Locator, record partialRecord) error {
+		filePath, err := s.entryPath(locator)
+		if err != nil {
+			return nil
+		}
+		partial := partialPath(filePath)
+		if _, err := os.Stat(partial); err != nil {
+			s.forgetPartial(locator)
+			return nil
+		}
+		entries = append(entries, diskEntry{
+			locator:    locator,
+			filePath:   partial,
+			size:       record.SizeBytes,
+			accessTime: record.ModTime,
+			modTime:    record.ModTime,
+			partial:    true,
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// scanPartials 遍历 <basePath>/<hub>/ 下的 .partial-* 前缀文件。
+func (s *fileStore) scanPartials(ctx context.Context) ([]diskEntry, error) {
+	hubs, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, err
+	}
+	var entries []diskEntry
+	for _, hubDir := range hubs {
+		if !hubDir.IsDir() || hubDir.Name() == BlobsDirName {
+			continue
+		}
+		err := filepath.WalkDir(filepath.Join(s.basePath, hubDir.Name()), func(filePath string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				if errors.Is(walkErr, fs.ErrNotExist) {
+					return nil
+				}
+				return walkErr
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() || !isPartialFileName(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			locator, ok := s.partialLocator(filePath)
+			if !ok {
+				return nil
+			}
+			entries = append(entries, diskEntry{
+				locator:    locator,
+				filePath:   filePath,
+				size:       info.Size(),
+				accessTime: info.ModTime(),
+				modTime:    info.ModTime(),
+				partial:    true,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// partialLocator 还原前缀文件所属的 Locator；hashed 布局取前缀文件 .meta 中记录的原始路径。
+func (s *fileStore) partialLocator(partial string) (Locator, bool) {
+	locator, ok := s.locatorForFile(partialBodyPath(partial))
+	if !ok {
+		return Locator{}, false
+	}
+	if metadata, err := s.readMetadata(partial); err == nil && metadata.Path != "" {
+		locator.Path = metadata.Path
+	}
+	return locator, true
+}
+
+// removeStalePartial 在持有条目锁的前提下删除 mtime 仍为 modTime 的前缀文件；
+// 文件已被续传或重新保留时返回 false。
+func (s *fileStore) removeStalePartial(locator Locator, partial string, modTime time.Time) (bool, error) {
+	unlock, ok := s.tryLockEntry(locator)
+	if !ok {
+		return false, nil
+	}
+	defer unlock()
+	info, err := os.Stat(partial)
+	if err != nil || !info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+	if err := removePartial(partialBodyPath(partial)); err != nil {
+		return false, err
+	}
+	s.forgetPartial(locator)
+	return true, nil
+}
+
+// feedPartial 将续传前已存在的前缀交给校验器，使摘要覆盖完整正文。
+func feedPartial(file *os.File, size int64, verifier Verifier) error {
+	if verifier == nil || size <= 0 {
+		return nil
+	}
+	_, err := io.Copy(verifier, io.NewSectionReader(file, 0, size))
+	return err
+}
+
+func removePartial(filePath string) error {
+	partial := partialPath(filePath)
+	if err := os.Remove(partial); err != nil && !errors.Is(err, fs.ErrNotExist) && !isNotDirError(err) {
+		return err
+	}
+	if err := os.Remove(metadataPath(partial)); err != nil && !errors.Is(err, fs.ErrNotExist) && !isNotDirError(err) {
+		return err
+	}
+	return nil
+}
+
+func partialPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), partialPrefix+filepath.Base(filePath))
+}
+
+// partialBodyPath 是 partialPath 的逆运算。
+func partialBodyPath(partial string) string {
+	return filepath.Join(filepath.Dir(partial), strings.TrimPrefix(filepath.Base(partial), partialPrefix))
+}
+
+// isPartialFileName 判断文件名是否为前缀文件本身（不含其 .meta）。
+func isPartialFileName(name string) bool {
+	return strings.HasPrefix(name, partialPrefix) && !strings.HasSuffix(name, ".meta")
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultRetentionInterval = time.Hour
+
+// RetentionRule 描述一条 Hub 级保留规则，按 path.Match 语法匹配 Locator.Path。
+type RetentionRule struct {
+	Hub   string
+	Match string
+	// MaxAge 大于 0 时，写入缓存超过该时长的条目会被删除（按 .meta 记录的写入时间，而非上游 Last-Modified）。
+	MaxAge time.Duration
+	// MaxVersions 大于 0 时，同一目录（视为同一包）下匹配的条目只保留最近写入的 MaxVersions 个。
+	MaxVersions int
+	// Pinned 为 true 时匹配的条目永不被淘汰、保留策略或管理接口清除。
+	Pinned bool
+}
+
+// RetentionPolicy 汇总所有 Hub 的保留规则，nil 表示没有任何规则。
+type RetentionPolicy struct {
+	rules map[string][]RetentionRule
+}
+
+// NewRetentionPolicy 按 Hub 分组保留规则；没有规则时返回 nil。
+func NewRetentionPolicy(rules []RetentionRule) *RetentionPolicy {
+	if len(rules) == 0 {
+		return nil
+	}
+	policy := &RetentionPolicy{rules: make(map[string][]RetentionRule)}
+	for _, rule := range rules {
+		policy.rules[rule.Hub] = append(policy.rules[rule.Hub], rule)
+	}
+	return policy
+}
+
+// Pinned 判断 Locator 是否命中某条 Pinned 规则。
+func (p *RetentionPolicy) Pinned(locator Locator) bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules[locator.HubName] {
+		if rule.Pinned && rule.matches(locator.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// expiring 判断是否存在需要周期执行的 MaxAge/MaxVersions 规则。
+func (p *RetentionPolicy) expiring() bool {
+	if p == nil {
+		return false
+	}
+	for _, rules := range p.rules {
+		for _, rule := range rules {
+			if !rule.Pinned && (rule.MaxAge > 0 || rule.MaxVersions > 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r RetentionRule) matches(p string) bool {
+	matched, _ := path.Match(r.Match, p)
+	return matched
+}
+
+// IsPinned 判断条目是否受保护：命中 Pinned 规则，或已通过 Pinner 固定。条目不存在时返回 false。
+func IsPinned(ctx context.Context, store Store, policy *RetentionPolicy, locator Locator) (bool, error) {
+	if policy.Pinned(locator) {
+		return true, nil
+	}
+	if _, ok := store.(Pinner); !ok {
+		return false, nil
+	}
+	result, err := store.Get(ctx, locator)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	result.Reader.Close()
+	return result.Entry.Pinned, nil
+}
+
+// RemoveUnpinned 删除未受保护的条目：命中 Pinned 规则或已固定时返回 ErrPinned。固定状态在条目锁内检查，
+// 不会与并发的 Pin 交错；Store 不支持固定时直接 Remove。
+func RemoveUnpinned(ctx context.Context, store Store, policy *RetentionPolicy, locator Locator) error {
+	if policy.Pinned(locator) {
+		return ErrPinned
+	}
+	if pinner, ok := store.(Pinner); ok {
+		return pinner.RemoveUnpinned(ctx, locator)
+	}
+	return store.Remove(ctx, locator)
+}
+
+// RetentionOptions 控制保留策略的执行节奏。
+type RetentionOptions struct {
+	// Interval 为后台执行周期，默认 1 小时。
+	Interval time.Duration
+}
+
+// RetentionReport 汇总一次执行的结果。
+type RetentionReport struct {
+	// Expired 为超过 MaxAge 被删除的条目数。
+	Expired int
+	// Trimmed 为超出 MaxVersions 被删除的条目数。
+	Trimmed int
+	// Pinned 为本应删除、但因固定而保留的条目数。
+	Pinned int
+	// FreedBytes 为删除的正文字节数。
+	FreedBytes int64
+}
+
+// String 便于日志与测试输出一行汇总。
+func (r RetentionReport) String() string {
+	return fmt.Sprintf("expired=%d trimmed=%d pinned=%d freed_bytes=%d", r.Expired, r.Trimmed, r.Pinned, r.FreedBytes)
+}
+
+// Retainer 周期性执行 MaxAge/MaxVersions 规则。它只依赖 Store 的 Walk/Get/Remove 能力，
+// 删除经 RemoveUnpinned 执行，在与写入、Pin 共用的条目锁内确认条目未被固定，所有动作以 action=cache_retention 记录日志。
+type Retainer struct {
+	store  Store
+	policy *RetentionPolicy
+	opts   RetentionOptions
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewRetainer 基于 Store 与保留规则构建执行器。
+func NewRetainer(store Store, policy *RetentionPolicy, opts RetentionOptions, logger *logrus.Logger) *Retainer {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultRetentionInterval
+	}
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	return &Retainer{store: store, policy: policy, opts: opts, logger: logger, now: time.Now}
+}
+
+// Enabled 表示是否配置了需要周期执行的规则；只有 Pinned 规则时无需启动后台任务。
+func (r *Retainer) Enabled() bool {
+	return r.policy.expiring()
+}
+
+// Run 立即执行一次，之后按 Interval 周期执行，直到 ctx 结束。
+func (r *Retainer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		r.Enforce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retentionCandidate 为一次执行中待删除的条目及原因。
+type retentionCandidate struct {
+	info   EntryInfo
+	reason string
+}
+
+// Enforce 逐个 Hub 执行一次保留规则并输出汇总日志。
+func (r *Retainer) Enforce(ctx context.Context) (RetentionReport, error) {
+	var report RetentionReport
+	if r.policy == nil {
+		return report, nil
+	}
+	hubs := make([]string, 0, len(r.policy.rules))
+	for hub := range r.policy.rules {
+		hubs = append(hubs, hub)
+	}
+	sort.Strings(hubs)
+
+	var err error
+	for _, hub := range hubs {
+		if err = r.enforceHub(ctx, hub, &report); err != nil {
+			break
+		}
+	}
+	r.log(report, err)
+	return report, err
+}
+
+func (r *Retainer) enforceHub(ctx context.Context, hub string, report *RetentionReport) error {
+	rules := r.policy.rules[hub]
+	// 先收集再删除：索引遍历处于只读事务中，不能在回调内写索引。
+	var entries []EntryInfo
+	err := WalkEntries(ctx, r.store, hub, func(info EntryInfo) error {
+		if r.policy.Pinned(info.Locator) {
+			return nil
+		}
+		for _, rule := range rules {
+			if !rule.Pinned && rule.matches(info.Locator.Path) {
+				entries = append(entries, info)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	now := r.now()
+	candidates := make(map[Locator]retentionCandidate)
+	for _, rule := range rules {
+		if rule.Pinned {
+			continue
+		}
+		packages := make(map[string][]EntryInfo)
+		for _, info := range entries {
+			if !rule.matches(info.Locator.Path) {
+				continue
+			}
+			// 写入时间未知的旧条目不按 MaxAge 过期，避免把上游发布时间误当作缓存时间。
+			if rule.MaxAge > 0 && !info.CachedAt.IsZero() && now.Sub(info.CachedAt) > rule.MaxAge {
+				candidates[info.Locator] = retentionCandidate{info: info, reason: "max_age"}
+				continue
+			}
+			if rule.MaxVersions > 0 {
+				dir := path.Dir(info.Locator.Path)
+				packages[dir] = append(packages[dir], info)
+			}
+		}
+		for _, versions := range packages {
+			if len(versions) <= rule.MaxVersions {
+				continue
+			}
+			sort.Slice(versions, func(i, j int) bool {
+				return versions[i].CachedAt.After(versions[j].CachedAt)
+			})
+			for _, info := range versions[rule.MaxVersions:] {
+				if _, ok := candidates[info.Locator]; !ok {
+					candidates[info.Locator] = retentionCandidate{info: info, reason: "max_versions"}
+				}
+			}
+		}
+	}
+
+	ordered := make([]retentionCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		ordered = append(ordered, candidate)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].info.Locator.Path < ordered[j].info.Locator.Path
+	})
+	for _, candidate := range ordered {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.remove(ctx, candidate, report)
+	}
+	return nil
+}
+
+func (r *Retainer) remove(ctx context.Context, candidate retentionCandidate, report *RetentionReport) {
+	locator := candidate.info.Locator
+	entry := r.logger.WithFields(logrus.Fields{
+		"action": "cache_retention",
+		"hub":    locator.HubName,
+		"path":   locator.Path,
+		"reason": candidate.reason,
+		"bytes":  candidate.info.SizeBytes,
+	})
+	if err := RemoveUnpinned(ctx, r.store, r.policy, locator); err != nil {
+		if errors.Is(err, ErrPinned) {
+			report.Pinned++
+			return
+		}
+		entry.WithError(err).Warn("cache_retention_failed")
+		return
+	}
+	if candidate.reason == "max_age" {
+		report.Expired++
+	} else {
+		report.Trimmed++
+	}
+	report.FreedBytes += candidate.info.SizeBytes
+	entry.Info("cache_retention_removed")
+}
+
+func (r *Retainer) log(report RetentionReport, err error) {
+	fields := logrus.Fields{
+		"action":      "cache_retention",
+		"expired":     report.Expired,
+		"trimmed":     report.Trimmed,
+		"pinned":      report.Pinned,
+		"freed_bytes": report.FreedBytes,
+	}
+	if err != nil {
+		r.logger.WithFields(fields).WithError(err).Warn("cache_retention_failed")
+		return
+	}
+	r.logger.WithFields(fields).Info("cache_retention_complete")
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetainerEnforcesMaxAgeAndMaxVersions(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"/nightly/tool/v0.zip": 20 * 24 * time.Hour,
+		"/nightly/tool/v1.zip": 10 * 24 * time.Hour,
+		"/nightly/tool/v2.zip": 4 * 24 * time.Hour,
+		"/nightly/tool/v3.zip": 3 * 24 * time.Hour,
+		"/nightly/tool/v4.zip": 2 * 24 * time.Hour,
+		"/nightly/tool/v5.zip": 24 * time.Hour,
+		"/stable/tool/v1.zip":  30 * 24 * time.Hour,
+	}
+	// 上游 Last-Modified 一律是两年前：保留策略只看写入缓存的时间。
+	published := now.Add(-2 * 365 * 24 * time.Hour)
+	for p, age := range ages {
+		locator := Locator{HubName: "go", Path: p}
+		if _, err := store.Put(ctx, locator, strings.NewReader(p), PutOptions{ModTime: published}); err != nil {
+			t.Fatalf("put %s error: %v", p, err)
+		}
+		setCachedAt(t, store, locator, now.Add(-age))
+	}
+	fresh := Locator{HubName: "go", Path: "/nightly/fresh/v1.zip"}
+	if _, err := store.Put(ctx, fresh, strings.NewReader("fresh"), PutOptions{ModTime: published}); err != nil {
+		t.Fatalf("put fresh error: %v", err)
+	}
+	legacy := Locator{HubName: "go", Path: "/nightly/legacy/v1.zip"}
+	if _, err := store.Put(ctx, legacy, strings.NewReader("legacy"), PutOptions{ModTime: published}); err != nil {
+		t.Fatalf("put legacy error: %v", err)
+	}
+	setCachedAt(t, store, legacy, time.Time{})
+	if err := store.(Pinner).Pin(ctx, Locator{HubName: "go", Path: "/nightly/tool/v3.zip"}, true); err != nil {
+		t.Fatalf("pin error: %v", err)
+	}
+
+	policy := NewRetentionPolicy([]RetentionRule{
+		{Hub: "go", Match: "/nightly/tool/v0.zip", Pinned: true},
+		{Hub: "go", Match: "/nightly/*/*", MaxAge: 5 * 24 * time.Hour, MaxVersions: 2},
+	})
+	retainer := NewRetainer(store, policy, RetentionOptions{}, nil)
+	if !retainer.Enabled() {
+		t.Fatalf("expiring rules should enable the retainer")
+	}
+	report, err := retainer.Enforce(ctx)
+	if err != nil {
+		t.Fatalf("enforce error: %v", err)
+	}
+	if report.Expired != 1 || report.Trimmed != 1 || report.Pinned != 1 {
+		t.Fatalf("unexpected report: %s", report)
+	}
+
+	for _, p := range []string{"/nightly/tool/v1.zip", "/nightly/tool/v2.zip"} {
+		if _, err := store.Get(ctx, Locator{HubName: "go", Path: p}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s should have been removed, got %v", p, err)
+		}
+	}
+	for _, p := range []string{"/nightly/tool/v0.zip", "/nightly/tool/v3.zip", "/nightly/tool/v4.zip", "/nightly/tool/v5.zip", "/stable/tool/v1.zip", fresh.Path, legacy.Path} {
+		result, err := store.Get(ctx, Locator{HubName: "go", Path: p})
+		if err != nil {
+			t.Fatalf("%s should be retained: %v", p, err)
+		}
+		result.Reader.Close()
+	}
+}
+
+// setCachedAt 改写条目 .meta 中的写入时间，零值模拟旧版本写入、未记录 cached_at 的条目。
+func setCachedAt(t *testing.T, store Store, locator Locator, at time.Time) {
+	t.Helper()
+	fsStore := store.(*fileStore)
+	filePath, err := fsStore.entryPath(locator)
+	if err != nil {
+		t.Fatalf("entry path: %v", err)
+	}
+	metadata, err := fsStore.readMetadata(filePath)
+	if err != nil {
+		t.Fatalf("read metadata: %v", err)
+	}
+	metadata.CachedAt = at
+	if err := fsStore.writeMetadata(filePath, metadata); err != nil {
+		t.Fatalf("write metadata: %v", err)
+	}
+}
+
+func TestPinSurvivesOverwriteAndIndexLookup(t *testing.T) {
+	store := newIndexedStore(t, t.TempDir())
+	ctx := context.Background()
+	locator := Locator{HubName: "docker", Path: "/v2/library/golang/manifests/1.25"}
+	if err := store.(Pinner).Pin(ctx, locator, true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("pinning a missing entry should return ErrNotFound, got %v", err)
+	}
+	if _, err := store.Put(ctx, locator, strings.NewReader("v1"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if err := store.(Pinner).Pin(ctx, locator, true); err != nil {
+		t.Fatalf("pin error: %v", err)
+	}
+	if _, err := store.Put(ctx, locator, strings.NewReader("v2"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	result, err := store.Get(ctx, locator)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	result.Reader.Close()
+	if !result.Entry.Pinned {
+		t.Fatalf("pin should survive an overwrite: %+v", result.Entry)
+	}
+	record, err := store.(Indexed).Index().Lookup(locator)
+	if err != nil || !record.Pinned {
+		t.Fatalf("index should record the pin: %+v %v", record, err)
+	}
+
+	if err := store.(Pinner).Pin(ctx, locator, false); err != nil {
+		t.Fatalf("unpin error: %v", err)
+	}
+	if pinned, err := IsPinned(ctx, store, nil, locator); err != nil || pinned {
+		t.Fatalf("entry should be unpinned: %v %v", pinned, err)
+	}
+}
+
+func TestRemoveUnpinnedKeepsPinnedEntries(t *testing.T) {
+	store := newIndexedStore(t, t.TempDir())
+	ctx := context.Background()
+	pinned := Locator{HubName: "docker", Path: "/v2/library/golang/manifests/1.25"}
+	plain := Locator{HubName: "docker", Path: "/v2/library/golang/manifests/1.24"}
+	for _, locator := range []Locator{pinned, plain} {
+		if _, err := store.Put(ctx, locator, strings.NewReader("v1"), PutOptions{}); err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+	}
+	if err := store.(Pinner).Pin(ctx, pinned, true); err != nil {
+		t.Fatalf("pin error: %v", err)
+	}
+
+	if err := RemoveUnpinned(ctx, store, nil, pinned); !errors.Is(err, ErrPinned) {
+		t.Fatalf("pinned entry should be refused, got %v", err)
+	}
+	if err := RemoveUnpinned(ctx, store, nil, plain); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+	if _, err := store.Get(ctx, plain); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unpinned entry should be removed, got %v", err)
+	}
+	result, err := store.Get(ctx, pinned)
+	if err != nil {
+		t.Fatalf("pinned entry should remain: %v", err)
+	}
+	result.Reader.Close()
+
+	policy := NewRetentionPolicy([]RetentionRule{{Hub: "docker", Match: "/v2/library/golang/manifests/*", Pinned: true}})
+	if _, err := store.Put(ctx, plain, strings.NewReader("v1"), PutOptions{}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if err := RemoveUnpinned(ctx, store, policy, plain); !errors.Is(err, ErrPinned) {
+		t.Fatalf("entries matched by a Pinned rule should be refused, got %v", err)
+	}
+}
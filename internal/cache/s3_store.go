@@ -0,0 +1,645 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3PartSize = 8 << 20
+	// minS3PartSize 为 S3 对除最后一片外各分片的最小要求。
+	minS3PartSize   = 5 << 20
+	defaultS3Region = "us-east-1"
+	// s3MetadataHeader 以 base64(JSON) 形式保存 entryMetadata 与 ModTime，
+	// 与正文在同一次提交中生效，避免旁路对象与正文不一致。
+	s3MetadataHeader = "X-Amz-Meta-Anyhub"
+	// S3 用户元数据总量上限为 2KB，超出时放弃回放头部，仅保留校验器。
+	maxS3MetadataBytes = 1800
+)
+
+// S3Options 描述 S3 兼容对象存储的连接参数。对象统一以 path-style
+// （<Endpoint>/<Bucket>/<Prefix>/<HubName>/<path>）访问，兼容 MinIO、Ceph RGW 等实现。
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PartSize 为分片上传的单片大小，默认 8MiB，不得小于 S3 的 5MiB 下限；小于一个分片的正文直接以 PutObject 写入。
+	PartSize int64
+	// Client 为空时使用 http.DefaultClient。
+	Client *http.Client
+}
+
+// NewS3Store 构建基于 S3 兼容对象存储的 Store，多个 any-hub 实例可共享同一个 bucket。
+// 正文以分片上传流式写入，CompleteMultipartUpload 之前对象不可见，满足 Put 的原子可见性约定；
+// 元数据随对象的用户元数据一起提交。该实现不支持断点前缀保留与磁盘淘汰。
+func NewS3Store(opts S3Options) (Store, error) {
+	if opts.PartSize != 0 && opts.PartSize < minS3PartSize {
+		return nil, fmt.Errorf("s3 part size %d is below the %d byte minimum", opts.PartSize, minS3PartSize)
+	}
+	return newS3Store(opts)
+}
+
+// newS3Store 构建 Store 而不检查分片下限，测试中的假 S3 借此以很小的分片覆盖分片上传。
+func newS3Store(opts S3Options) (Store, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(opts.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.New("s3 endpoint must be an http(s) URL")
+	}
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket required")
+	}
+	region := opts.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = defaultS3PartSize
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &s3Store{
+		endpoint: endpoint,
+		bucket:   opts.Bucket,
+		prefix:   strings.Trim(opts.Prefix, "/"),
+		partSize: partSize,
+		client:   client,
+		signer: s3Signer{
+			accessKeyID:     opts.AccessKeyID,
+			secretAccessKey: opts.SecretAccessKey,
+			region:          region,
+		},
+	}, nil
+}
+
+type s3Store struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	partSize int64
+	client   *http.Client
+	signer   s3Signer
+}
+
+// s3ObjectMetadata 为对象用户元数据的内容；ModTime 语义与磁盘实现的文件 mtime 一致。
+type s3ObjectMetadata struct {
+	entryMetadata
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (s *s3Store) Get(ctx context.Context, locator Locator) (*ReadResult, error) {
+	key, err := s.objectKey(locator)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3ResponseError(resp, "get object")
+	}
+	entry, err := s.entryFromResponse(ctx, locator, key, resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	reader := &s3Reader{ctx: ctx, store: s, key: key, size: entry.SizeBytes, body: resp.Body}
+	return &ReadResult{Entry: entry, Reader: reader}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, locator Locator, body io.Reader, opts PutOptions) (*Entry, error) {
+	if opts.ResumeFrom > 0 {
+		// 对象存储不保留中断前缀，调用方应退回完整回源。
+		return nil, ErrPartialMismatch
+	}
+	key, err := s.objectKey(locator)
+	if err != nil {
+		return nil, err
+	}
+
+	digestHex, shared := parseSHA256Digest(opts.Digest)
+	verifier := opts.Verifier
+	if verifier == nil && shared {
+		verifier, _ = NewVerifier(IntegritySHA256, digestHex)
+	}
+	// 校验器可能需要读取完整文件（Go 模块 zip），因此同时落一份本地临时副本。
+	var spool *os.File
+	sink := io.Discard
+	if verifier != nil {
+		spool, err = os.CreateTemp("", ".cache-s3-*")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			spool.Close()
+			_ = os.Remove(spool.Name())
+		}()
+		sink = io.MultiWriter(spool, verifier)
+	}
+
+	modTime := opts.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now().UTC()
+	}
+	metadata := s3ObjectMetadata{
+		entryMetadata: entryMetadata{EffectiveUpstreamPath: opts.EffectiveUpstreamPath},
+		ModTime:       modTime.UTC(),
+	}
+	if !opts.Response.IsZero() {
+		response := opts.Response
+		metadata.Response = &response
+	}
+	if shared {
+		metadata.Digest = "sha256:" + digestHex
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set(s3MetadataHeader, encodeS3Metadata(metadata))
+
+	upload := &s3Upload{store: s, key: key, header: header}
+	verify := func() error {
+		if verifier == nil {
+			return nil
+		}
+		return verifier.Verify(spool.Name())
+	}
+	buf := make([]byte, s.partSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			upload.abort()
+			return nil, err
+		}
+		n, eof, readErr := readPart(body, buf)
+		if n > 0 {
+			if _, err := sink.Write(buf[:n]); err != nil {
+				upload.abort()
+				return nil, err
+			}
+			written += int64(n)
+		}
+		if readErr != nil {
+			upload.abort()
+			return nil, readErr
+		}
+		if eof && upload.id == "" {
+			// 正文不足一个分片：校验通过后以单次 PutObject 提交。
+			if err := verify(); err != nil {
+				return nil, err
+			}
+			if err := upload.putObject(ctx, buf[:n]); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if n > 0 {
+			if err := upload.uploadPart(ctx, buf[:n]); err != nil {
+				upload.abort()
+				return nil, err
+			}
+		}
+		if eof {
+			if err := verify(); err != nil {
+				upload.abort()
+				return nil, err
+			}
+			if err := upload.complete(ctx); err != nil {
+				upload.abort()
+				return nil, err
+			}
+			break
+		}
+	}
+
+	entry := Entry{
+		Locator:               locator,
+		FilePath:              s.objectLocation(key),
+		SizeBytes:             written,
+		ModTime:               metadata.ModTime,
+		EffectiveUpstreamPath: opts.EffectiveUpstreamPath,
+		Digest:                metadata.Digest,
+		Response:              opts.Response,
+	}
+	return &entry, nil
+}
+
+func (s *s3Store) Remove(ctx context.Context, locator Locator) error {
+	key, err := s.objectKey(locator)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s3ResponseError(resp, "delete object")
+	}
+}
+
+// UpdateResponse 通过 CopyObject（metadata-directive=REPLACE）原地替换对象元数据，正文不变。
+func (s *s3Store) UpdateResponse(ctx context.Context, locator Locator, response ResponseMetadata) error {
+	key, err := s.objectKey(locator)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp, "head object")
+	}
+	metadata, err := decodeS3Metadata(resp.Header.Get(s3MetadataHeader))
+	if err != nil {
+		return err
+	}
+	metadata.Response = nil
+	if !response.IsZero() {
+		metadata.Response = &response
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set(s3MetadataHeader, encodeS3Metadata(metadata))
+	header.Set("X-Amz-Copy-Source", "/"+s.bucket+"/"+s3EscapePath(key))
+	header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	resp, err = s.do(ctx, http.MethodPut, key, nil, header, nil)
+	if err != nil {
+		return err
+	}
+	return s3CheckResult(resp, "copy object")
+}
+
+func (s *s3Store) entryFromResponse(ctx context.Context, locator Locator, key string, resp *http.Response) (Entry, error) {
+	metadata, err := decodeS3Metadata(resp.Header.Get(s3MetadataHeader))
+	if err != nil {
+		return Entry{}, err
+	}
+	size, err := s.objectSize(ctx, key, resp)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		Locator:               locator,
+		FilePath:              s.objectLocation(key),
+		SizeBytes:             size,
+		ModTime:               metadata.ModTime,
+		EffectiveUpstreamPath: metadata.EffectiveUpstreamPath,
+		Digest:                metadata.Digest,
+	}
+	if entry.ModTime.IsZero() {
+		entry.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	}
+	if metadata.Response != nil {
+		entry.Response = *metadata.Response
+	}
+	return entry, nil
+}
+
+// objectSize 返回 GET 响应对应对象的总大小。分块传输的响应没有 Content-Length，
+// 依次回退到 Content-Range 的总长与一次 HEAD 请求，避免以 -1 作为条目大小。
+func (s *s3Store) objectSize(ctx context.Context, key string, resp *http.Response) (int64, error) {
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength, nil
+	}
+	if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+		if size, err := strconv.ParseInt(total, 10, 64); err == nil && size >= 0 {
+			return size, nil
+		}
+	}
+	head, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	head.Body.Close()
+	if head.StatusCode == http.StatusNotFound {
+		return 0, ErrNotFound
+	}
+	if head.StatusCode != http.StatusOK {
+		return 0, s3ResponseError(head, "head object")
+	}
+	if head.ContentLength < 0 {
+		return 0, errors.New("s3 object size unknown")
+	}
+	return head.ContentLength, nil
+}
+
+// objectKey 将 Locator 映射为对象键，清理规则与磁盘实现的 entryPath 保持一致。
+func (s *s3Store) objectKey(locator Locator) (string, error) {
+	if locator.HubName == "" {
+		return "", errors.New("hub name required")
+	}
+	rel := locator.Path
+	if rel == "" || rel == "/" {
+		rel = "root"
+	}
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if rel == "" {
+		rel = "root"
+	}
+	key := locator.HubName + "/" + rel
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return key, nil
+}
+
+func (s *s3Store) objectLocation(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
+
+func (s *s3Store) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	target := *s.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + s.bucket + "/" + key
+	target.RawPath = s3EscapePath(target.Path)
+	target.RawQuery = canonicalS3Query(query)
+
+	var reader io.Reader = http.NoBody
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	s.signer.sign(req, payloadHash)
+	return s.client.Do(req)
+}
+
+// s3Upload 管理一次写入：首个分片到达时才发起分片上传，失败时 abort 清理已上传的分片。
+type s3Upload struct {
+	store  *s3Store
+	key    string
+	header http.Header
+	id     string
+	parts  []s3CompletedPart
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (u *s3Upload) putObject(ctx context.Context, data []byte) error {
+	resp, err := u.store.do(ctx, http.MethodPut, u.key, nil, u.header, data)
+	if err != nil {
+		return err
+	}
+	return s3CheckResult(resp, "put object")
+}
+
+func (u *s3Upload) uploadPart(ctx context.Context, data []byte) error {
+	if u.id == "" {
+		if err := u.initiate(ctx); err != nil {
+			return err
+		}
+	}
+	number := len(u.parts) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {u.id}}
+	resp, err := u.store.do(ctx, http.MethodPut, u.key, query, nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp, "upload part")
+	}
+	u.parts = append(u.parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	return nil
+}
+
+func (u *s3Upload) initiate(ctx context.Context) error {
+	resp, err := u.store.do(ctx, http.MethodPost, u.key, url.Values{"uploads": {""}}, u.header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp, "create multipart upload")
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode multipart upload: %w", err)
+	}
+	if result.UploadID == "" {
+		return errors.New("s3 create multipart upload: empty upload id")
+	}
+	u.id = result.UploadID
+	return nil
+}
+
+func (u *s3Upload) complete(ctx context.Context) error {
+	payload, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: u.parts})
+	if err != nil {
+		return err
+	}
+	resp, err := u.store.do(ctx, http.MethodPost, u.key, url.Values{"uploadId": {u.id}}, nil, payload)
+	if err != nil {
+		return err
+	}
+	return s3CheckResult(resp, "complete multipart upload")
+}
+
+// abort 使用独立 context，确保请求被取消后仍能清理服务端的未完成分片。
+func (u *s3Upload) abort() {
+	if u.id == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := u.store.do(ctx, http.MethodDelete, u.key, url.Values{"uploadId": {u.id}}, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+	u.id = ""
+}
+
+// s3Reader 在首个 GET 的正文上顺序读取；Seek 后按需以 Range 请求重新打开，供代理层切分 Range。
+type s3Reader struct {
+	ctx    context.Context
+	store  *s3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *s3Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", r.offset)}}
+		resp, err := r.store.do(r.ctx, http.MethodGet, r.key, nil, header, nil)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			return 0, s3ResponseError(resp, "get object range")
+		}
+		r.body = resp.Body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *s3Reader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.offset + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if target < 0 {
+		return 0, errors.New("negative position")
+	}
+	if target != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = target
+	return target, nil
+}
+
+func (r *s3Reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// readPart 尽量填满 buf；与 io.ReadFull 不同，它把上游返回的 ErrUnexpectedEOF
+// 视为错误而不是正常结束，避免被截断的正文被当作完整对象提交。
+func readPart(r io.Reader, buf []byte) (int, bool, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if errors.Is(err, io.EOF) {
+			return n, true, nil
+		}
+		if err != nil {
+			return n, false, err
+		}
+	}
+	return n, false, nil
+}
+
+func encodeS3Metadata(metadata s3ObjectMetadata) string {
+	data, _ := json.Marshal(metadata)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if len(encoded) > maxS3MetadataBytes && metadata.Response != nil && len(metadata.Response.Headers) > 0 {
+		trimmed := *metadata.Response
+		trimmed.Headers = nil
+		metadata.Response = &trimmed
+		return encodeS3Metadata(metadata)
+	}
+	return encoded
+}
+
+func decodeS3Metadata(raw string) (s3ObjectMetadata, error) {
+	var metadata s3ObjectMetadata
+	if raw == "" {
+		return metadata, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return metadata, fmt.Errorf("decode s3 metadata: %w", err)
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return metadata, fmt.Errorf("decode s3 metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// s3CheckResult 处理 CompleteMultipartUpload/CopyObject 等可能在 200 正文中返回 <Error> 的接口。
+func s3CheckResult(resp *http.Response, op string) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp, op)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		return fmt.Errorf("s3 %s: %s", op, s3ErrorCode(data))
+	}
+	return nil
+}
+
+func s3ResponseError(resp *http.Response, op string) error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if code := s3ErrorCode(data); code != "" {
+		return fmt.Errorf("s3 %s: %s (%s)", op, resp.Status, code)
+	}
+	return fmt.Errorf("s3 %s: %s", op, resp.Status)
+}
+
+func s3ErrorCode(data []byte) string {
+	var payload struct {
+		Code string `xml:"Code"`
+	}
+	if err := xml.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+	return payload.Code
+}
@@ -9,9 +9,16 @@ import (
 
 // Store 负责管理磁盘缓存的读写。磁盘布局遵循：
 //
-//	<StoragePath>/<HubName>/<path>    # 实际正文（与请求路径一致）
+//	<StoragePath>/<HubName>/<path>                    # raw_path 布局（默认）的正文，与请求路径一致
+//	<StoragePath>/<HubName>/.h/<aa>/<bb>/<sha256>     # hashed 布局的正文，<sha256> 为规范化请求路径的摘要
+//	<正文路径>.meta                                    # 可选的元数据旁路文件（校验器、回放头部等）
+//	<StoragePath>/blobs/sha256/<hex>                  # 按摘要共享的不可变正文，Hub 路径以硬链接引用
 //
-// 每个条目仅由正文文件组成，文件的 ModTime/Size 由文件系统提供。
+// hashed 布局的 <aa>/<bb> 取摘要前两字节的十六进制，原始路径记录在 .meta 中，供列举与淘汰还原 Locator。
+// 使用 hashed 布局的 Hub 保留其目录下的 .h 子目录：迁移整棵跳过该子树，以 /.h/ 开头的 raw_path 旧副本不会被迁移；
+// blobs 同理是保留的 Hub 名称（见 ReservedHubName）。
+//
+// Size 由文件系统提供；ModTime 记录在 .meta 中（旧条目回退到文件 mtime），共享 blob 的 inode 时间不代表任何条目。
 type Store interface {
 	// Get 返回一个可流式读取的缓存条目。若不存在则返回 ErrNotFound。
 	Get(ctx context.Context, locator Locator) (*ReadResult, error)
@@ -24,10 +31,87 @@ type Store interface {
 	Remove(ctx context.Context, locator Locator) error
 }
 
+// AccessRecorder 由支持访问时间追踪的 Store 实现；缓存命中时调用 Touch，
+// 供淘汰器按最近最少使用（LRU）顺序回收空间。
+type AccessRecorder interface {
+	Touch(ctx context.Context, locator Locator) error
+}
+
+// MetadataUpdater 由支持单独改写 .meta 的 Store 实现；再验证确认正文未变但校验器更新时，
+// 只刷新元数据而无需重写正文。
+type MetadataUpdater interface {
+	UpdateResponse(ctx context.Context, locator Locator, response ResponseMetadata) error
+}
+
+// PartialStore 由支持断点续传的 Store 实现：以 PutOptions.KeepPartial 写入时回源中断会保留
+// 已下载的前缀，下次回源通过 Range 续传，并以 PutOptions.ResumeFrom 接续写入。
+type PartialStore interface {
+	// Partial 返回保留的前缀，不存在时返回 ErrNotFound。
+	Partial(ctx context.Context, locator Locator) (*PartialEntry, error)
+	// DiscardPartial 删除保留的前缀。
+	DiscardPartial(ctx context.Context, locator Locator) error
+}
+
+// Pinner 由支持固定条目的 Store 实现。被固定的条目（Entry.Pinned）不会被磁盘淘汰、保留策略或管理接口清除，
+// 覆盖写入时保留固定状态。
+type Pinner interface {
+	// Pin 设置或取消条目的固定状态，条目不存在时返回 ErrNotFound。
+	Pin(ctx context.Context, locator Locator, pinned bool) error
+	// RemoveUnpinned 在条目锁内确认条目未被固定后再删除，与 Pin 互斥；已固定时返回 ErrPinned。
+	RemoveUnpinned(ctx context.Context, locator Locator) error
+}
+
+// PartialEntry 描述一次中断下载保留的前缀及其当时的响应元数据（用于 If-Range）。
+type PartialEntry struct {
+	Locator   Locator
+	SizeBytes int64
+	Response  ResponseMetadata
+}
+
+// ResponseMetadata 记录上游响应的校验器与命中时需要回放的头部，随 .meta 旁路文件持久化，
+// 进程重启后仍可发起条件请求。
+type ResponseMetadata struct {
+	ETag                string `json:"etag,omitempty"`
+	DockerContentDigest string `json:"docker_content_digest,omitempty"`
+	LastModified        string `json:"last_modified,omitempty"`
+	ContentType         string `json:"content_type,omitempty"`
+	ContentEncoding     string `json:"content_encoding,omitempty"`
+	// Headers 保存白名单内的其他上游头部（如 Cache-Control、Link），命中时原样回放。
+	Headers map[string]string `json:"headers,omitempty"`
+	// ValidatedAt 为最近一次从上游取得或确认该响应的时间，用于判断 StaleIfError 窗口。
+	ValidatedAt time.Time `json:"validated_at,omitzero"`
+	// Status 非 0 时条目为负缓存标记：上游以该状态（404/410）应答，正文为上游的错误正文。
+	Status int `json:"status,omitempty"`
+	// Variant 记录写入时请求中被上游 Vary 列出的头部取值（已归一化），命中时须与当前请求一致。
+	Variant map[string]string `json:"variant,omitempty"`
+	// Upstream 为写入时实际提供响应的上游地址（不含查询参数），命中时作为 X-Any-Hub-Upstream 回放。
+	Upstream string `json:"upstream,omitempty"`
+}
+
+// IsZero 表示未记录任何响应元数据。
+func (m ResponseMetadata) IsZero() bool {
+	return m.ETag == "" && m.DockerContentDigest == "" && m.LastModified == "" &&
+		m.ContentType == "" && m.ContentEncoding == "" && len(m.Headers) == 0 && m.ValidatedAt.IsZero() && m.Status == 0 && len(m.Variant) == 0 &&
+		m.Upstream == ""
+}
+
 // PutOptions 控制写入过程中的可选属性。
 type PutOptions struct {
 	ModTime               time.Time
 	EffectiveUpstreamPath string
+	// Digest 为不可变正文的内容摘要（sha256:<hex>）。设置后正文写入共享的
+	// blobs/sha256/<hex>，多个 Hub 的相同内容只占用一份磁盘空间。
+	Digest string
+	// Verifier 在写入过程中校验正文，失败时丢弃临时文件并返回 ErrIntegrityMismatch。
+	// 未设置但 Digest 合法时，Put 会自动按 sha256 校验，避免错误内容进入共享 blob。
+	Verifier Verifier
+	// Response 为需要随条目持久化的上游响应元数据。
+	Response ResponseMetadata
+	// KeepPartial 为 true 时，读取 body 中途失败会保留已写入的前缀而不是删除临时文件。
+	KeepPartial bool
+	// ResumeFrom 大于 0 时表示 body 从该偏移开始，之前的字节取自保留的前缀；
+	// 前缀缺失或大小不符时返回 ErrPartialMismatch。
+	ResumeFrom int64
 }
 
 // Locator 唯一定位一个缓存条目（Hub + 相对路径），所有路径均为 URL 路径风格。
@@ -38,11 +122,30 @@ type Locator struct {
 
 // Entry 表示一次缓存命中结果，包含绝对文件路径及文件信息。
 type Entry struct {
-	Locator               Locator   `json:"locator"`
-	FilePath              string    `json:"file_path"`
-	SizeBytes             int64     `json:"size_bytes"`
-	ModTime               time.Time `json:"mod_time"`
-	EffectiveUpstreamPath string    `json:"effective_upstream_path,omitempty"`
+	Locator               Locator          `json:"locator"`
+	FilePath              string           `json:"file_path"`
+	SizeBytes             int64            `json:"size_bytes"`
+	ModTime               time.Time        `json:"mod_time"`
+	EffectiveUpstreamPath string           `json:"effective_upstream_path,omitempty"`
+	Digest                string           `json:"digest,omitempty"`
+	Response              ResponseMetadata `json:"response"`
+	// Pinned 为 true 时条目已被管理接口固定，见 Pinner。
+	Pinned bool `json:"pinned,omitempty"`
+	// CachedAt 为正文写入缓存的时间；旧版本写入、未记录该时间的条目为零值。
+	CachedAt time.Time `json:"cached_at,omitzero"`
+}
+
+// LastValidated 返回最近一次从上游取得或确认该条目的时间，未记录 ValidatedAt 的旧条目回退为 ModTime。
+func (e Entry) LastValidated() time.Time {
+	if !e.Response.ValidatedAt.IsZero() {
+		return e.Response.ValidatedAt
+	}
+	return e.ModTime
+}
+
+// Negative 表示条目是上游 404/410 的负缓存标记，而不是可回放的正文。
+func (e Entry) Negative() bool {
+	return e.Response.Status != 0
 }
 
 // ReadResult 组合 Entry 与正文 Reader，便于代理层直接将 Body 流式返回。
@@ -53,3 +156,9 @@ type ReadResult struct {
 
 // ErrNotFound 表示缓存不存在。
 var ErrNotFound = errors.New("cache entry not found")
+
+// ErrPinned 表示条目已被固定，RemoveUnpinned 未删除它。
+var ErrPinned = errors.New("cache entry is pinned")
+
+// ErrPinUnsupported 表示 Store 不支持固定条目（例如 S3 后端）。
+var ErrPinUnsupported = errors.New("cache store does not support pinning")
@@ -3,8 +3,11 @@ package cache
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -107,6 +110,126 @@ func TestStoreIgnoresDirectories(t *testing.T) {
 	}
 }
 
+func TestStoreSharesDigestBlobsAcrossHubs(t *testing.T) {
+	store := newTestStore(t)
+	fsStore := store.(*fileStore)
+	digest := sha256Digest("layer")
+	dockerLoc := Locator{HubName: "docker", Path: "/v2/library/demo/blobs/" + digest}
+	ghcrLoc := Locator{HubName: "ghcr", Path: "/v2/org/demo/blobs/" + digest}
+
+	for _, loc := range []Locator{dockerLoc, ghcrLoc} {
+		entry, err := store.Put(context.Background(), loc, strings.NewReader("layer"), PutOptions{Digest: digest})
+		if err != nil {
+			t.Fatalf("put error: %v", err)
+		}
+		if entry.Digest != digest {
+			t.Fatalf("unexpected entry digest: %q", entry.Digest)
+		}
+	}
+
+	blob := fsStore.blobPath(strings.TrimPrefix(digest, "sha256:"))
+	blobInfo, err := os.Stat(blob)
+	if err != nil {
+		t.Fatalf("expected shared blob: %v", err)
+	}
+	for _, loc := range []Locator{dockerLoc, ghcrLoc} {
+		filePath, _ := fsStore.entryPath(loc)
+		info, err := os.Stat(filePath)
+		if err != nil {
+			t.Fatalf("stat hub entry: %v", err)
+		}
+		if !os.SameFile(info, blobInfo) {
+			t.Fatalf("expected %s to link to the shared blob", loc.HubName)
+		}
+	}
+
+	if err := store.Remove(context.Background(), dockerLoc); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+	if _, err := os.Stat(blob); err != nil {
+		t.Fatalf("blob still referenced by ghcr must survive: %v", err)
+	}
+	result, err := store.Get(context.Background(), ghcrLoc)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	body, _ := io.ReadAll(result.Reader)
+	result.Reader.Close()
+	if string(body) != "layer" {
+		t.Fatalf("unexpected shared body: %s", body)
+	}
+
+	if err := store.Remove(context.Background(), ghcrLoc); err != nil {
+		t.Fatalf("remove error: %v", err)
+	}
+	if _, err := os.Stat(blob); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected unreferenced blob to be deleted, got %v", err)
+	}
+}
+
+func TestStoreKeepsPerHubTimesForSharedBlobs(t *testing.T) {
+	store := newTestStore(t)
+	digest := sha256Digest("layer")
+	dockerLoc := Locator{HubName: "docker", Path: "/v2/library/demo/blobs/" + digest}
+	ghcrLoc := Locator{HubName: "ghcr", Path: "/v2/org/demo/blobs/" + digest}
+	dockerTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ghcrTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := store.Put(context.Background(), dockerLoc, strings.NewReader("layer"), PutOptions{Digest: digest, ModTime: dockerTime}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if _, err := store.Put(context.Background(), ghcrLoc, strings.NewReader("layer"), PutOptions{Digest: digest, ModTime: ghcrTime}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	for loc, want := range map[Locator]time.Time{dockerLoc: dockerTime, ghcrLoc: ghcrTime} {
+		result, err := store.Get(context.Background(), loc)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		result.Reader.Close()
+		if !result.Entry.ModTime.Equal(want) {
+			t.Fatalf("%s: writing another hub must not change ModTime, got %s want %s", loc.HubName, result.Entry.ModTime, want)
+		}
+	}
+
+	// 访问 ghcr 只刷新它自己的访问时间，docker 条目的 LRU 位置不变（仍为写入时间）。
+	fsStore := store.(*fileStore)
+	if err := fsStore.Touch(context.Background(), ghcrLoc); err != nil {
+		t.Fatalf("touch error: %v", err)
+	}
+	entries, err := fsStore.scanEntries(context.Background())
+	if err != nil {
+		t.Fatalf("scan error: %v", err)
+	}
+	for _, entry := range entries {
+		switch entry.locator.HubName {
+		case "docker":
+			if !entry.accessTime.Equal(entry.cachedAt) {
+				t.Fatalf("docker access time changed by ghcr hit: %s", entry.accessTime)
+			}
+		case "ghcr":
+			if time.Since(entry.accessTime) > time.Minute {
+				t.Fatalf("ghcr access time not refreshed: %s", entry.accessTime)
+			}
+		}
+	}
+}
+
+func TestStoreIgnoresMalformedDigest(t *testing.T) {
+	store := newTestStore(t)
+	loc := Locator{HubName: "pypi", Path: "/files/pkg.whl"}
+	entry, err := store.Put(context.Background(), loc, strings.NewReader("wheel"), PutOptions{Digest: "sha256:not-hex"})
+	if err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+	if entry.Digest != "" {
+		t.Fatalf("malformed digest should not be recorded, got %q", entry.Digest)
+	}
+	if _, err := os.Stat(filepath.Join(store.(*fileStore).basePath, BlobsDirName)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("blobs directory should not be created for malformed digest")
+	}
+}
+
 // newTestStore returns a Store backed by a temporary directory.
 func newTestStore(t *testing.T) Store {
 	t.Helper()
@@ -116,3 +239,91 @@ func newTestStore(t *testing.T) Store {
 	}
 	return store
 }
+
+func TestStorePersistsResponseMetadata(t *testing.T) {
+	store := newTestStore(t)
+	locator := Locator{HubName: "npm", Path: "/lodash"}
+	response := ResponseMetadata{
+		ETag:        `"v1"`,
+		ContentType: "application/json",
+		Headers:     map[string]string{"Cache-Control": "max-age=300"},
+	}
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("{}"), PutOptions{Response: response}); err != nil {
+		t.Fatalf("put error: %v", err)
+	}
+
+	updated := response
+	updated.ETag = `"v2"`
+	if err := store.(MetadataUpdater).UpdateResponse(context.Background(), locator, updated); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+
+	result, err := store.Get(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	result.Reader.Close()
+	if result.Entry.Response.ETag != `"v2"` || result.Entry.Response.ContentType != "application/json" ||
+		result.Entry.Response.Headers["Cache-Control"] != "max-age=300" {
+		t.Fatalf("unexpected response metadata: %+v", result.Entry.Response)
+	}
+
+	missing := Locator{HubName: "npm", Path: "/missing"}
+	if err := store.(MetadataUpdater).UpdateResponse(context.Background(), missing, updated); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing entry, got %v", err)
+	}
+}
+
+func TestStoreKeepsPartialAndResumes(t *testing.T) {
+	store := newTestStore(t)
+	partials := store.(PartialStore)
+	locator := Locator{HubName: "docker", Path: "/v2/x/blobs/layer"}
+	response := ResponseMetadata{ETag: `"layer-v1"`}
+	full := "0123456789"
+	sum := sha256Digest(full)
+	verifier, _ := NewVerifier(IntegritySHA256, strings.TrimPrefix(sum, "sha256:"))
+
+	broken := io.MultiReader(strings.NewReader(full[:4]), iotestErrReader{})
+	if _, err := store.Put(context.Background(), locator, broken, PutOptions{Response: response, KeepPartial: true, Verifier: verifier}); err == nil {
+		t.Fatalf("expected interrupted put to fail")
+	}
+	partial, err := partials.Partial(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("expected partial to be kept: %v", err)
+	}
+	if partial.SizeBytes != 4 || partial.Response.ETag != `"layer-v1"` {
+		t.Fatalf("unexpected partial: %+v", partial)
+	}
+
+	if _, err := store.Put(context.Background(), locator, strings.NewReader(full[4:]), PutOptions{ResumeFrom: 3}); !errors.Is(err, ErrPartialMismatch) {
+		t.Fatalf("expected size mismatch to be rejected, got %v", err)
+	}
+
+	verifier, _ = NewVerifier(IntegritySHA256, strings.TrimPrefix(sum, "sha256:"))
+	entry, err := store.Put(context.Background(), locator, strings.NewReader(full[4:]), PutOptions{ResumeFrom: 4, Verifier: verifier})
+	if err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	if entry.SizeBytes != int64(len(full)) {
+		t.Fatalf("unexpected resumed size: %d", entry.SizeBytes)
+	}
+	result, err := store.Get(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	body, _ := io.ReadAll(result.Reader)
+	result.Reader.Close()
+	if string(body) != full {
+		t.Fatalf("unexpected resumed body: %q", body)
+	}
+	if _, err := partials.Partial(context.Background(), locator); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("partial should be consumed after resume, got %v", err)
+	}
+}
+
+// iotestErrReader 模拟上游连接在传输中途断开。
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 校验算法取值，与 hooks.Integrity.Algorithm 保持一致。
+const (
+	IntegritySHA1     = "sha1"
+	IntegritySHA256   = "sha256"
+	IntegritySHA512   = "sha512"
+	IntegrityGoModH1  = "h1-mod"
+	IntegrityGoZipH1  = "h1-zip"
+	goModuleHashLabel = "h1:"
+)
+
+// ErrIntegrityMismatch 表示正文摘要与模块声明的期望值不一致，条目不会被提交。
+var ErrIntegrityMismatch = errors.New("integrity mismatch")
+
+// IntegrityError 携带不一致的细节，errors.Is(err, ErrIntegrityMismatch) 成立。
+type IntegrityError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%s: %s expected %s got %s", ErrIntegrityMismatch, e.Algorithm, e.Expected, e.Actual)
+}
+
+// Unwrap 让调用方可以用 errors.Is 识别校验失败。
+func (e *IntegrityError) Unwrap() error {
+	return ErrIntegrityMismatch
+}
+
+// Verifier 在 Put 写入临时文件时同步接收正文字节，并在 rename 提交前给出结论。
+// Verify 可读取 tempPath（例如 Go 模块 zip 需要解析目录结构），返回错误时临时文件被丢弃。
+type Verifier interface {
+	io.Writer
+	Verify(tempPath string) error
+}
+
+// NewVerifier 根据算法与期望值构建校验器。sha* 期望值可为 hex 或 base64（npm SRI），
+// h1-* 为 go.sum 中的 "h1:<base64>" 形式。
+func NewVerifier(algorithm, expected string) (Verifier, error) {
+	expected = strings.TrimSpace(expected)
+	if expected == "" {
+		return nil, errors.New("expected digest required")
+	}
+	switch strings.ToLower(algorithm) {
+	case IntegritySHA1:
+		return &hashVerifier{algorithm: IntegritySHA1, hash: sha1.New(), expected: expected}, nil
+	case IntegritySHA256:
+		return &hashVerifier{algorithm: IntegritySHA256, hash: sha256.New(), expected: expected}, nil
+	case IntegritySHA512:
+		return &hashVerifier{algorithm: IntegritySHA512, hash: sha512.New(), expected: expected}, nil
+	case IntegrityGoModH1:
+		return &goModVerifier{hash: sha256.New(), expected: expected}, nil
+	case IntegrityGoZipH1:
+		return &goZipVerifier{expected: expected}, nil
+	default:
+		return nil, fmt.Errorf("unsupported integrity algorithm: %s", algorithm)
+	}
+}
+
+// hashVerifier 以流式哈希比对摘要，兼容 hex 与标准 base64 两种编码。
+type hashVerifier struct {
+	algorithm string
+	hash      hash.Hash
+	expected  string
+}
+
+func (v *hashVerifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+func (v *hashVerifier) Verify(string) error {
+	sum := v.hash.Sum(nil)
+	actualHex := hex.EncodeToString(sum)
+	if strings.EqualFold(v.expected, actualHex) || v.expected == base64.StdEncoding.EncodeToString(sum) {
+		return nil
+	}
+	return &IntegrityError{Algorithm: v.algorithm, Expected: v.expected, Actual: actualHex}
+}
+
+// goModVerifier 计算 go.sum 中 "<mod> <ver>/go.mod h1:" 的值，即单文件 go.mod 的 dirhash。
+type goModVerifier struct {
+	hash     hash.Hash
+	expected string
+}
+
+func (v *goModVerifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+func (v *goModVerifier) Verify(string) error {
+	summary := fmt.Sprintf("%x  %s\n", v.hash.Sum(nil), "go.mod")
+	return compareGoHash(v.expected, goHash1([]string{summary}))
+}
+
+// goZipVerifier 在写入完成后读取临时 zip，按 dirhash Hash1 规则计算模块哈希。
+type goZipVerifier struct {
+	expected string
+}
+
+func (v *goZipVerifier) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (v *goZipVerifier) Verify(tempPath string) error {
+	file, err := os.Open(tempPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return &IntegrityError{Algorithm: IntegrityGoZipH1, Expected: v.expected, Actual: "invalid zip"}
+	}
+	summaries := make([]string, 0, len(reader.File))
+	for _, entry := range reader.File {
+		if strings.Contains(entry.Name, "\n") {
+			return &IntegrityError{Algorithm: IntegrityGoZipH1, Expected: v.expected, Actual: "invalid file name"}
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		sum := sha256.New()
+		_, err = io.Copy(sum, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, fmt.Sprintf("%x  %s\n", sum.Sum(nil), entry.Name))
+	}
+	return compareGoHash(v.expected, goHash1(summaries))
+}
+
+// goHash1 实现 golang.org/x/mod/sumdb/dirhash.Hash1：按文件名排序后对 "<sha256>  <name>\n" 行再做 sha256。
+func goHash1(summaries []string) string {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i][66:] < summaries[j][66:]
+	})
+	h := sha256.New()
+	for _, line := range summaries {
+		io.WriteString(h, line)
+	}
+	return goModuleHashLabel + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func compareGoHash(expected, actual string) error {
+	if expected == actual {
+		return nil
+	}
+	return &IntegrityError{Algorithm: "h1", Expected: expected, Actual: actual}
+}
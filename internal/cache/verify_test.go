@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const demoGoMod = "module example.com/demo\n\ngo 1.21\n"
+
+func TestHashVerifierAcceptsHexAndBase64(t *testing.T) {
+	for _, expected := range []string{
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+	} {
+		verifier, err := NewVerifier(IntegritySHA256, expected)
+		if err != nil {
+			t.Fatalf("verifier error: %v", err)
+		}
+		verifier.Write([]byte("hello"))
+		if err := verifier.Verify(""); err != nil {
+			t.Fatalf("expected %s to match: %v", expected, err)
+		}
+	}
+
+	verifier, _ := NewVerifier(IntegritySHA1, strings.Repeat("0", 40))
+	verifier.Write([]byte("hello"))
+	if err := verifier.Verify(""); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+}
+
+func TestGoModVerifier(t *testing.T) {
+	verifier, err := NewVerifier(IntegrityGoModH1, "h1:ukAGGLvIL/hl/2TNzcoN7jyZArBBO+cuxDprR1BfeSY=")
+	if err != nil {
+		t.Fatalf("verifier error: %v", err)
+	}
+	verifier.Write([]byte(demoGoMod))
+	if err := verifier.Verify(""); err != nil {
+		t.Fatalf("go.mod hash mismatch: %v", err)
+	}
+}
+
+func TestGoZipVerifier(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"example.com/demo@v1.0.0/go.mod":  demoGoMod,
+		"example.com/demo@v1.0.0/demo.go": "package demo\n",
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	zipPath := filepath.Join(t.TempDir(), "demo.zip")
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write zip: %v", err)
+	}
+
+	verifier, _ := NewVerifier(IntegrityGoZipH1, "h1:MXEOZC/j1SxrctS+l9F/65ADAGuePmzxyAMzvvmFIrA=")
+	if err := verifier.Verify(zipPath); err != nil {
+		t.Fatalf("zip hash mismatch: %v", err)
+	}
+	verifier, _ = NewVerifier(IntegrityGoZipH1, "h1:ukAGGLvIL/hl/2TNzcoN7jyZArBBO+cuxDprR1BfeSY=")
+	if err := verifier.Verify(zipPath); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected zip mismatch, got %v", err)
+	}
+}
+
+func TestStoreDiscardsBodyOnIntegrityMismatch(t *testing.T) {
+	store := newTestStore(t)
+	locator := Locator{HubName: "npm", Path: "/pkg/-/pkg-1.0.0.tgz"}
+	verifier, _ := NewVerifier(IntegritySHA256, strings.Repeat("0", 64))
+	if _, err := store.Put(context.Background(), locator, strings.NewReader("tampered"), PutOptions{Verifier: verifier}); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected integrity mismatch, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), locator); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("mismatched body must not be committed, got %v", err)
+	}
+	filePath, _ := store.(*fileStore).entryPath(locator)
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(filePath), ".cache-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("temp files should be removed: %v", leftovers)
+	}
+}
+
+func TestStoreVerifiesDigestWithoutExplicitVerifier(t *testing.T) {
+	store := newTestStore(t)
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	_, err := store.Put(context.Background(), Locator{HubName: "docker", Path: "/v2/x/blobs/" + digest}, strings.NewReader("truncated"), PutOptions{Digest: digest})
+	if !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected digest mismatch, got %v", err)
+	}
+	if _, err := os.Stat(store.(*fileStore).blobPath(strings.Repeat("ab", 32))); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("mismatched body must not become a shared blob")
+	}
+}
+
+// sha256Digest 返回 body 的 sha256:<hex> 摘要，用于构造合法的共享 blob。
+func sha256Digest(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrWalkUnsupported 表示 Store 不支持枚举条目（例如 S3 后端）。
+var ErrWalkUnsupported = errors.New("cache store does not support listing entries")
+
+// EntryInfo 为枚举时返回的条目摘要，不打开正文文件。
+type EntryInfo struct {
+	Locator   Locator
+	SizeBytes int64
+	ModTime   time.Time
+	// CachedAt 为条目写入缓存的时间（.meta 的 cached_at）；旧版本写入、未记录该时间的条目为零值。
+	CachedAt time.Time
+}
+
+// Walk 按 Hub、路径顺序遍历条目定位；hub 为空时遍历全部 Hub。fn 返回错误时停止遍历。
+func Walk(ctx context.Context, store Store, hub string, fn func(Locator) error) error {
+	return WalkEntries(ctx, store, hub, func(info EntryInfo) error {
+		return fn(info.Locator)
+	})
+}
+
+// WalkEntries 与 Walk 相同，但同时返回条目大小与修改时间。磁盘后端启用索引时直接查询索引，
+// 否则遍历目录。
+func WalkEntries(ctx context.Context, store Store, hub string, fn func(EntryInfo) error) error {
+	if memory, ok := store.(*memoryStore); ok {
+		store = memory.backend
+	}
+	fsStore, ok := store.(*fileStore)
+	if !ok {
+		return ErrWalkUnsupported
+	}
+	if fsStore.index != nil {
+		return fsStore.index.List(ctx, hub, func(record IndexRecord) error {
+			return fn(EntryInfo{Locator: record.Locator, SizeBytes: record.SizeBytes, ModTime: record.ModTime, CachedAt: record.CreatedAt})
+		})
+	}
+	entries, err := fsStore.scanEntries(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].locator.HubName != entries[j].locator.HubName {
+			return entries[i].locator.HubName < entries[j].locator.HubName
+		}
+		return entries[i].locator.Path < entries[j].locator.Path
+	})
+	for _, entry := range entries {
+		if hub != "" && entry.locator.HubName != hub {
+			continue
+		}
+		if err := fn(EntryInfo{Locator: entry.locator, SizeBytes: entry.size, ModTime: entry.modTime, CachedAt: entry.cachedAt}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -87,6 +87,169 @@ func TestValidateRequiresCredentialPairs(t *testing.T) {
 	}
 }
 
+func TestValidateRejectsNegativeDiskCacheSize(t *testing.T) {
+	cfg := validConfig()
+	cfg.Global.MaxDiskCache = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("负数的全局 MaxDiskCacheSize 应当报错")
+	}
+
+	cfg = validConfig()
+	cfg.Hubs[0].MaxDiskCache = -1
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("负数的 Hub MaxDiskCacheSize 应当报错")
+	}
+
+	cfg = validConfig()
+	cfg.Hubs[0].MaxDiskCache = 1024
+	if limits := cfg.HubDiskCacheLimits(); limits["npm"] != 1024 {
+		t.Fatalf("Hub 配额应出现在 HubDiskCacheLimits 中: %v", limits)
+	}
+}
+
+func TestValidateRejectsNegativeCacheWindows(t *testing.T) {
+	cfg := validConfig()
+	cfg.Hubs[0].StaleIfError = Duration(-time.Second)
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("负数的 StaleIfError 应当报错")
+	}
+
+	cfg = validConfig()
+	cfg.Hubs[0].NegativeCacheTTL = Duration(-time.Second)
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("负数的 NegativeCacheTTL 应当报错")
+	}
+
+	cfg = validConfig()
+	cfg.Hubs[0].StaleWhileRevalidate = Duration(-time.Second)
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("负数的 StaleWhileRevalidate 应当报错")
+	}
+}
+
+func TestValidateDiskLayout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Hubs[0].DiskLayout = "flat"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("未知的 DiskLayout 应当报错")
+	}
+
+	cfg = validConfig()
+	if layouts := cfg.HubDiskLayouts(); layouts["npm"] != "raw_path" {
+		t.Fatalf("未覆盖时应使用模块默认布局: %v", layouts)
+	}
+	cfg.Hubs[0].DiskLayout = " Hashed "
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("hashed 布局应通过校验: %v", err)
+	}
+	if layouts := cfg.HubDiskLayouts(); layouts["npm"] != "hashed" {
+		t.Fatalf("Hub 覆盖应出现在 HubDiskLayouts 中: %v", layouts)
+	}
+}
+
+func TestValidateUpstreams(t *testing.T) {
+	cases := map[string]func(*HubConfig){
+		"同时设置 Upstream 与 Upstreams": func(h *HubConfig) {
+			h.Upstreams = []UpstreamEntry{{URL: "https://registry.npmmirror.com"}}
+		},
+		"非法镜像地址": func(h *HubConfig) {
+			h.Upstream = ""
+			h.Upstreams = []UpstreamEntry{{URL: "ftp://mirror.local"}}
+		},
+		"重复镜像": func(h *HubConfig) {
+			h.Upstream = ""
+			h.Upstreams = []UpstreamEntry{{URL: "https://a.local"}, {URL: "https://a.local"}}
+		},
+		"负数权重": func(h *HubConfig) {
+			h.Upstream = ""
+			h.Upstreams = []UpstreamEntry{{URL: "https://a.local", Weight: -1}}
+		},
+		"未知选择方式": func(h *HubConfig) {
+			h.UpstreamSelection = "random"
+		},
+	}
+	for name, mutate := range cases {
+		cfg := validConfig()
+		mutate(&cfg.Hubs[0])
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s 应当报错", name)
+		}
+	}
+
+	cfg := validConfig()
+	cfg.Hubs[0].Upstream = ""
+	cfg.Hubs[0].Upstreams = []UpstreamEntry{{URL: "https://registry.npmjs.org"}, {URL: "https://registry.npmmirror.com"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("多上游配置应通过校验: %v", err)
+	}
+}
+
+func TestValidateRetentionRules(t *testing.T) {
+	cases := map[string]RetentionRule{
+		"缺少 Match":       {MaxAge: Duration(time.Hour)},
+		"非法 glob":        {Match: "/[", Pinned: true},
+		"没有任何动作":         {Match: "/nightly/*"},
+		"固定与过期冲突":        {Match: "/nightly/*", Pinned: true, MaxVersions: 3},
+		"负数 MaxVersions": {Match: "/nightly/*", MaxVersions: -1},
+	}
+	for name, rule := range cases {
+		cfg := validConfig()
+		cfg.Hubs[0].Retention = []RetentionRule{rule}
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s 应当报错", name)
+		}
+	}
+
+	cfg := validConfig()
+	cfg.Hubs[0].Retention = []RetentionRule{
+		{Match: "/typescript/-/*", Pinned: true},
+		{Match: "/nightly-*/-/*", MaxAge: Duration(72 * time.Hour), MaxVersions: 5},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("合法的保留规则应通过校验: %v", err)
+	}
+}
+
+func TestValidateRejectsReservedHubName(t *testing.T) {
+	cfg := validConfig()
+	cfg.Hubs[0].Name = "blobs"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("blobs 为缓存保留目录，不能作为 Hub 名称")
+	}
+}
+
+func TestValidateStorageBackend(t *testing.T) {
+	cfg := validConfig()
+	if err := cfg.Validate(); err != nil || cfg.Global.StorageBackend != StorageBackendFS {
+		t.Fatalf("未设置 StorageBackend 时应默认 fs: %v %q", err, cfg.Global.StorageBackend)
+	}
+
+	cfg = validConfig()
+	cfg.Global.StorageBackend = "gcs"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("不支持的 StorageBackend 应当报错")
+	}
+
+	cfg = validConfig()
+	cfg.Global.StorageBackend = "S3"
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("s3 后端缺少 endpoint/bucket 时应当报错")
+	}
+
+	cfg.Global.S3Endpoint = "http://minio.local:9000"
+	cfg.Global.S3Bucket = "any-hub"
+	cfg.Global.S3AccessKeyID = "ak"
+	cfg.Global.S3SecretAccessKey = "sk"
+	if err := cfg.Validate(); err != nil || cfg.Global.StorageBackend != StorageBackendS3 {
+		t.Fatalf("完整的 s3 配置应通过校验: %v", err)
+	}
+
+	cfg.Global.MaxDiskCache = 1024
+	if err := cfg.Validate(); err == nil {
+		t.Fatalf("s3 后端不支持 MaxDiskCacheSize")
+	}
+}
+
 func validConfig() *Config {
 	return &Config{
 		Global: GlobalConfig{
@@ -32,7 +32,8 @@ func Load(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := v.Unmarshal(&cfg, viper.DecodeHook(durationDecodeHook())); err != nil {
+	hooks := mapstructure.ComposeDecodeHookFunc(durationDecodeHook(), upstreamEntryDecodeHook())
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(hooks)); err != nil {
 		return nil, fmt.Errorf("解析配置失败: %w", err)
 	}
 
@@ -64,9 +65,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("StoragePath", "./storage")
 	v.SetDefault("CacheTTL", 86400)
 	v.SetDefault("MaxMemoryCacheSize", 256*1024*1024)
+	v.SetDefault("MaxDiskCacheSize", 0)
 	v.SetDefault("MaxRetries", 3)
 	v.SetDefault("InitialBackoff", "1s")
 	v.SetDefault("UpstreamTimeout", "30s")
+	v.SetDefault("StorageBackend", StorageBackendFS)
+	v.SetDefault("S3Region", "us-east-1")
 }
 
 func applyGlobalDefaults(g *GlobalConfig) {
@@ -99,6 +103,21 @@ func NormalizeHubConfig(h HubConfig) HubConfig {
 	return h
 }
 
+// upstreamEntryDecodeHook 允许 Upstreams 中直接写 URL 字符串。
+func upstreamEntryDecodeHook() mapstructure.DecodeHookFunc {
+	targetType := reflect.TypeOf(UpstreamEntry{})
+
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != targetType {
+			return data, nil
+		}
+		if raw, ok := data.(string); ok {
+			return UpstreamEntry{URL: raw}, nil
+		}
+		return data, nil
+	}
+}
+
 func durationDecodeHook() mapstructure.DecodeHookFunc {
 	targetType := reflect.TypeOf(Duration(0))
 
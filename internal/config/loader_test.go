@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestLoadFailsWithMissingFields(t *testing.T) {
 	if _, err := Load(testConfigPath(t, "missing.toml")); err == nil {
@@ -25,3 +28,107 @@ Upstream = "https://registry-1.docker.io"
 		t.Fatalf("无效 Duration 应失败")
 	}
 }
+
+func TestLoadParsesRetentionRules(t *testing.T) {
+	cfg := `
+LogLevel = "info"
+StoragePath = "./data"
+
+[[Hub]]
+Name = "docker"
+Domain = "docker.local"
+Type = "docker"
+Upstream = "https://registry-1.docker.io"
+
+[[Hub.Retention]]
+Match = "/v2/library/golang/*/*"
+Pinned = true
+
+[[Hub.Retention]]
+Match = "/v2/acme/nightly/manifests/*"
+MaxAge = "72h"
+MaxVersions = 5
+`
+	loaded, err := Load(writeTempConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("Load 返回错误: %v", err)
+	}
+	rules := loaded.Hubs[0].Retention
+	if len(rules) != 2 || !rules[0].Pinned || rules[1].MaxAge.DurationValue().Hours() != 72 || rules[1].MaxVersions != 5 {
+		t.Fatalf("保留规则解析不符合预期: %+v", rules)
+	}
+}
+
+func TestLoadParsesHubRetryOverrides(t *testing.T) {
+	cfg := `
+LogLevel = "info"
+StoragePath = "./data"
+MaxRetries = 2
+InitialBackoff = "500ms"
+
+[[Hub]]
+Name = "docker"
+Domain = "docker.local"
+Type = "docker"
+Upstream = "https://registry-1.docker.io"
+MaxRetries = 0
+
+[[Hub]]
+Name = "npm"
+Domain = "npm.local"
+Type = "npm"
+Upstream = "https://registry.npmjs.org"
+InitialBackoff = "2s"
+`
+	loaded, err := Load(writeTempConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("Load 返回错误: %v", err)
+	}
+	if retries, backoff := loaded.EffectiveRetries(loaded.Hubs[0]); retries != 0 || backoff != 500*time.Millisecond {
+		t.Fatalf("显式 MaxRetries = 0 应关闭重试: retries=%d backoff=%s", retries, backoff)
+	}
+	if retries, backoff := loaded.EffectiveRetries(loaded.Hubs[1]); retries != 2 || backoff != 2*time.Second {
+		t.Fatalf("未覆盖的字段应回退到全局值: retries=%d backoff=%s", retries, backoff)
+	}
+
+	negative := -1
+	loaded.Hubs[0].MaxRetries = &negative
+	if err := loaded.Validate(); err == nil {
+		t.Fatalf("负数 MaxRetries 应当报错")
+	}
+}
+
+func TestLoadParsesUpstreamList(t *testing.T) {
+	cfg := `
+LogLevel = "info"
+StoragePath = "./data"
+
+[[Hub]]
+Name = "npm"
+Domain = "npm.local"
+Type = "npm"
+Upstreams = ["https://registry.npmjs.org", "https://registry.npmmirror.com"]
+
+[[Hub]]
+Name = "go"
+Domain = "go.local"
+Type = "go"
+UpstreamSelection = "Weighted"
+Upstreams = [
+  { URL = "https://proxy.golang.org", Weight = 3 },
+  { URL = "https://goproxy.cn" },
+]
+`
+	loaded, err := Load(writeTempConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("Load 返回错误: %v", err)
+	}
+	npm := loaded.Hubs[0].UpstreamList()
+	if len(npm) != 2 || npm[0].URL != "https://registry.npmjs.org" || npm[1].URL != "https://registry.npmmirror.com" {
+		t.Fatalf("字符串形式的 Upstreams 解析不符合预期: %+v", npm)
+	}
+	goHub := loaded.Hubs[1]
+	if goHub.UpstreamSelection != UpstreamSelectionWeighted || goHub.Upstreams[0].Weight != 3 || goHub.Upstreams[1].URL != "https://goproxy.cn" {
+		t.Fatalf("表形式的 Upstreams 解析不符合预期: %+v", goHub)
+	}
+}
@@ -57,22 +57,72 @@ type GlobalConfig struct {
 	StoragePath     string   `mapstructure:"StoragePath"`
 	CacheTTL        Duration `mapstructure:"CacheTTL"`
 	MaxMemoryCache  int64    `mapstructure:"MaxMemoryCacheSize"`
+	MaxDiskCache    int64    `mapstructure:"MaxDiskCacheSize"`
 	MaxRetries      int      `mapstructure:"MaxRetries"`
 	InitialBackoff  Duration `mapstructure:"InitialBackoff"`
 	UpstreamTimeout Duration `mapstructure:"UpstreamTimeout"`
+	// AdminToken 启用 /-/cache 管理接口，请求需携带 Authorization: Bearer <AdminToken>；留空表示关闭。
+	AdminToken string `mapstructure:"AdminToken"`
+	// StorageBackend 选择缓存后端：fs（默认，StoragePath 目录）或 s3（S3 兼容对象存储）。
+	StorageBackend    string `mapstructure:"StorageBackend"`
+	S3Endpoint        string `mapstructure:"S3Endpoint"`
+	S3Region          string `mapstructure:"S3Region"`
+	S3Bucket          string `mapstructure:"S3Bucket"`
+	S3Prefix          string `mapstructure:"S3Prefix"`
+	S3AccessKeyID     string `mapstructure:"S3AccessKeyID"`
+	S3SecretAccessKey string `mapstructure:"S3SecretAccessKey"`
 }
 
+// 缓存后端取值。
+const (
+	StorageBackendFS = "fs"
+	StorageBackendS3 = "s3"
+)
+
 // HubConfig 决定单个代理实例如何与下游/上游交互。
 type HubConfig struct {
-	Name           string   `mapstructure:"Name"`
-	Domain         string   `mapstructure:"Domain"`
-	Upstream       string   `mapstructure:"Upstream"`
-	Proxy          string   `mapstructure:"Proxy"`
-	Type           string   `mapstructure:"Type"`
-	Username       string   `mapstructure:"Username"`
-	Password       string   `mapstructure:"Password"`
-	CacheTTL       Duration `mapstructure:"CacheTTL"`
-	ValidationMode string   `mapstructure:"ValidationMode"`
+	Name                 string          `mapstructure:"Name"`
+	Domain               string          `mapstructure:"Domain"`
+	Upstream             string          `mapstructure:"Upstream"`
+	Proxy                string          `mapstructure:"Proxy"`
+	Type                 string          `mapstructure:"Type"`
+	Username             string          `mapstructure:"Username"`
+	Password             string          `mapstructure:"Password"`
+	CacheTTL             Duration        `mapstructure:"CacheTTL"`
+	ValidationMode       string          `mapstructure:"ValidationMode"`
+	MaxDiskCache         int64           `mapstructure:"MaxDiskCacheSize"`
+	StaleIfError         Duration        `mapstructure:"StaleIfError"`
+	NegativeCacheTTL     Duration        `mapstructure:"NegativeCacheTTL"`
+	StaleWhileRevalidate Duration        `mapstructure:"StaleWhileRevalidate"`
+	DiskLayout           string          `mapstructure:"DiskLayout"`
+	MaxRetries           *int            `mapstructure:"MaxRetries"`
+	InitialBackoff       Duration        `mapstructure:"InitialBackoff"`
+	Retention            []RetentionRule `mapstructure:"Retention"`
+	Upstreams            []UpstreamEntry `mapstructure:"Upstreams"`
+	UpstreamSelection    string          `mapstructure:"UpstreamSelection"`
+}
+
+// UpstreamEntry 对应 Upstreams 中的一项，可直接写 URL 字符串，或写成 { URL = "...", Weight = 3 }。
+type UpstreamEntry struct {
+	URL string `mapstructure:"URL"`
+	// Weight 仅在 UpstreamSelection = "weighted" 时生效，0 视为 1。
+	Weight int `mapstructure:"Weight"`
+}
+
+// 多上游的选择方式。
+const (
+	// UpstreamSelectionPriority 按列表顺序优先使用靠前的健康上游（默认）。
+	UpstreamSelectionPriority = "priority"
+	// UpstreamSelectionWeighted 按 Weight 在健康上游间随机分配请求。
+	UpstreamSelectionWeighted = "weighted"
+)
+
+// RetentionRule 对应 [[Hub.Retention]]：按 glob 匹配缓存路径，设置最长保留时间、每个包保留的版本数或固定。
+type RetentionRule struct {
+	Match       string   `mapstructure:"Match"`
+	MaxAge      Duration `mapstructure:"MaxAge"`
+	MaxVersions int      `mapstructure:"MaxVersions"`
+	Pinned      bool     `mapstructure:"Pinned"`
 }
 
 // Config 是 TOML 文件映射的整体结构。
@@ -81,6 +131,14 @@ type Config struct {
 	Hubs   []HubConfig  `mapstructure:"Hub"`
 }
 
+// UpstreamList 返回 Hub 的全部上游：配置了 Upstreams 时按列表顺序返回，否则为单个 Upstream。
+func (h HubConfig) UpstreamList() []UpstreamEntry {
+	if len(h.Upstreams) > 0 {
+		return h.Upstreams
+	}
+	return []UpstreamEntry{{URL: h.Upstream}}
+}
+
 // HasCredentials 表示当前 Hub 是否配置了完整的上游凭证。
 func (h HubConfig) HasCredentials() bool {
 	return h.Username != "" && h.Password != ""
@@ -106,12 +164,15 @@ func CredentialModes(hubs []HubConfig) []string {
 	return result
 }
 
-// StrategyOverrides 将 hub 层的 TTL/Validation 配置映射为模块策略覆盖项。
+// StrategyOverrides 将 hub 层的 TTL/Validation/DiskLayout 配置映射为模块策略覆盖项。
 func (h HubConfig) StrategyOverrides(ttl time.Duration) hubmodule.StrategyOptions {
 	opts := hubmodule.StrategyOptions{}
 	if mode := strings.TrimSpace(h.ValidationMode); mode != "" {
 		opts.ValidationOverride = hubmodule.ValidationMode(mode)
 	}
+	if layout := strings.TrimSpace(h.DiskLayout); layout != "" {
+		opts.DiskLayoutOverride = layout
+	}
 	if h.CacheTTL.DurationValue() > 0 {
 		opts.TTLOverride = ttl
 	}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/any-hub/any-hub/internal/cache"
 	"github.com/any-hub/any-hub/internal/hubmodule"
 )
 
@@ -41,6 +43,9 @@ func (c *Config) Validate() error {
 	if g.MaxMemoryCache <= 0 {
 		return newFieldError("Global.MaxMemoryCacheSize", "必须大于 0")
 	}
+	if g.MaxDiskCache < 0 {
+		return newFieldError("Global.MaxDiskCacheSize", "不能为负数")
+	}
 	if g.MaxRetries < 0 {
 		return newFieldError("Global.MaxRetries", "不能为负数")
 	}
@@ -50,6 +55,9 @@ func (c *Config) Validate() error {
 	if g.UpstreamTimeout.DurationValue() <= 0 {
 		return newFieldError("Global.UpstreamTimeout", "必须大于 0")
 	}
+	if err := c.validateStorageBackend(); err != nil {
+		return err
+	}
 
 	if len(c.Hubs) == 0 {
 		return errors.New("至少需要配置一个 Hub")
@@ -65,6 +73,9 @@ func (c *Config) Validate() error {
 			return newFieldError(hubField(hub.Name, "Name"), "重复")
 		}
 		seenNames[hub.Name] = struct{}{}
+		if cache.ReservedHubName(hub.Name) {
+			return newFieldError(hubField(hub.Name, "Name"), "为缓存目录保留，请更换")
+		}
 
 		if err := validateDomain(hub.Domain); err != nil {
 			return fmt.Errorf("%s: %w", hubField(hub.Name, "Domain"), err)
@@ -91,12 +102,45 @@ func (c *Config) Validate() error {
 				return newFieldError(hubField(hub.Name, "ValidationMode"), "仅支持 etag/last-modified/never")
 			}
 		}
+		if hub.DiskLayout != "" {
+			layout := strings.ToLower(strings.TrimSpace(hub.DiskLayout))
+			switch layout {
+			case hubmodule.DiskLayoutRawPath, hubmodule.DiskLayoutHashed:
+				hub.DiskLayout = layout
+			default:
+				return newFieldError(hubField(hub.Name, "DiskLayout"), "仅支持 raw_path/hashed")
+			}
+		}
+
+		if hub.MaxDiskCache < 0 {
+			return newFieldError(hubField(hub.Name, "MaxDiskCacheSize"), "不能为负数")
+		}
+		if hub.StaleIfError.DurationValue() < 0 {
+			return newFieldError(hubField(hub.Name, "StaleIfError"), "不能为负数")
+		}
+		if hub.NegativeCacheTTL.DurationValue() < 0 {
+			return newFieldError(hubField(hub.Name, "NegativeCacheTTL"), "不能为负数")
+		}
+		if hub.StaleWhileRevalidate.DurationValue() < 0 {
+			return newFieldError(hubField(hub.Name, "StaleWhileRevalidate"), "不能为负数")
+		}
+		if hub.MaxRetries != nil && *hub.MaxRetries < 0 {
+			return newFieldError(hubField(hub.Name, "MaxRetries"), "不能为负数")
+		}
+		if hub.InitialBackoff.DurationValue() < 0 {
+			return newFieldError(hubField(hub.Name, "InitialBackoff"), "不能为负数")
+		}
+		for _, rule := range hub.Retention {
+			if err := validateRetentionRule(rule); err != nil {
+				return fmt.Errorf("%s: %w", hubField(hub.Name, "Retention"), err)
+			}
+		}
 
 		if (hub.Username == "") != (hub.Password == "") {
 			return newFieldError(hubField(hub.Name, "Username/Password"), "必须同时提供或同时留空")
 		}
-		if err := validateUpstream(hub.Upstream); err != nil {
-			return fmt.Errorf("%s: %w", hubField(hub.Name, "Upstream"), err)
+		if err := validateUpstreams(hub); err != nil {
+			return err
 		}
 		if hub.Proxy != "" {
 			if err := validateUpstream(hub.Proxy); err != nil {
@@ -108,6 +152,41 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateStorageBackend 规范化 StorageBackend，并在选择 s3 时检查连接参数。
+func (c *Config) validateStorageBackend() error {
+	g := &c.Global
+	backend := strings.ToLower(strings.TrimSpace(g.StorageBackend))
+	switch backend {
+	case "", StorageBackendFS:
+		g.StorageBackend = StorageBackendFS
+		return nil
+	case StorageBackendS3:
+		g.StorageBackend = StorageBackendS3
+	default:
+		return newFieldError("Global.StorageBackend", "仅支持 fs|s3")
+	}
+
+	if err := validateUpstream(g.S3Endpoint); err != nil {
+		return fmt.Errorf("Global.S3Endpoint: %w", err)
+	}
+	if g.S3Bucket == "" {
+		return newFieldError("Global.S3Bucket", "StorageBackend=s3 时不能为空")
+	}
+	if g.S3AccessKeyID == "" || g.S3SecretAccessKey == "" {
+		return newFieldError("Global.S3AccessKeyID/S3SecretAccessKey", "StorageBackend=s3 时必须同时提供")
+	}
+	// 磁盘淘汰器依赖本地目录扫描，对象存储的容量请使用 bucket 生命周期规则管理。
+	if g.MaxDiskCache > 0 {
+		return newFieldError("Global.MaxDiskCacheSize", "仅 fs 后端支持")
+	}
+	for _, hub := range c.Hubs {
+		if hub.MaxDiskCache > 0 {
+			return newFieldError(hubField(hub.Name, "MaxDiskCacheSize"), "仅 fs 后端支持")
+		}
+	}
+	return nil
+}
+
 func validateDomain(domain string) error {
 	if domain == "" {
 		return errors.New("Domain 不能为空")
@@ -124,6 +203,59 @@ func validateDomain(domain string) error {
 	return nil
 }
 
+func validateRetentionRule(rule RetentionRule) error {
+	if !strings.HasPrefix(rule.Match, "/") {
+		return errors.New("Match 必须为以 / 开头的路径 glob")
+	}
+	if _, err := path.Match(rule.Match, ""); err != nil {
+		return fmt.Errorf("Match %q 不是合法的 glob", rule.Match)
+	}
+	if rule.MaxAge.DurationValue() < 0 || rule.MaxVersions < 0 {
+		return fmt.Errorf("规则 %s 的 MaxAge/MaxVersions 不能为负数", rule.Match)
+	}
+	hasExpiry := rule.MaxAge.DurationValue() > 0 || rule.MaxVersions > 0
+	switch {
+	case rule.Pinned && hasExpiry:
+		return fmt.Errorf("规则 %s 不能同时设置 Pinned 与 MaxAge/MaxVersions", rule.Match)
+	case !rule.Pinned && !hasExpiry:
+		return fmt.Errorf("规则 %s 需要设置 Pinned、MaxAge 或 MaxVersions", rule.Match)
+	}
+	return nil
+}
+
+// validateUpstreams 校验 Upstream/Upstreams 二选一、每个地址合法，并规范化 UpstreamSelection。
+func validateUpstreams(hub *HubConfig) error {
+	if len(hub.Upstreams) == 0 {
+		if err := validateUpstream(hub.Upstream); err != nil {
+			return fmt.Errorf("%s: %w", hubField(hub.Name, "Upstream"), err)
+		}
+	} else if hub.Upstream != "" {
+		return newFieldError(hubField(hub.Name, "Upstream/Upstreams"), "只能设置其中一个")
+	}
+	seen := make(map[string]struct{}, len(hub.Upstreams))
+	for _, entry := range hub.Upstreams {
+		if err := validateUpstream(entry.URL); err != nil {
+			return fmt.Errorf("%s: %w", hubField(hub.Name, "Upstreams"), err)
+		}
+		if _, dup := seen[entry.URL]; dup {
+			return newFieldError(hubField(hub.Name, "Upstreams"), "重复的上游: "+entry.URL)
+		}
+		seen[entry.URL] = struct{}{}
+		if entry.Weight < 0 {
+			return newFieldError(hubField(hub.Name, "Upstreams"), "Weight 不能为负数")
+		}
+	}
+	selection := strings.ToLower(strings.TrimSpace(hub.UpstreamSelection))
+	switch selection {
+	case "":
+	case UpstreamSelectionPriority, UpstreamSelectionWeighted:
+		hub.UpstreamSelection = selection
+	default:
+		return newFieldError(hubField(hub.Name, "UpstreamSelection"), "仅支持 priority/weighted")
+	}
+	return nil
+}
+
 func validateUpstream(raw string) error {
 	if raw == "" {
 		return errors.New("缺少上游地址")
@@ -148,3 +280,40 @@ func (c *Config) EffectiveCacheTTL(h HubConfig) time.Duration {
 	}
 	return c.Global.CacheTTL.DurationValue()
 }
+
+// EffectiveRetries 返回特定 Hub 生效的回源重试次数与初始退避，未覆盖时回退至全局值。
+func (c *Config) EffectiveRetries(h HubConfig) (int, time.Duration) {
+	retries := c.Global.MaxRetries
+	if h.MaxRetries != nil {
+		retries = *h.MaxRetries
+	}
+	backoff := c.Global.InitialBackoff.DurationValue()
+	if h.InitialBackoff.DurationValue() > 0 {
+		backoff = h.InitialBackoff.DurationValue()
+	}
+	return retries, backoff
+}
+
+// HubDiskCacheLimits 返回显式配置了 MaxDiskCacheSize 的 Hub 配额，键为 Hub 名称。
+func (c *Config) HubDiskCacheLimits() map[string]int64 {
+	limits := make(map[string]int64)
+	for _, hub := range c.Hubs {
+		if hub.MaxDiskCache > 0 {
+			limits[hub.Name] = hub.MaxDiskCache
+		}
+	}
+	return limits
+}
+
+// HubDiskLayouts 返回各 Hub 生效的磁盘布局（Hub 覆盖优先，否则取模块默认值），键为 Hub 名称。
+func (c *Config) HubDiskLayouts() map[string]string {
+	layouts := make(map[string]string, len(c.Hubs))
+	for _, hub := range c.Hubs {
+		meta, ok := hubmodule.Resolve(strings.ToLower(strings.TrimSpace(hub.Type)))
+		if !ok {
+			continue
+		}
+		layouts[hub.Name] = hubmodule.ResolveStrategy(meta, hub.StrategyOverrides(0)).DiskLayout
+	}
+	return layouts
+}
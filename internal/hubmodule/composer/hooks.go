@@ -2,11 +2,14 @@ package composer
 
 import (
 	"encoding/json"
+	"io"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
 	"sync"
 
+	"github.com/any-hub/any-hub/internal/hubmodule"
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
 )
 
@@ -53,11 +56,13 @@ func (r *distRegistry) reset() {
 
 func init() {
 	hooks.MustRegister("composer", hooks.Hooks{
-		NormalizePath:   normalizePath,
-		ResolveUpstream: resolveDistUpstream,
-		RewriteResponse: rewriteResponse,
-		CachePolicy:     cachePolicy,
-		ContentType:     contentType,
+		NormalizePath:     normalizePath,
+		ResolveUpstream:   resolveDistUpstream,
+		RewriteResponse:   rewriteResponse,
+		RewriteStream:     rewriteStream,
+		SkipStreamRewrite: skipStreamRewrite,
+		CachePolicy:       cachePolicy,
+		ContentType:       contentType,
 	})
 }
 
@@ -123,16 +128,82 @@ func rewriteResponse(
 	}
 }
 
+// rewriteStream 边读边改写包元数据（/p2/、/p/、provider 文件）中的 dist.url，
+// 这类正文对热门包可达数十 MB；/packages.json 交给 rewriteResponse。
+func rewriteStream(ctx *hooks.RequestContext, resp *http.Response) (io.ReadCloser, http.Header, error) {
+	cleanPath := trimComposerNamespace(ctx.Path)
+	domain := strings.TrimSpace(ctx.Domain)
+	if cleanPath == "/packages.json" || !isComposerMetadataPath(cleanPath) || domain == "" {
+		return nil, nil, nil
+	}
+	headers := resp.Header.Clone()
+	headers.Set("Content-Type", "application/json")
+	headers.Del("Content-Encoding")
+	return composerMetadataRewriter(domain).Stream(resp.Body), headers, nil
+}
+
+// composerMetadataRewriter 处理 packages.<name>.<下标或版本号> 下的版本对象：
+// 改写 dist.url 并记录 reference/type 与原始地址供 /dists/ 镜像路径回源，缺少 name 时补上包名。
+func composerMetadataRewriter(domain string) hubmodule.JSONRewriter {
+	var distURL, reference, distType string
+	isVersion := func(path []string) bool {
+		return len(path) >= 3 && path[0] == "packages"
+	}
+	return hubmodule.JSONRewriter{
+		String: func(path []string, value string) string {
+			if !isVersion(path) {
+				return value
+			}
+			switch {
+			case len(path) == 4 && path[3] == "name" && strings.TrimSpace(value) == "":
+				return path[1]
+			case len(path) == 5 && path[3] == "dist":
+				switch path[4] {
+				case "url":
+					distURL = value
+					return rewriteComposerLegacyDistURL(value, domain)
+				case "reference":
+					reference = value
+				case "type":
+					distType = value
+				}
+			}
+			return value
+		},
+		CloseObject: func(path []string, keys map[string]struct{}) []hubmodule.JSONMember {
+			if !isVersion(path) {
+				return nil
+			}
+			switch {
+			case len(path) == 4 && path[3] == "dist":
+				if distURL != "" && reference != "" && distType != "" {
+					composerDists.remember(domain, path[1], reference, distType, distURL)
+				}
+				distURL, reference, distType = "", "", ""
+			case len(path) == 3:
+				if _, ok := keys["name"]; !ok {
+					return []hubmodule.JSONMember{{Key: "name", Value: path[1]}}
+				}
+			}
+			return nil
+		},
+	}
+}
+
 func ensureJSONHeaders(headers map[string]string) map[string]string {
 	if headers == nil {
 		headers = map[string]string{}
 	}
 	headers["Content-Type"] = "application/json"
 	delete(headers, "Content-Encoding")
-	delete(headers, "Etag")
 	return headers
 }
 
+// skipStreamRewrite 让 dist 包跳过 rewriteStream，只有包元数据需要改写 dist.url。
+func skipStreamRewrite(_ *hooks.RequestContext, locatorPath string) bool {
+	return isComposerDistPath(locatorPath)
+}
+
 func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.CachePolicy) hooks.CachePolicy {
 	switch {
 	case isComposerDistPath(locatorPath):
@@ -2,6 +2,9 @@ package composer
 
 import (
 	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
@@ -63,6 +66,55 @@ func TestRewriteResponseUpdatesURLs(t *testing.T) {
 	}
 }
 
+func TestRewriteStreamUpdatesURLs(t *testing.T) {
+	resetComposerDistRegistry()
+	ctx := &hooks.RequestContext{Domain: "cache.example", Path: "/p2/a/b.json"}
+	body := `{"packages":{"a/b":[{"version":"1.0.0","dist":{"type":"zip","url":"https://api.github.com/repos/org/repo/zipball/ref","reference":"abc123"}},{"name":"a/b","version":"0.9.0"}]},"minified":"composer/2.0"}`
+	resp := &http.Response{
+		Header: http.Header{"Etag": {`"v1"`}, "Content-Type": {"text/plain"}},
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}
+	stream, headers, err := rewriteStream(ctx, resp)
+	if err != nil || stream == nil {
+		t.Fatalf("rewrite declined: %v", err)
+	}
+	rewritten, err := io.ReadAll(stream)
+	stream.Close()
+	if err != nil {
+		t.Fatalf("read rewritten body: %v", err)
+	}
+	if headers.Get("Content-Type") != "application/json" || headers.Get("Etag") != `"v1"` {
+		t.Fatalf("unexpected headers: %v", headers)
+	}
+	want := `{"packages":{"a/b":[{"version":"1.0.0","dist":{"type":"zip","url":"https://cache.example/dist/https/api.github.com/repos/org/repo/zipball/ref","reference":"abc123"},"name":"a/b"},{"name":"a/b","version":"0.9.0"}]},"minified":"composer/2.0"}`
+	if string(rewritten) != want {
+		t.Fatalf("unexpected rewrite:\n%s", rewritten)
+	}
+	if target := resolveDistUpstream(ctx, "", "/dists/a/b/abc123.zip", nil); target != "https://api.github.com/repos/org/repo/zipball/ref" {
+		t.Fatalf("expected dist recorded for mirror lookups, got %q", target)
+	}
+}
+
+func TestRewriteStreamLeavesPackagesRootToBufferedHook(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "cache.example", Path: "/packages.json"}
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}
+	if stream, _, err := rewriteStream(ctx, resp); stream != nil || err != nil {
+		t.Fatalf("expected packages.json to be declined, got %v %v", stream, err)
+	}
+}
+
+func TestRewriteResponseKeepsValidator(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "cache.example"}
+	body := []byte(`{"packages":{"a/b":{"1.0.0":{"dist":{"url":"https://api.github.com/repos/org/repo/zipball/ref"}}}}}`)
+	_, headers, _, err := rewriteResponse(ctx, 200, map[string]string{"Etag": `"v1"`}, body, "/p2/a/b.json")
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	if headers["Etag"] != `"v1"` {
+		t.Fatalf("rewritten metadata should keep the upstream validator, got %v", headers)
+	}
+}
+
 func TestRewritePackagesRoot(t *testing.T) {
 	resetComposerDistRegistry()
 	ctx := &hooks.RequestContext{Domain: "cache.example"}
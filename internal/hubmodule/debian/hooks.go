@@ -15,6 +15,7 @@ func init() {
 		NormalizePath: normalizePath,
 		CachePolicy:   cachePolicy,
 		ContentType:   contentType,
+		ContentDigest: contentDigest,
 	})
 }
 
@@ -25,6 +26,10 @@ func normalizePath(_ *hooks.RequestContext, p string, rawQuery []byte) (string,
 
 func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.CachePolicy) hooks.CachePolicy {
 	clean := canonicalPath(locatorPath)
+	if strings.Contains(clean, "/dists/") {
+		// 镜像同步期间索引与 by-hash 文件会短暂 404，不做负缓存以免把缺失状态保留整个 TTL。
+		current.AllowNegativeCache = false
+	}
 	if strings.Contains(clean, "/by-hash/") || strings.Contains(clean, "/pool/") {
 		// pool/*.deb 与 by-hash 路径视为不可变，直接缓存后续不再 HEAD。
 		current.AllowCache = true
@@ -75,6 +80,21 @@ func contentType(_ *hooks.RequestContext, locatorPath string) string {
 	}
 }
 
+// contentDigest 识别 by-hash/SHA256/<hex> 路径，内容与摘要一一对应，可跨 Hub 共享存储。
+func contentDigest(_ *hooks.RequestContext, locatorPath string) string {
+	clean := canonicalPath(locatorPath)
+	const marker = "/by-hash/sha256/"
+	idx := strings.Index(clean, marker)
+	if idx < 0 {
+		return ""
+	}
+	hexValue := clean[idx+len(marker):]
+	if strings.Contains(hexValue, "/") {
+		return ""
+	}
+	return "sha256:" + hexValue
+}
+
 func canonicalPath(p string) string {
 	if p == "" {
 		return "/"
@@ -46,6 +46,19 @@ func TestCachePolicyImmutable(t *testing.T) {
 	}
 }
 
+func TestCachePolicyDistsSkipsNegativeCache(t *testing.T) {
+	for _, p := range []string{"/dists/bookworm/InRelease", "/dists/bookworm/main/binary-amd64/by-hash/SHA256/def"} {
+		current := cachePolicy(nil, p, hooks.CachePolicy{AllowNegativeCache: true})
+		if current.AllowNegativeCache {
+			t.Fatalf("expected %s to opt out of negative caching", p)
+		}
+	}
+	current := cachePolicy(nil, "/pool/main/h/hello.deb", hooks.CachePolicy{AllowNegativeCache: true})
+	if !current.AllowNegativeCache {
+		t.Fatalf("expected pool path to keep negative caching")
+	}
+}
+
 func TestCachePolicyNonAptPath(t *testing.T) {
 	current := cachePolicy(nil, "/other/path", hooks.CachePolicy{})
 	if current.AllowCache || current.AllowStore || current.RequireRevalidate {
@@ -71,3 +84,13 @@ func TestContentType(t *testing.T) {
 		t.Fatalf("expected gzip content-type, got %s", ct)
 	}
 }
+
+func TestContentDigestFromByHashPath(t *testing.T) {
+	got := contentDigest(nil, "/dists/bookworm/main/binary-amd64/by-hash/SHA256/ABCDEF")
+	if got != "sha256:abcdef" {
+		t.Fatalf("expected by-hash digest, got %q", got)
+	}
+	if got := contentDigest(nil, "/pool/main/h/hello.deb"); got != "" {
+		t.Fatalf("expected no digest for pool path, got %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package hubmodule
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultDigestRegistrySize 为 DigestRegistry 默认保留的记录数。
+const DefaultDigestRegistrySize = 100000
+
+// DigestRegistry 记录元数据中为制品声明的摘要，键为 Hub 域名与制品路径，下载制品时据此校验。
+// 记录数受 capacity 约束，超出后淘汰最久未使用的记录；记录只保存在内存中，重启或被淘汰后，
+// 相应制品在其元数据再次经过代理前不做校验。
+type DigestRegistry[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type digestItem[V any] struct {
+	key   string
+	value V
+}
+
+// NewDigestRegistry 创建最多保留 capacity 条记录的注册表，capacity <= 0 时使用 DefaultDigestRegistrySize。
+func NewDigestRegistry[V any](capacity int) *DigestRegistry[V] {
+	if capacity <= 0 {
+		capacity = DefaultDigestRegistrySize
+	}
+	return &DigestRegistry[V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Remember 记录 domain 下 path 的期望摘要，已有记录会被覆盖并视为最近使用。
+func (r *DigestRegistry[V]) Remember(domain, path string, value V) {
+	key := domain + "|" + path
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.items[key]; ok {
+		elem.Value.(*digestItem[V]).value = value
+		r.order.MoveToFront(elem)
+		return
+	}
+	r.items[key] = r.order.PushFront(&digestItem[V]{key: key, value: value})
+	for r.order.Len() > r.capacity {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.items, oldest.Value.(*digestItem[V]).key)
+	}
+}
+
+// Lookup 返回 domain 下 path 的期望摘要。
+func (r *DigestRegistry[V]) Lookup(domain, path string) (V, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elem, ok := r.items[domain+"|"+path]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	r.order.MoveToFront(elem)
+	return elem.Value.(*digestItem[V]).value, true
+}
+
+// Len 返回当前保留的记录数。
+func (r *DigestRegistry[V]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.order.Len()
+}
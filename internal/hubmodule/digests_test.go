@@ -0,0 +1,26 @@
+package hubmodule
+
+import "testing"
+
+func TestDigestRegistryEvictsLeastRecentlyUsed(t *testing.T) {
+	registry := NewDigestRegistry[string](2)
+	registry.Remember("a.local", "/one", "1")
+	registry.Remember("a.local", "/two", "2")
+	if _, ok := registry.Lookup("a.local", "/one"); !ok {
+		t.Fatalf("expected /one to be remembered")
+	}
+	registry.Remember("a.local", "/three", "3")
+
+	if _, ok := registry.Lookup("a.local", "/two"); ok {
+		t.Fatalf("least recently used record should be evicted")
+	}
+	if value, ok := registry.Lookup("a.local", "/one"); !ok || value != "1" {
+		t.Fatalf("recently read record should survive, got %q ok=%v", value, ok)
+	}
+	if _, ok := registry.Lookup("b.local", "/three"); ok {
+		t.Fatalf("records must be scoped by domain")
+	}
+	if registry.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", registry.Len())
+	}
+}
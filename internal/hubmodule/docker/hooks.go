@@ -12,6 +12,9 @@ func init() {
 		NormalizePath: normalizePath,
 		CachePolicy:   cachePolicy,
 		ContentType:   contentType,
+		ContentDigest: contentDigest,
+		Integrity:     integrity,
+		Vary:          vary,
 	})
 }
 
@@ -57,6 +60,41 @@ func contentType(_ *hooks.RequestContext, locatorPath string) string {
 	}
 }
 
+// contentDigest 从 /blobs/sha256:<hex> 路径中提取层摘要，使 docker/ghcr/quay 等 Hub 共享同一份层文件。
+func contentDigest(_ *hooks.RequestContext, locatorPath string) string {
+	idx := strings.Index(locatorPath, "/blobs/sha256:")
+	if idx < 0 {
+		return ""
+	}
+	digest := locatorPath[idx+len("/blobs/"):]
+	if end := strings.IndexByte(digest, '/'); end >= 0 {
+		digest = digest[:end]
+	}
+	return digest
+}
+
+// vary 让按 tag 拉取的 manifest 按 Accept 分别缓存：只接受 schema2 的客户端与接受 OCI index 的客户端
+// 会得到不同的表示。按摘要拉取的 manifest 内容固定，无需区分。
+func vary(_ *hooks.RequestContext, locatorPath string) []string {
+	if strings.Contains(locatorPath, "/manifests/") && !strings.Contains(locatorPath, "/manifests/sha256:") {
+		return []string{"Accept"}
+	}
+	return nil
+}
+
+// integrity 校验按摘要拉取的 manifest（/manifests/sha256:<hex>），层文件已由 contentDigest 覆盖。
+func integrity(_ *hooks.RequestContext, locatorPath string) (hooks.Integrity, bool) {
+	idx := strings.Index(locatorPath, "/manifests/sha256:")
+	if idx < 0 {
+		return hooks.Integrity{}, false
+	}
+	expected := locatorPath[idx+len("/manifests/sha256:"):]
+	if expected == "" || strings.Contains(expected, "/") {
+		return hooks.Integrity{}, false
+	}
+	return hooks.Integrity{Algorithm: "sha256", Expected: expected}, true
+}
+
 func isDockerHubHost(host string) bool {
 	if parsedHost, _, err := net.SplitHostPort(host); err == nil {
 		host = parsedHost
@@ -91,6 +129,16 @@ func RegistryK8sManifestFallbackPath(ctx *hooks.RequestContext, clean string) (s
 	return manifestFallbackPath(ctx, clean)
 }
 
+// PullScope 返回访问 /v2/<repo>/... 所需的 registry token scope（repository:<repo>:pull），
+// 供代理在收到认证质询前直接携带已缓存的 token。
+func PullScope(clean string) (string, bool) {
+	repo, _, ok := splitDockerRepoPath(clean)
+	if !ok {
+		return "", false
+	}
+	return "repository:" + repo + ":pull", true
+}
+
 func splitDockerRepoPath(path string) (string, string, bool) {
 	if !strings.HasPrefix(path, "/v2/") {
 		return "", "", false
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
@@ -75,3 +76,44 @@ func TestSplitDockerRepoPath(t *testing.T) {
 		t.Fatalf("expected catalog path to be ignored")
 	}
 }
+
+func TestPullScope(t *testing.T) {
+	if scope, ok := PullScope("/v2/library/nginx/blobs/sha256:abc"); !ok || scope != "repository:library/nginx:pull" {
+		t.Fatalf("unexpected scope %q ok=%v", scope, ok)
+	}
+	if _, ok := PullScope("/v2/"); ok {
+		t.Fatalf("expected base endpoint to have no repository scope")
+	}
+}
+
+func TestContentDigestFromBlobPath(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	if got := contentDigest(nil, "/v2/library/nginx/blobs/"+digest); got != digest {
+		t.Fatalf("expected blob digest, got %q", got)
+	}
+	if got := contentDigest(nil, "/v2/library/nginx/manifests/latest"); got != "" {
+		t.Fatalf("expected no digest for tag manifest, got %q", got)
+	}
+}
+
+func TestIntegrityForDigestManifest(t *testing.T) {
+	hex := strings.Repeat("b", 64)
+	spec, ok := integrity(nil, "/v2/library/nginx/manifests/sha256:"+hex)
+	if !ok || spec.Algorithm != "sha256" || spec.Expected != hex {
+		t.Fatalf("unexpected integrity spec: %+v ok=%v", spec, ok)
+	}
+	if _, ok := integrity(nil, "/v2/library/nginx/manifests/latest"); ok {
+		t.Fatalf("tag manifest must not carry an expected digest")
+	}
+}
+
+func TestVaryOnlyForTagManifests(t *testing.T) {
+	if got := vary(nil, "/v2/library/nginx/manifests/latest"); len(got) != 1 || got[0] != "Accept" {
+		t.Fatalf("tag manifest should vary on Accept, got %v", got)
+	}
+	for _, p := range []string{"/v2/library/nginx/manifests/sha256:abc", "/v2/library/nginx/blobs/sha256:abc"} {
+		if got := vary(nil, p); got != nil {
+			t.Fatalf("%s should not vary, got %v", p, got)
+		}
+	}
+}
@@ -1,12 +1,22 @@
 package golang
 
-import "github.com/any-hub/any-hub/internal/proxy/hooks"
+import (
+	"net/http"
+	"strings"
 
-import "strings"
+	"github.com/any-hub/any-hub/internal/hubmodule"
+	"github.com/any-hub/any-hub/internal/proxy/hooks"
+)
+
+// moduleSums 记录经代理转发的 sumdb lookup 响应中的 go.sum 哈希，
+// 键为 /<module>/@v/<version>.zip|.mod，下载模块文件时据此校验。
+var moduleSums = hubmodule.NewDigestRegistry[hooks.Integrity](0)
 
 func init() {
 	hooks.MustRegister("go", hooks.Hooks{
-		CachePolicy: cachePolicy,
+		RewriteResponse: rewriteResponse,
+		CachePolicy:     cachePolicy,
+		Integrity:       integrity,
 	})
 }
 
@@ -25,3 +35,55 @@ func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.Cach
 	current.RequireRevalidate = true
 	return current
 }
+
+// integrity 返回 sumdb 为该 .zip/.mod 记录的 h1 哈希；go 命令通常先取 .mod 并查询 sumdb，
+// 因此随后的 .zip 下载即可被校验。
+func integrity(ctx *hooks.RequestContext, locatorPath string) (hooks.Integrity, bool) {
+	if ctx == nil || !strings.Contains(locatorPath, "/@v/") {
+		return hooks.Integrity{}, false
+	}
+	return moduleSums.Lookup(ctx.Domain, locatorPath)
+}
+
+// rewriteResponse 不修改 sumdb 响应，仅解析 lookup 记录中的 go.sum 行。
+func rewriteResponse(
+	ctx *hooks.RequestContext,
+	status int,
+	headers map[string]string,
+	body []byte,
+	path string,
+) (int, map[string]string, []byte, error) {
+	if status == http.StatusOK && ctx != nil {
+		rememberLookup(ctx.Domain, path, body)
+	}
+	return status, headers, body, nil
+}
+
+// rememberLookup 处理 /sumdb/<db>/lookup/<escaped-module>@<version>。记录行中的模块路径未转义，
+// 因此直接沿用请求路径中的转义形式拼出代理下载路径。
+func rememberLookup(domain, requestPath string, body []byte) {
+	if !strings.HasPrefix(requestPath, "/sumdb/") {
+		return
+	}
+	idx := strings.Index(requestPath, "/lookup/")
+	if idx < 0 {
+		return
+	}
+	escaped, version, ok := strings.Cut(requestPath[idx+len("/lookup/"):], "@")
+	if !ok || escaped == "" || version == "" {
+		return
+	}
+	base := "/" + escaped + "/@v/" + version
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], "h1:") {
+			continue
+		}
+		switch fields[1] {
+		case version:
+			moduleSums.Remember(domain, base+".zip", hooks.Integrity{Algorithm: "h1-zip", Expected: fields[2]})
+		case version + "/go.mod":
+			moduleSums.Remember(domain, base+".mod", hooks.Integrity{Algorithm: "h1-mod", Expected: fields[2]})
+		}
+	}
+}
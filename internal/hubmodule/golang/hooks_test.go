@@ -17,3 +17,22 @@ func TestCachePolicyForModuleFiles(t *testing.T) {
 		t.Fatalf("expected non-artifacts to require revalidate")
 	}
 }
+
+func TestRewriteResponseRemembersSumdbHashes(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "go.example.com"}
+	body := []byte("123\ngithub.com/Azure/sdk v1.2.0 h1:zip=\ngithub.com/Azure/sdk v1.2.0/go.mod h1:mod=\n\n— sum.golang.org sig\n")
+	if _, _, out, err := rewriteResponse(ctx, 200, nil, body, "/sumdb/sum.golang.org/lookup/github.com/!azure/sdk@v1.2.0"); err != nil || string(out) != string(body) {
+		t.Fatalf("lookup body must pass through unchanged: %v", err)
+	}
+	spec, ok := integrity(ctx, "/github.com/!azure/sdk/@v/v1.2.0.zip")
+	if !ok || spec.Algorithm != "h1-zip" || spec.Expected != "h1:zip=" {
+		t.Fatalf("unexpected zip integrity: %+v ok=%v", spec, ok)
+	}
+	spec, ok = integrity(ctx, "/github.com/!azure/sdk/@v/v1.2.0.mod")
+	if !ok || spec.Algorithm != "h1-mod" || spec.Expected != "h1:mod=" {
+		t.Fatalf("unexpected mod integrity: %+v ok=%v", spec, ok)
+	}
+	if _, ok := integrity(ctx, "/github.com/!azure/sdk/@v/v1.2.0.info"); ok {
+		t.Fatalf(".info files have no go.sum entry")
+	}
+}
@@ -20,6 +20,13 @@ const (
 	ValidationModeNever        ValidationMode = "never"
 )
 
+// 磁盘布局：raw_path 按 Locator 路径原样落盘；hashed 以路径摘要分片落盘，原始路径记录在 .meta 中，
+// 不受文件/目录冲突、文件名长度与特殊字符的影响。
+const (
+	DiskLayoutRawPath = "raw_path"
+	DiskLayoutHashed  = "hashed"
+)
+
 // CacheStrategyProfile 描述模块的缓存读写策略及其默认值。
 type CacheStrategyProfile struct {
 	TTLHint                time.Duration
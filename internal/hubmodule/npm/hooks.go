@@ -1,19 +1,30 @@
 package npm
 
 import (
+	"io"
+	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/any-hub/any-hub/internal/hubmodule"
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
 )
 
+// tarballIntegrity 记录 packument 中 dist.integrity / dist.shasum 声明的摘要，
+// 键为 tarball 的 URL 路径，下载 tarball 时据此校验正文。
+var tarballIntegrity = hubmodule.NewDigestRegistry[hooks.Integrity](0)
+
 func init() {
 	hooks.MustRegister("npm", hooks.Hooks{
-		CachePolicy: cachePolicy,
+		ObserveStream: observeStream,
+		CachePolicy:   cachePolicy,
+		Integrity:     integrity,
+		Vary:          vary,
 	})
 }
 
 func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.CachePolicy) hooks.CachePolicy {
-	if strings.Contains(locatorPath, "/-/") && strings.HasSuffix(locatorPath, ".tgz") {
+	if isTarballPath(locatorPath) {
 		current.AllowCache = true
 		current.AllowStore = true
 		current.RequireRevalidate = false
@@ -24,3 +35,94 @@ func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.Cach
 	current.RequireRevalidate = true
 	return current
 }
+
+// integrity 返回此前 packument 为该 tarball 声明的摘要，未见过时不校验。
+func integrity(ctx *hooks.RequestContext, locatorPath string) (hooks.Integrity, bool) {
+	if ctx == nil || !isTarballPath(locatorPath) {
+		return hooks.Integrity{}, false
+	}
+	return tarballIntegrity.Lookup(ctx.Domain, locatorPath)
+}
+
+// observeStream 边转发边从 packument 中收集各版本 tarball 的期望摘要，不整包缓冲。
+// 客户端与缓存收到的仍是上游原始字节；非 JSON 或格式异常的正文只是收集不到摘要。
+func observeStream(ctx *hooks.RequestContext, resp *http.Response) io.WriteCloser {
+	if resp.StatusCode != http.StatusOK || isTarballPath(ctx.Path) {
+		return nil
+	}
+	return packumentObserver(ctx.Domain).Observe()
+}
+
+// packumentObserver 在每个 versions.<v>.dist 对象结束时记录其 tarball 的 integrity/shasum。
+func packumentObserver(domain string) hubmodule.JSONRewriter {
+	var tarball, sri, shasum string
+	return hubmodule.JSONRewriter{
+		String: func(path []string, value string) string {
+			if len(path) == 4 && path[0] == "versions" && path[2] == "dist" {
+				switch path[3] {
+				case "tarball":
+					tarball = value
+				case "integrity":
+					sri = value
+				case "shasum":
+					shasum = value
+				}
+			}
+			return value
+		},
+		CloseObject: func(path []string, _ map[string]struct{}) []hubmodule.JSONMember {
+			if len(path) == 3 && path[0] == "versions" && path[2] == "dist" {
+				rememberTarball(domain, tarball, sri, shasum)
+				tarball, sri, shasum = "", "", ""
+			}
+			return nil
+		},
+	}
+}
+
+func rememberTarball(domain, tarball, sri, shasum string) {
+	parsed, err := url.Parse(tarball)
+	if err != nil || parsed.Path == "" {
+		return
+	}
+	if spec, ok := parseDistIntegrity(sri, shasum); ok {
+		tarballIntegrity.Remember(domain, parsed.Path, spec)
+	}
+}
+
+// parseDistIntegrity 从 SRI 字符串中挑选最强的算法（sha512 > sha256 > sha1），
+// 旧包缺少 integrity 时回退到 shasum（sha1 hex）。
+func parseDistIntegrity(sri, shasum string) (hooks.Integrity, bool) {
+	rank := map[string]int{"sha1": 1, "sha256": 2, "sha512": 3}
+	var best hooks.Integrity
+	for _, token := range strings.Fields(sri) {
+		algorithm, value, ok := strings.Cut(token, "-")
+		if !ok || value == "" || rank[algorithm] <= rank[best.Algorithm] {
+			continue
+		}
+		if idx := strings.IndexByte(value, '?'); idx >= 0 {
+			value = value[:idx]
+		}
+		best = hooks.Integrity{Algorithm: algorithm, Expected: value}
+	}
+	if best.Algorithm != "" {
+		return best, true
+	}
+	if shasum = strings.TrimSpace(shasum); shasum != "" {
+		return hooks.Integrity{Algorithm: "sha1", Expected: shasum}, true
+	}
+	return hooks.Integrity{}, false
+}
+
+// vary 让 packument 按 Accept 分别缓存：application/vnd.npm.install-v1+json 为精简元数据，
+// 与完整 packument 不能混用。
+func vary(_ *hooks.RequestContext, locatorPath string) []string {
+	if isTarballPath(locatorPath) {
+		return nil
+	}
+	return []string{"Accept"}
+}
+
+func isTarballPath(locatorPath string) bool {
+	return strings.Contains(locatorPath, "/-/") && strings.HasSuffix(locatorPath, ".tgz")
+}
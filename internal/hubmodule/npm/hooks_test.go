@@ -1,7 +1,11 @@
 package npm
 
 import (
+	"io"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
 )
@@ -20,3 +24,90 @@ func TestCachePolicyForTarball(t *testing.T) {
 		t.Fatalf("metadata should require revalidate")
 	}
 }
+
+func TestObserveStreamRemembersTarballIntegrity(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "npm.example.com", Path: "/pkg"}
+	src := `{"name":"pkg","versions":{"1.0.0":{"dist":{"tarball":"https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz","integrity":"sha1-abc sha512-xyz==","shasum":"deadbeef"}},` +
+		`"0.9.0":{"dist":{"shasum":"0123abcd","tarball":"https://registry.npmjs.org/pkg/-/pkg-0.9.0.tgz"}}}}`
+	sink := observeStream(ctx, jsonResponse(src))
+	if sink == nil {
+		t.Fatalf("expected packument to be observed")
+	}
+	if _, err := io.Copy(sink, strings.NewReader(src)); err != nil {
+		t.Fatalf("observe packument: %v", err)
+	}
+	sink.Close()
+	waitIntegrity(t, ctx, "/pkg/-/pkg-0.9.0.tgz")
+	spec, ok := integrity(ctx, "/pkg/-/pkg-1.0.0.tgz")
+	if !ok || spec.Algorithm != "sha512" || spec.Expected != "xyz==" {
+		t.Fatalf("expected strongest sri digest, got %+v ok=%v", spec, ok)
+	}
+	if spec, ok := integrity(ctx, "/pkg/-/pkg-0.9.0.tgz"); !ok || spec.Algorithm != "sha1" || spec.Expected != "0123abcd" {
+		t.Fatalf("expected shasum fallback regardless of key order, got %+v ok=%v", spec, ok)
+	}
+	if _, ok := integrity(&hooks.RequestContext{Domain: "other"}, "/pkg/-/pkg-1.0.0.tgz"); ok {
+		t.Fatalf("integrity must be scoped by hub domain")
+	}
+}
+
+func TestObserveStreamSkipsNonPackuments(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "npm.example.com", Path: "/pkg"}
+	resp := jsonResponse("{}")
+	resp.StatusCode = http.StatusNotFound
+	if sink := observeStream(ctx, resp); sink != nil {
+		t.Fatalf("error responses should not be observed")
+	}
+	tarball := &hooks.RequestContext{Domain: "npm.example.com", Path: "/pkg/-/pkg-1.0.0.tgz"}
+	if sink := observeStream(tarball, jsonResponse("{}")); sink != nil {
+		t.Fatalf("tarballs should not be observed")
+	}
+}
+
+func TestObserveStreamStopsOnMalformedBody(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "npm.example.com", Path: "/broken"}
+	sink := observeStream(ctx, jsonResponse("Not Found"))
+	if _, err := io.Copy(sink, strings.NewReader(strings.Repeat("Not Found ", 1024))); err == nil {
+		t.Fatalf("expected observer to reject malformed body")
+	}
+	sink.Close()
+}
+
+// waitIntegrity 等待观察 goroutine 处理完写入的正文。
+func waitIntegrity(t *testing.T, ctx *hooks.RequestContext, locatorPath string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := integrity(ctx, locatorPath); ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("integrity for %s was not recorded", locatorPath)
+}
+
+func TestParseDistIntegrityFallsBackToShasum(t *testing.T) {
+	spec, ok := parseDistIntegrity("", "0123abcd")
+	if !ok || spec.Algorithm != "sha1" || spec.Expected != "0123abcd" {
+		t.Fatalf("unexpected fallback: %+v ok=%v", spec, ok)
+	}
+	if _, ok := parseDistIntegrity("md5-abc", ""); ok {
+		t.Fatalf("unsupported algorithms should be ignored")
+	}
+}
+
+func TestVaryOnlyForPackuments(t *testing.T) {
+	if got := vary(nil, "/@scope/pkg/package.json"); len(got) != 1 || got[0] != "Accept" {
+		t.Fatalf("packument should vary on Accept, got %v", got)
+	}
+	if got := vary(nil, "/pkg/-/pkg-1.0.0.tgz"); got != nil {
+		t.Fatalf("tarball should not vary, got %v", got)
+	}
+}
+
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -1,23 +1,43 @@
 package pypi
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
 
 	"golang.org/x/net/html"
 
+	"github.com/any-hub/any-hub/internal/hubmodule"
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
 )
 
+// fileDigests 记录 simple 页面中声明的 sha256（#sha256= 片段或 JSON hashes），
+// 键为改写后的 /files/... 路径，供下载分发文件时声明内容摘要。
+var fileDigests = hubmodule.NewDigestRegistry[string](0)
+
+func rememberDigest(domain, filePath, hexValue string) {
+	hexValue = strings.ToLower(strings.TrimSpace(hexValue))
+	if filePath == "" || hexValue == "" {
+		return
+	}
+	fileDigests.Remember(domain, filePath, hexValue)
+}
+
 func init() {
 	hooks.MustRegister("pypi", hooks.Hooks{
-		NormalizePath:   normalizePath,
-		ResolveUpstream: resolveFilesUpstream,
-		RewriteResponse: rewriteResponse,
-		CachePolicy:     cachePolicy,
-		ContentType:     contentType,
+		NormalizePath:     normalizePath,
+		ResolveUpstream:   resolveFilesUpstream,
+		RewriteResponse:   rewriteResponse,
+		RewriteStream:     rewriteStream,
+		SkipStreamRewrite: skipStreamRewrite,
+		CachePolicy:       cachePolicy,
+		ContentType:       contentType,
+		ContentDigest:     contentDigest,
+		Vary:              vary,
 	})
 }
 
@@ -81,6 +101,30 @@ func cachePolicy(_ *hooks.RequestContext, locatorPath string, current hooks.Cach
 	return current
 }
 
+// skipStreamRewrite 让分发文件跳过 rewriteStream，只有 simple 页面需要改写链接。
+func skipStreamRewrite(_ *hooks.RequestContext, locatorPath string) bool {
+	return isDistributionAsset(locatorPath)
+}
+
+// vary 让 simple 页面按 Accept 分别缓存：PEP 691 JSON 与 HTML 是同一路径的两种表示。
+func vary(_ *hooks.RequestContext, locatorPath string) []string {
+	if strings.HasPrefix(locatorPath, "/simple/") {
+		return []string{"Accept"}
+	}
+	return nil
+}
+
+// contentDigest 返回此前 simple 页面为该分发文件声明的 sha256，未见过时返回空串。
+func contentDigest(ctx *hooks.RequestContext, locatorPath string) string {
+	if ctx == nil || !strings.HasPrefix(locatorPath, "/files/") {
+		return ""
+	}
+	if hexValue, ok := fileDigests.Lookup(ctx.Domain, locatorPath); ok {
+		return "sha256:" + hexValue
+	}
+	return ""
+}
+
 func contentType(_ *hooks.RequestContext, locatorPath string) string {
 	if strings.Contains(locatorPath, "/simple/") {
 		return "text/html"
@@ -124,7 +168,13 @@ func rewritePyPIBody(body []byte, contentType string, domain string) ([]byte, st
 			for _, entry := range files {
 				if fileMap, ok := entry.(map[string]interface{}); ok {
 					if urlValue, ok := fileMap["url"].(string); ok {
-						fileMap["url"] = rewritePyPIFileURL(domain, urlValue)
+						rewritten := rewritePyPIFileURL(domain, urlValue)
+						fileMap["url"] = rewritten
+						if hashes, ok := fileMap["hashes"].(map[string]interface{}); ok {
+							if sha, ok := hashes["sha256"].(string); ok {
+								rememberFileDigest(domain, rewritten, sha)
+							}
+						}
 					}
 				}
 			}
@@ -176,6 +226,76 @@ func rewriteHTMLAttributes(n *html.Node, domain string) {
 	}
 }
 
+// rewriteStream 边读边改写 simple 页面中的分发文件链接，JSON（PEP 691）与 HTML 均不整页缓冲。
+func rewriteStream(ctx *hooks.RequestContext, resp *http.Response) (io.ReadCloser, http.Header, error) {
+	if !strings.HasPrefix(ctx.Path, "/simple") && ctx.Path != "/" {
+		return nil, nil, nil
+	}
+	domain := ctx.Domain
+	headers := resp.Header.Clone()
+	headers.Del("Content-Encoding")
+	peeked := bufio.NewReader(resp.Body)
+	body := readCloser{Reader: peeked, Closer: resp.Body}
+	if isSimpleJSON(headers.Get("Content-Type"), peeked) {
+		headers.Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+		return simpleJSONRewriter(domain).Stream(body), headers, nil
+	}
+	headers.Set("Content-Type", "text/html; charset=utf-8")
+	return hubmodule.StreamHTML(body, func(_, key, value string) (string, bool) {
+		switch key {
+		case "href", "data-dist-info-metadata", "data-core-metadata":
+			if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+				return rewritePyPIFileURL(domain, value), true
+			}
+		}
+		return value, false
+	}), headers, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// isSimpleJSON 依据 Content-Type 或正文首个非空白字符判断是否为 PEP 691 JSON 页面。
+func isSimpleJSON(contentType string, body *bufio.Reader) bool {
+	if strings.Contains(strings.ToLower(contentType), "application/vnd.pypi.simple.v1+json") {
+		return true
+	}
+	head, _ := body.Peek(512)
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// simpleJSONRewriter 改写 files[].url，并在每个文件对象结束时记录其 hashes.sha256。
+func simpleJSONRewriter(domain string) hubmodule.JSONRewriter {
+	var fileURL, sha string
+	return hubmodule.JSONRewriter{
+		String: func(path []string, value string) string {
+			if len(path) < 3 || path[0] != "files" {
+				return value
+			}
+			switch {
+			case len(path) == 3 && path[2] == "url":
+				fileURL = rewritePyPIFileURL(domain, value)
+				return fileURL
+			case len(path) == 4 && path[2] == "hashes" && path[3] == "sha256":
+				sha = value
+			}
+			return value
+		},
+		CloseObject: func(path []string, _ map[string]struct{}) []hubmodule.JSONMember {
+			if len(path) == 2 && path[0] == "files" {
+				if fileURL != "" && sha != "" {
+					rememberFileDigest(domain, fileURL, sha)
+				}
+				fileURL, sha = "", ""
+			}
+			return nil
+		},
+	}
+}
+
 func rewritePyPIFileURL(domain, original string) string {
 	parsed, err := url.Parse(original)
 	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
@@ -192,9 +312,20 @@ func rewritePyPIFileURL(domain, original string) string {
 	if raw := parsed.RawPath; raw != "" {
 		newURL.RawPath = prefix + raw
 	}
+	if sha, ok := strings.CutPrefix(parsed.Fragment, "sha256="); ok {
+		rememberDigest(domain, newURL.Path, sha)
+	}
 	return newURL.String()
 }
 
+func rememberFileDigest(domain, rewrittenURL, hexValue string) {
+	parsed, err := url.Parse(rewrittenURL)
+	if err != nil {
+		return
+	}
+	rememberDigest(domain, parsed.Path, hexValue)
+}
+
 func isDistributionAsset(path string) bool {
 	switch {
 	case strings.HasSuffix(path, ".whl"):
@@ -1,6 +1,8 @@
 package pypi
 
 import (
+	"io"
+	"net/http"
 	"strings"
 	"testing"
 
@@ -40,3 +42,98 @@ func TestRewriteResponseAdjustsLinks(t *testing.T) {
 		t.Fatalf("expected rewritten link, got %s", string(rewritten))
 	}
 }
+
+func rewriteBody(t *testing.T, ctx *hooks.RequestContext, contentType, body string) (http.Header, string) {
+	t.Helper()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}, "Content-Encoding": {"identity"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	stream, headers, err := rewriteStream(ctx, resp)
+	if err != nil || stream == nil {
+		t.Fatalf("rewrite declined: %v", err)
+	}
+	defer stream.Close()
+	out, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("read rewritten body: %v", err)
+	}
+	return headers, string(out)
+}
+
+func TestRewriteStreamAdjustsLinks(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "cache.example", Path: "/simple/requests/"}
+	body := `<html><body><a href="https://files.pythonhosted.org/package.whl">link</a><a href="/relative.whl">rel</a></body></html>`
+	headers, rewritten := rewriteBody(t, ctx, "text/html", body)
+	if headers.Get("Content-Type") != "text/html; charset=utf-8" || headers.Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected headers: %v", headers)
+	}
+	want := `<html><body><a href="https://cache.example/files/https/files.pythonhosted.org/package.whl">link</a><a href="/relative.whl">rel</a></body></html>`
+	if rewritten != want {
+		t.Fatalf("expected only absolute links rewritten, got %s", rewritten)
+	}
+}
+
+func TestRewriteStreamDeclinesNonSimplePaths(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "cache.example", Path: "/files/https/files.pythonhosted.org/package.whl"}
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader("wheel"))}
+	if stream, _, err := rewriteStream(ctx, resp); stream != nil || err != nil {
+		t.Fatalf("expected non-simple path to be declined, got %v %v", stream, err)
+	}
+}
+
+func TestRewriteStreamJSONKeepsOrderAndRecordsHashes(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "json.example", Path: "/simple/demo/"}
+	sha := strings.Repeat("c", 64)
+	body := `  {"meta":{"api-version":"1.1"},"name":"demo","files":[{"hashes":{"sha256":"` + sha + `"},"url":"https://files.pythonhosted.org/packages/demo-1.0.whl","size":10}]}`
+	headers, rewritten := rewriteBody(t, ctx, "application/octet-stream", body)
+	if headers.Get("Content-Type") != "application/vnd.pypi.simple.v1+json" {
+		t.Fatalf("unexpected content type: %s", headers.Get("Content-Type"))
+	}
+	want := `{"meta":{"api-version":"1.1"},"name":"demo","files":[{"hashes":{"sha256":"` + sha + `"},"url":"https://json.example/files/https/files.pythonhosted.org/packages/demo-1.0.whl","size":10}]}`
+	if rewritten != want {
+		t.Fatalf("unexpected JSON rewrite:\n%s", rewritten)
+	}
+	if got := contentDigest(ctx, "/files/https/files.pythonhosted.org/packages/demo-1.0.whl"); got != "sha256:"+sha {
+		t.Fatalf("expected digest from hashes declared before url, got %q", got)
+	}
+}
+
+func TestContentDigestFromSimpleFragment(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "digest.example"}
+	sha := strings.Repeat("b", 64)
+	body := []byte(`<html><body><a href="https://files.pythonhosted.org/packages/demo-1.0.whl#sha256=` + sha + `">demo</a></body></html>`)
+	if _, _, _, err := rewriteResponse(ctx, 200, map[string]string{"Content-Type": "text/html"}, body, "/simple/demo/"); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	got := contentDigest(ctx, "/files/https/files.pythonhosted.org/packages/demo-1.0.whl")
+	if got != "sha256:"+sha {
+		t.Fatalf("expected digest from fragment, got %q", got)
+	}
+	if got := contentDigest(&hooks.RequestContext{Domain: "other.example"}, "/files/https/files.pythonhosted.org/packages/demo-1.0.whl"); got != "" {
+		t.Fatalf("digests must be scoped to the hub domain, got %q", got)
+	}
+}
+
+func TestRewriteStreamRemembersFragmentDigest(t *testing.T) {
+	ctx := &hooks.RequestContext{Domain: "stream-digest.example", Path: "/simple/demo/"}
+	sha := strings.Repeat("b", 64)
+	rewriteBody(t, ctx, "text/html", `<html><body><a href="https://files.pythonhosted.org/packages/demo-1.0.whl#sha256=`+sha+`">demo</a></body></html>`)
+	got := contentDigest(ctx, "/files/https/files.pythonhosted.org/packages/demo-1.0.whl")
+	if got != "sha256:"+sha {
+		t.Fatalf("expected digest from fragment, got %q", got)
+	}
+	if got := contentDigest(&hooks.RequestContext{Domain: "other.example"}, "/files/https/files.pythonhosted.org/packages/demo-1.0.whl"); got != "" {
+		t.Fatalf("digests must be scoped to the hub domain, got %q", got)
+	}
+}
+
+func TestVaryOnlyForSimplePages(t *testing.T) {
+	if got := vary(nil, "/simple/requests/"); len(got) != 1 || got[0] != "Accept" {
+		t.Fatalf("simple page should vary on Accept, got %v", got)
+	}
+	if got := vary(nil, "/files/packages/aa/bb/requests-2.0.whl"); got != nil {
+		t.Fatalf("distribution file should not vary, got %v", got)
+	}
+}
@@ -6,6 +6,7 @@ import "time"
 type StrategyOptions struct {
 	TTLOverride        time.Duration
 	ValidationOverride ValidationMode
+	DiskLayoutOverride string
 }
 
 // ResolveStrategy 将模块的默认策略与 hub 级覆盖合并。
@@ -14,6 +15,9 @@ func ResolveStrategy(meta ModuleMetadata, opts StrategyOptions) CacheStrategyPro
 	if opts.ValidationOverride != "" {
 		strategy.ValidationMode = opts.ValidationOverride
 	}
+	if opts.DiskLayoutOverride != "" {
+		strategy.DiskLayout = opts.DiskLayoutOverride
+	}
 	strategy = normalizeStrategy(strategy)
 	if opts.TTLOverride > 0 {
 		strategy.TTLHint = opts.TTLOverride
@@ -29,7 +33,7 @@ func normalizeStrategy(profile CacheStrategyProfile) CacheStrategyProfile {
 		profile.ValidationMode = ValidationModeETag
 	}
 	if profile.DiskLayout == "" {
-		profile.DiskLayout = "raw_path"
+		profile.DiskLayout = DiskLayoutRawPath
 	}
 	return profile
 }
@@ -0,0 +1,263 @@
+package hubmodule
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"golang.org/x/net/html"
+)
+
+// JSONRewriter 以 token 为单位流式改写 JSON 正文，内存占用只与嵌套深度有关，与正文大小无关。
+// 回调中的 path 为从根开始的对象键与数组下标（十进制字符串），调用方不得持有该切片。
+type JSONRewriter struct {
+	// String 在每个字符串值（不含对象键）处调用，返回值替换原值。
+	String func(path []string, value string) string
+	// CloseObject 在对象结束前调用，keys 为对象已有的键；返回的成员依次追加到对象末尾。
+	CloseObject func(path []string, keys map[string]struct{}) []JSONMember
+}
+
+// JSONMember 描述 CloseObject 追加的对象成员，Value 按 encoding/json 编码。
+type JSONMember struct {
+	Key   string
+	Value any
+}
+
+// Stream 返回改写后的正文。语法错误或读取失败会在读取端以错误结束，调用方不能把截断的结果当作成功。
+func (r JSONRewriter) Stream(src io.ReadCloser) io.ReadCloser {
+	return pipeRewrite(src, r.rewrite)
+}
+
+// Observe 返回只读观察用的写入端：写入的正文在独立 goroutine 中按 token 解析并触发回调，
+// 输出被丢弃。语法错误使后续写入返回错误，调用方应停止抄送但照常使用原始正文；写完后必须 Close。
+func (r JSONRewriter) Observe() io.WriteCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		err := r.rewrite(pr, bufio.NewWriter(io.Discard))
+		if err == nil {
+			err = io.ErrClosedPipe
+		}
+		pr.CloseWithError(err)
+	}()
+	return pw
+}
+
+type jsonFrame struct {
+	object  bool
+	wantKey bool
+	count   int
+	key     string
+	keys    map[string]struct{}
+}
+
+type jsonStream struct {
+	rewriter JSONRewriter
+	out      *bufio.Writer
+	stack    []*jsonFrame
+	path     []string
+	buf      bytes.Buffer
+	enc      *json.Encoder
+}
+
+func (r JSONRewriter) rewrite(src io.Reader, out *bufio.Writer) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	s := &jsonStream{rewriter: r, out: out}
+	s.enc = json.NewEncoder(&s.buf)
+	s.enc.SetEscapeHTML(false)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			if len(s.stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.token(tok); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *jsonStream) top() *jsonFrame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *jsonStream) token(tok json.Token) error {
+	top := s.top()
+	if top != nil && top.object && top.wantKey {
+		if tok == json.Delim('}') {
+			return s.closeObject()
+		}
+		key, _ := tok.(string)
+		if top.count > 0 {
+			s.out.WriteByte(',')
+		}
+		if err := s.encode(key); err != nil {
+			return err
+		}
+		s.out.WriteByte(':')
+		top.key = key
+		top.wantKey = false
+		top.keys[key] = struct{}{}
+		return nil
+	}
+	if tok == json.Delim(']') {
+		s.out.WriteByte(']')
+		s.stack = s.stack[:len(s.stack)-1]
+		s.valueDone()
+		return nil
+	}
+	if top != nil && !top.object && top.count > 0 {
+		s.out.WriteByte(',')
+	}
+	switch value := tok.(type) {
+	case json.Delim:
+		s.out.WriteByte(byte(value))
+		s.stack = append(s.stack, &jsonFrame{object: value == '{', wantKey: true, keys: map[string]struct{}{}})
+		return nil
+	case string:
+		if s.rewriter.String != nil {
+			value = s.rewriter.String(s.currentPath(len(s.stack)), value)
+		}
+		if err := s.encode(value); err != nil {
+			return err
+		}
+	case json.Number:
+		s.out.WriteString(value.String())
+	default:
+		if err := s.encode(value); err != nil {
+			return err
+		}
+	}
+	s.valueDone()
+	return nil
+}
+
+func (s *jsonStream) closeObject() error {
+	top := s.top()
+	if s.rewriter.CloseObject != nil {
+		for _, member := range s.rewriter.CloseObject(s.currentPath(len(s.stack)-1), top.keys) {
+			if top.count > 0 {
+				s.out.WriteByte(',')
+			}
+			if err := s.encode(member.Key); err != nil {
+				return err
+			}
+			s.out.WriteByte(':')
+			if err := s.encode(member.Value); err != nil {
+				return err
+			}
+			top.count++
+		}
+	}
+	s.out.WriteByte('}')
+	s.stack = s.stack[:len(s.stack)-1]
+	s.valueDone()
+	return nil
+}
+
+// valueDone 在一个完整的值写出后推进所属容器的计数。
+func (s *jsonStream) valueDone() {
+	top := s.top()
+	if top == nil {
+		return
+	}
+	top.count++
+	if top.object {
+		top.wantKey = true
+	}
+}
+
+// currentPath 返回前 depth 层容器定位到的当前位置。
+func (s *jsonStream) currentPath(depth int) []string {
+	s.path = s.path[:0]
+	for _, frame := range s.stack[:depth] {
+		if frame.object {
+			s.path = append(s.path, frame.key)
+		} else {
+			s.path = append(s.path, strconv.Itoa(frame.count))
+		}
+	}
+	return s.path
+}
+
+func (s *jsonStream) encode(value any) error {
+	s.buf.Reset()
+	if err := s.enc.Encode(value); err != nil {
+		return err
+	}
+	_, err := s.out.Write(bytes.TrimSuffix(s.buf.Bytes(), []byte{'\n'}))
+	return err
+}
+
+// StreamHTML 逐个 token 改写 HTML 开始标签的属性值：attr 返回 false 时保留原值，
+// 未改动的 token 按原始字节输出，不会像 html.Parse/Render 那样补全或重排文档结构。
+func StreamHTML(src io.ReadCloser, attr func(tag, key, value string) (string, bool)) io.ReadCloser {
+	return pipeRewrite(src, func(r io.Reader, out *bufio.Writer) error {
+		z := html.NewTokenizer(r)
+		var raw []byte
+		for {
+			tt := z.Next()
+			switch tt {
+			case html.ErrorToken:
+				if errors.Is(z.Err(), io.EOF) {
+					return nil
+				}
+				return z.Err()
+			case html.StartTagToken, html.SelfClosingTagToken:
+				raw = append(raw[:0], z.Raw()...)
+				token := z.Token()
+				changed := false
+				for i, a := range token.Attr {
+					if value, ok := attr(token.Data, a.Key, a.Val); ok {
+						token.Attr[i].Val = value
+						changed = true
+					}
+				}
+				if changed {
+					out.WriteString(token.String())
+				} else {
+					out.Write(raw)
+				}
+			default:
+				out.Write(z.Raw())
+			}
+		}
+	})
+}
+
+// pipeRewrite 在独立 goroutine 中执行 rewrite，把结果经管道交给读取端；
+// 读取端关闭时同时关闭上游正文，使仍在读取的 goroutine 尽快退出。
+func pipeRewrite(src io.ReadCloser, rewrite func(io.Reader, *bufio.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer src.Close()
+		out := bufio.NewWriterSize(pw, 32*1024)
+		err := rewrite(src, out)
+		if err == nil {
+			err = out.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return &rewriteBody{PipeReader: pr, src: src}
+}
+
+type rewriteBody struct {
+	*io.PipeReader
+	src io.Closer
+}
+
+func (b *rewriteBody) Close() error {
+	b.PipeReader.Close()
+	return b.src.Close()
+}
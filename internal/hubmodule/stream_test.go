@@ -0,0 +1,69 @@
+package hubmodule
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func readStream(t *testing.T, body io.ReadCloser) (string, error) {
+	t.Helper()
+	defer body.Close()
+	out, err := io.ReadAll(body)
+	return string(out), err
+}
+
+func TestJSONRewriterPreservesValuesAndOrder(t *testing.T) {
+	var paths []string
+	rewriter := JSONRewriter{
+		String: func(path []string, value string) string {
+			paths = append(paths, strings.Join(path, "/"))
+			if value == "old" {
+				return "<new>"
+			}
+			return value
+		},
+		CloseObject: func(path []string, keys map[string]struct{}) []JSONMember {
+			if _, ok := keys["z"]; len(path) == 2 && !ok {
+				return []JSONMember{{Key: "z", Value: []int{1}}}
+			}
+			return nil
+		},
+	}
+	src := `{"b":1.50e3,"a":[true,null,"old",{"k":"old"},{}],"s":"é\"x"}`
+	got, err := readStream(t, rewriter.Stream(io.NopCloser(strings.NewReader(src))))
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	want := `{"b":1.50e3,"a":[true,null,"<new>",{"k":"<new>","z":[1]},{"z":[1]}],"s":"é\"x"}`
+	if got != want {
+		t.Fatalf("unexpected output:\n got %s\nwant %s", got, want)
+	}
+	if strings.Join(paths, ",") != "a/2,a/3/k,s" {
+		t.Fatalf("unexpected string paths: %v", paths)
+	}
+}
+
+func TestJSONRewriterReportsTruncatedInput(t *testing.T) {
+	_, err := readStream(t, JSONRewriter{}.Stream(io.NopCloser(strings.NewReader(`{"a":[1,2`))))
+	if err == nil {
+		t.Fatalf("expected truncated JSON to fail the stream")
+	}
+}
+
+func TestStreamHTMLRewritesOnlyMatchingAttributes(t *testing.T) {
+	src := "<!DOCTYPE html>\n<html><body><A HREF='https://x/a.whl' class=pkg>a</A><br/><script>if (a<b) {}</script></body></html>"
+	got, err := readStream(t, StreamHTML(io.NopCloser(strings.NewReader(src)), func(tag, key, value string) (string, bool) {
+		if tag == "a" && key == "href" {
+			return "/files/" + strings.TrimPrefix(value, "https://"), true
+		}
+		return value, false
+	}))
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	want := "<!DOCTYPE html>\n<html><body><a href=\"/files/x/a.whl\" class=\"pkg\">a</A><br/><script>if (a<b) {}</script></body></html>"
+	if got != want {
+		t.Fatalf("unexpected output:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+// Package metrics 提供进程内的按 Hub 计数器，并以 Prometheus 文本格式通过 /-/metrics 暴露。
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Counter{}
+)
+
+// Counter 是以 Hub 名称为标签的单调递增计数器。
+type Counter struct {
+	name string
+	help string
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+// NewCounter 创建并注册计数器；同名重复注册返回已存在的实例，便于包级变量初始化。
+func NewCounter(name, help string) *Counter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, ok := registry[name]; ok {
+		return existing
+	}
+	counter := &Counter{name: name, help: help, values: map[string]uint64{}}
+	registry[name] = counter
+	return counter
+}
+
+// Inc 为指定 Hub 加一。
+func (c *Counter) Inc(hub string) {
+	c.Add(hub, 1)
+}
+
+// Add 为指定 Hub 累加 delta。
+func (c *Counter) Add(hub string, delta uint64) {
+	c.mu.Lock()
+	c.values[hub] += delta
+	c.mu.Unlock()
+}
+
+// Value 返回指定 Hub 的当前值。
+func (c *Counter) Value(hub string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[hub]
+}
+
+func (c *Counter) snapshot() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]uint64, len(c.values))
+	for hub, value := range c.values {
+		out[hub] = value
+	}
+	return out
+}
+
+// WriteText 以 Prometheus 文本格式输出全部计数器，按名称与 Hub 排序保证输出稳定。
+func WriteText(w io.Writer) error {
+	registryMu.RLock()
+	counters := make([]*Counter, 0, len(registry))
+	for _, counter := range registry {
+		counters = append(counters, counter)
+	}
+	registryMu.RUnlock()
+	sort.Slice(counters, func(i, j int) bool { return counters[i].name < counters[j].name })
+
+	for _, counter := range counters {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name); err != nil {
+			return err
+		}
+		values := counter.snapshot()
+		hubs := make([]string, 0, len(values))
+		for hub := range values {
+			hubs = append(hubs, hub)
+		}
+		sort.Strings(hubs)
+		for _, hub := range hubs {
+			if _, err := fmt.Fprintf(w, "%s{hub=\"%s\"} %d\n", counter.name, escapeLabel(hub), values[hub]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTextRendersCountersPerHub(t *testing.T) {
+	counter := NewCounter("anyhub_test_total", "Test counter.")
+	if NewCounter("anyhub_test_total", "ignored") != counter {
+		t.Fatalf("duplicate registration should return the existing counter")
+	}
+	counter.Inc("npm")
+	counter.Add("docker", 2)
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE anyhub_test_total counter\n",
+		"anyhub_test_total{hub=\"docker\"} 2\nanyhub_test_total{hub=\"npm\"} 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
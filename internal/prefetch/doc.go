@@ -0,0 +1,10 @@
+// Package prefetch warms the cache ahead of time from lockfiles and image
+// lists. Parsers turn package-lock.json, requirements.txt/pylock.toml, go.sum,
+// composer.lock or image references into request targets per hub type; the
+// Prefetcher picks the matching hub, resolves each target to a cache locator
+// through the same hooks the proxy uses (NormalizePath, LocatorRewrite,
+// ResolveUpstream) and, on a miss, replays the request through the Fiber
+// handler chain so entries land in the store exactly as a real client would
+// leave them. Metadata is fetched before the artifacts it describes so module
+// integrity checks stay effective.
+package prefetch
@@ -0,0 +1,312 @@
+package prefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	defaultConcurrency = 4
+	// maxExpandBody 限制为派生后续目标而读取的正文大小（manifest、simple 页面）。
+	maxExpandBody = 16 << 20
+)
+
+// Options 控制预热行为。
+type Options struct {
+	// Concurrency 为同时进行的回源数，默认 4。
+	Concurrency int
+	// Hub 非空时所有目标都发往该 Hub，而不是按类型/registry 自动匹配。
+	Hub string
+}
+
+// Result 为单个目标的处理结果。
+type Result string
+
+const (
+	ResultFetched Result = "fetched"
+	ResultCached  Result = "cached"
+	ResultFailed  Result = "failed"
+)
+
+// Failure 记录一个未能写入缓存的目标。
+type Failure struct {
+	Hub    string
+	Path   string
+	Reason string
+}
+
+// Summary 汇总一次预热。
+type Summary struct {
+	Fetched  int
+	Cached   int
+	Failures []Failure
+}
+
+// Prefetcher 通过与线上请求相同的 Fiber 处理链（Host 路由 → 模块 hooks → 缓存写入）预热缓存。
+type Prefetcher struct {
+	registry *server.HubRegistry
+	handler  fasthttp.RequestHandler
+	store    cache.Store
+	opts     Options
+	logger   *logrus.Logger
+
+	mu      sync.Mutex
+	seen    map[string]struct{}
+	summary Summary
+}
+
+// New 构建 Prefetcher；handler 通常为 server.NewApp(...).Handler()，store 与 handler 使用的 Store 相同。
+func New(registry *server.HubRegistry, handler fasthttp.RequestHandler, store cache.Store, opts Options, logger *logrus.Logger) *Prefetcher {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if logger == nil {
+		logger = logrus.New()
+		logger.SetOutput(io.Discard)
+	}
+	return &Prefetcher{
+		registry: registry,
+		handler:  handler,
+		store:    store,
+		opts:     opts,
+		logger:   logger,
+		seen:     map[string]struct{}{},
+	}
+}
+
+// Run 以 Options.Concurrency 为上限处理 targets 及其派生目标，返回汇总结果。
+// 同一缓存条目只处理一次。
+func (p *Prefetcher) Run(ctx context.Context, targets []Target) Summary {
+	sem := make(chan struct{}, p.opts.Concurrency)
+	var wg sync.WaitGroup
+	var schedule func(targets []Target)
+	schedule = func(targets []Target) {
+		for _, target := range targets {
+			wg.Add(1)
+			go func(target Target) {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					p.record(target.HubType, target.Path, ResultFailed, ctx.Err().Error())
+					return
+				}
+				next := p.process(ctx, target)
+				<-sem
+				schedule(next)
+			}(target)
+		}
+	}
+	schedule(targets)
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	summary := p.summary
+	summary.Failures = append([]Failure(nil), p.summary.Failures...)
+	return summary
+}
+
+// process 处理单个目标并返回需要继续预热的派生目标。
+func (p *Prefetcher) process(ctx context.Context, target Target) []Target {
+	route, err := p.route(target)
+	if err != nil {
+		p.record(target.HubType, target.Path, ResultFailed, err.Error())
+		return target.Next
+	}
+	hub := route.Config.Name
+	header := http.Header{}
+	if target.Accept != "" {
+		header.Set("Accept", target.Accept)
+	}
+	locator, upstream, err := proxy.ResolveRequest(route, target.Path, header)
+	if err != nil {
+		p.record(hub, target.Path, ResultFailed, err.Error())
+		return target.Next
+	}
+	if !p.claim(locator) {
+		return nil
+	}
+
+	result := ResultCached
+	if !p.cached(ctx, locator) {
+		status := p.fetch(route, target)
+		switch {
+		case p.cached(ctx, locator):
+			result = ResultFetched
+		case target.Fallback != "":
+			fallback := target
+			fallback.Path, fallback.Fallback = target.Fallback, ""
+			return p.process(ctx, fallback)
+		case status >= http.StatusBadRequest:
+			p.record(hub, target.Path, ResultFailed, fmt.Sprintf("upstream status %d (%s)", status, upstream))
+			return target.Next
+		default:
+			p.record(hub, target.Path, ResultFailed, fmt.Sprintf("not cacheable (status %d)", status))
+			return target.Next
+		}
+	}
+	p.record(hub, target.Path, result, "")
+
+	next := target.Next
+	if target.Expand != nil {
+		if body, err := p.readCached(ctx, locator); err == nil {
+			next = append(append([]Target(nil), next...), target.Expand(body)...)
+		}
+	}
+	return next
+}
+
+// fetch 构造发往 Hub 域名的 GET 请求并交给处理链。不需要正文的目标附带 Range: bytes=0-0 并要求先落盘，
+// 代理会先把完整正文写入缓存再从缓存切出 1 字节，避免在响应缓冲中保留大文件。
+func (p *Prefetcher) fetch(route *server.HubRoute, target Target) int {
+	var req fasthttp.Request
+	req.Header.SetMethod(http.MethodGet)
+	req.SetRequestURI(target.Path)
+	req.Header.SetHost(route.Config.Domain)
+	if target.Accept != "" {
+		req.Header.Set("Accept", target.Accept)
+	}
+	if target.Expand == nil {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	var fctx fasthttp.RequestCtx
+	fctx.Init(&req, nil, nil)
+	proxy.RequireStoreBeforeRange(&fctx)
+	p.handler(&fctx)
+	status := fctx.Response.StatusCode()
+	fctx.Response.Reset()
+	return status
+}
+
+func (p *Prefetcher) cached(ctx context.Context, locator cache.Locator) bool {
+	result, err := p.store.Get(ctx, locator)
+	if err != nil {
+		return false
+	}
+	result.Reader.Close()
+	// 负缓存标记记录的是上游 404/410，不算已预热。
+	return !result.Entry.Negative()
+}
+
+func (p *Prefetcher) readCached(ctx context.Context, locator cache.Locator) ([]byte, error) {
+	result, err := p.store.Get(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Reader.Close()
+	return io.ReadAll(io.LimitReader(result.Reader, maxExpandBody))
+}
+
+// route 选出目标所属的 Hub：显式 --hub 优先，否则按类型匹配；镜像还需 registry 与 Upstream 主机一致。
+func (p *Prefetcher) route(target Target) (*server.HubRoute, error) {
+	var candidates []server.HubRoute
+	for _, route := range p.registry.List() {
+		if p.opts.Hub != "" {
+			if route.Config.Name == p.opts.Hub {
+				candidates = append(candidates, route)
+			}
+			continue
+		}
+		if !strings.EqualFold(route.Config.Type, target.HubType) {
+			continue
+		}
+		if target.HubType == "docker" && !registryMatches(target.Registry, route) {
+			continue
+		}
+		candidates = append(candidates, route)
+	}
+	switch {
+	case len(candidates) == 0 && p.opts.Hub != "":
+		return nil, fmt.Errorf("hub %q not found", p.opts.Hub)
+	case len(candidates) == 0 && target.Registry != "":
+		return nil, fmt.Errorf("no %s hub proxies %s", target.HubType, target.Registry)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("no %s hub configured", target.HubType)
+	case len(candidates) > 1:
+		return nil, fmt.Errorf("multiple %s hubs configured, use --hub", target.HubType)
+	}
+	if !strings.EqualFold(candidates[0].Config.Type, target.HubType) {
+		return nil, fmt.Errorf("hub %q has type %s, want %s", candidates[0].Config.Name, candidates[0].Config.Type, target.HubType)
+	}
+	route, ok := p.registry.Lookup(candidates[0].Config.Domain)
+	if !ok {
+		return nil, fmt.Errorf("hub %q not routable", candidates[0].Config.Name)
+	}
+	return route, nil
+}
+
+// registryMatches 判断镜像引用的 registry 是否由该 Hub 代理，任一上游匹配即可。
+func registryMatches(registry string, route server.HubRoute) bool {
+	for _, upstream := range route.Upstreams {
+		if hostMatches(registry, strings.ToLower(upstream.URL.Host)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostMatches(registry, host string) bool {
+	if registry == "" {
+		hostname, _, err := net.SplitHostPort(host)
+		if err != nil {
+			hostname = host
+		}
+		switch hostname {
+		case "registry-1.docker.io", "docker.io", "index.docker.io":
+			return true
+		}
+		return false
+	}
+	return host == registry || strings.TrimSuffix(host, ":443") == registry
+}
+
+// claim 标记 locator 已处理，返回 false 表示其他目标已覆盖同一条目。
+func (p *Prefetcher) claim(locator cache.Locator) bool {
+	key := locator.HubName + "|" + locator.Path
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.seen[key]; ok {
+		return false
+	}
+	p.seen[key] = struct{}{}
+	return true
+}
+
+func (p *Prefetcher) record(hub, requestPath string, result Result, reason string) {
+	p.mu.Lock()
+	switch result {
+	case ResultFetched:
+		p.summary.Fetched++
+	case ResultCached:
+		p.summary.Cached++
+	case ResultFailed:
+		p.summary.Failures = append(p.summary.Failures, Failure{Hub: hub, Path: requestPath, Reason: reason})
+	}
+	p.mu.Unlock()
+
+	entry := p.logger.WithFields(logrus.Fields{
+		"action": "prefetch",
+		"hub":    hub,
+		"path":   requestPath,
+		"result": string(result),
+	})
+	if result == ResultFailed {
+		entry.WithError(errors.New(reason)).Warn("prefetch_failed")
+		return
+	}
+	entry.Info("prefetch_entry")
+}
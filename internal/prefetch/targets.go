@@ -0,0 +1,522 @@
+package prefetch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	dockerManifestAccept = "application/vnd.oci.image.index.v1+json, " +
+		"application/vnd.docker.distribution.manifest.list.v2+json, " +
+		"application/vnd.docker.distribution.manifest.v2+json, " +
+		"application/vnd.oci.image.manifest.v1+json"
+	// DefaultPlatform 为镜像索引（多架构 manifest）默认选取的平台。
+	DefaultPlatform = "linux/amd64"
+)
+
+// Target 描述一次预热请求：在 HubType 类型的 Hub 上 GET Path。
+type Target struct {
+	// HubType 对应 [[Hub]].Type，用于挑选 Hub。
+	HubType string
+	// Registry 仅用于镜像：镜像引用中的 registry 主机，Docker Hub 为空。
+	Registry string
+	// Path 为发往 Hub 的请求 URI（路径 + 可选查询串）。
+	Path string
+	// Accept 非空时作为请求的 Accept 头（例如 Docker manifest 的媒体类型）。
+	Accept string
+	// Fallback 在 Path 未能写入缓存时尝试的替代路径。
+	Fallback string
+	// Next 在本条目处理完成后（无论成功与否）继续预热，用于先元数据后制品的顺序。
+	Next []Target
+	// Expand 根据已缓存的正文派生后续目标（镜像 manifest → 层、simple 页面 → 分发文件）。
+	Expand func(body []byte) []Target
+}
+
+// ParseFile 按文件名识别锁文件格式并解析出预热目标。
+func ParseFile(name string, data []byte) ([]Target, error) {
+	base := strings.ToLower(filepath.Base(name))
+	switch {
+	case base == "package-lock.json" || base == "npm-shrinkwrap.json":
+		return ParsePackageLock(data)
+	case base == "go.sum":
+		return ParseGoSum(bytes.NewReader(data))
+	case base == "composer.lock":
+		return ParseComposerLock(data)
+	case base == "pylock.toml" || (strings.HasPrefix(base, "pylock.") && strings.HasSuffix(base, ".toml")):
+		return ParsePylock(data)
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return ParseRequirements(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unrecognized lockfile %q", name)
+	}
+}
+
+type npmLockPackage struct {
+	Resolved     string                    `json:"resolved"`
+	Link         bool                      `json:"link"`
+	Dependencies map[string]npmLockPackage `json:"dependencies"`
+}
+
+// ParsePackageLock 解析 package-lock.json（v1 的 dependencies 与 v2/v3 的 packages），
+// 每个包先预热 packument 再预热 resolved 指向的 tarball。
+func ParsePackageLock(data []byte) ([]Target, error) {
+	var doc struct {
+		Packages     map[string]npmLockPackage `json:"packages"`
+		Dependencies map[string]npmLockPackage `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse package-lock.json: %w", err)
+	}
+	tarballs := map[string][]string{}
+	add := func(name, resolved string) {
+		parsed, err := url.Parse(resolved)
+		if name == "" || err != nil || parsed.Scheme == "" || parsed.Path == "" {
+			return
+		}
+		tarballs[name] = append(tarballs[name], parsed.EscapedPath())
+	}
+	for key, pkg := range doc.Packages {
+		if key == "" || pkg.Link {
+			continue
+		}
+		idx := strings.LastIndex(key, "node_modules/")
+		if idx < 0 {
+			continue
+		}
+		add(key[idx+len("node_modules/"):], pkg.Resolved)
+	}
+	if len(doc.Packages) == 0 {
+		var walk func(deps map[string]npmLockPackage)
+		walk = func(deps map[string]npmLockPackage) {
+			for name, pkg := range deps {
+				add(name, pkg.Resolved)
+				walk(pkg.Dependencies)
+			}
+		}
+		walk(doc.Dependencies)
+	}
+
+	var targets []Target
+	for _, name := range sortedKeys(tarballs) {
+		target := Target{HubType: "npm", Path: "/" + name}
+		for _, tarball := range uniqueStrings(tarballs[name]) {
+			target.Next = append(target.Next, Target{HubType: "npm", Path: tarball})
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*(?:===?\s*([^\s;,#]+))?`)
+
+// ParseRequirements 解析 requirements.txt：每个包预热 simple 页面；
+// 以 == 固定版本的包再从页面中挑出该版本的全部分发文件。
+func ParseRequirements(r io.Reader) ([]Target, error) {
+	var targets []Target
+	scanner := bufio.NewScanner(r)
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			logical.WriteString(strings.TrimSuffix(line, "\\"))
+			continue
+		}
+		logical.WriteString(line)
+		entry := strings.TrimSpace(logical.String())
+		logical.Reset()
+		if idx := strings.Index(entry, " #"); idx >= 0 {
+			entry = entry[:idx]
+		}
+		if entry == "" || strings.HasPrefix(entry, "#") || strings.HasPrefix(entry, "-") || strings.Contains(entry, "://") {
+			continue
+		}
+		match := requirementPattern.FindStringSubmatch(entry)
+		if match == nil {
+			continue
+		}
+		name := normalizePyPIName(match[1])
+		target := Target{HubType: "pypi", Path: "/simple/" + name + "/"}
+		if version := match[3]; version != "" && !strings.Contains(version, "*") {
+			target.Expand = func(body []byte) []Target {
+				return pypiFilesForVersion(body, name, version)
+			}
+		}
+		targets = append(targets, target)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read requirements: %w", err)
+	}
+	return targets, nil
+}
+
+type pylockFile struct {
+	URL string `toml:"url"`
+}
+
+// ParsePylock 解析 PEP 751 pylock.toml：预热 simple 页面后下载锁定的 wheel/sdist。
+func ParsePylock(data []byte) ([]Target, error) {
+	var doc struct {
+		Packages []struct {
+			Name   string       `toml:"name"`
+			Sdist  *pylockFile  `toml:"sdist"`
+			Wheels []pylockFile `toml:"wheels"`
+		} `toml:"packages"`
+	}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse pylock: %w", err)
+	}
+	var targets []Target
+	for _, pkg := range doc.Packages {
+		if pkg.Name == "" {
+			continue
+		}
+		target := Target{HubType: "pypi", Path: "/simple/" + normalizePyPIName(pkg.Name) + "/"}
+		files := pkg.Wheels
+		if pkg.Sdist != nil {
+			files = append(files, *pkg.Sdist)
+		}
+		for _, file := range files {
+			if filePath := pypiFilePath(file.URL); filePath != "" {
+				target.Next = append(target.Next, Target{HubType: "pypi", Path: filePath})
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// ParseGoSum 解析 go.sum：先经代理查询 sumdb（以便校验 h1 哈希），再预热 .info/.mod/.zip。
+// 仅有 /go.mod 行的模块版本只需要 .mod。
+func ParseGoSum(r io.Reader) ([]Target, error) {
+	type moduleVersion struct {
+		module, version string
+	}
+	needZip := map[moduleVersion]bool{}
+	var order []moduleVersion
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		version, goModOnly := strings.CutSuffix(fields[1], "/go.mod")
+		key := moduleVersion{module: fields[0], version: version}
+		if _, seen := needZip[key]; !seen {
+			order = append(order, key)
+			needZip[key] = false
+		}
+		if !goModOnly {
+			needZip[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read go.sum: %w", err)
+	}
+
+	var targets []Target
+	for _, key := range order {
+		escapedModule, err := escapeModulePath(key.module)
+		if err != nil {
+			return nil, err
+		}
+		escapedVersion, err := escapeModulePath(key.version)
+		if err != nil {
+			return nil, err
+		}
+		base := "/" + escapedModule + "/@v/" + escapedVersion
+		target := Target{
+			HubType: "go",
+			Path:    "/sumdb/sum.golang.org/lookup/" + escapedModule + "@" + escapedVersion,
+			Next:    []Target{{HubType: "go", Path: base + ".mod"}},
+		}
+		if needZip[key] {
+			target.Next = append(target.Next,
+				Target{HubType: "go", Path: base + ".info"},
+				Target{HubType: "go", Path: base + ".zip"},
+			)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// ParseComposerLock 解析 composer.lock：预热 p2 元数据后按 mirrors 形式（/dists/）拉取分发包，
+// 代理尚未见过该包元数据时回退到 /dist/<scheme>/<host>/<path>。
+func ParseComposerLock(data []byte) ([]Target, error) {
+	type lockPackage struct {
+		Name string `json:"name"`
+		Dist struct {
+			Type      string `json:"type"`
+			URL       string `json:"url"`
+			Reference string `json:"reference"`
+		} `json:"dist"`
+	}
+	var doc struct {
+		Packages    []lockPackage `json:"packages"`
+		PackagesDev []lockPackage `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse composer.lock: %w", err)
+	}
+	var targets []Target
+	for _, pkg := range append(doc.Packages, doc.PackagesDev...) {
+		name := strings.ToLower(strings.TrimSpace(pkg.Name))
+		if name == "" {
+			continue
+		}
+		target := Target{HubType: "composer", Path: "/p2/" + name + ".json"}
+		if pkg.Dist.Reference != "" && pkg.Dist.Type != "" {
+			dist := Target{
+				HubType: "composer",
+				Path:    "/dists/" + name + "/" + pkg.Dist.Reference + "." + pkg.Dist.Type,
+			}
+			if parsed, err := url.Parse(pkg.Dist.URL); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+				dist.Fallback = "/dist/" + parsed.Scheme + "/" + parsed.Host + parsed.EscapedPath()
+				if parsed.RawQuery != "" {
+					dist.Fallback += "?" + parsed.RawQuery
+				}
+			}
+			target.Next = []Target{dist}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// ParseImageList 解析每行一个的镜像引用（# 开头为注释），
+// 预热 manifest、platform 对应的子 manifest、config 与全部层。
+func ParseImageList(r io.Reader, platform string) ([]Target, error) {
+	if platform == "" {
+		platform = DefaultPlatform
+	}
+	var targets []Target
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		registry, repo, reference, err := parseImageReference(line)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, manifestTarget(registry, repo, reference, platform))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read image list: %w", err)
+	}
+	return targets, nil
+}
+
+// parseImageReference 拆分 [registry/]repo[:tag][@digest]；首段包含 . 或 : 或为 localhost 时视为 registry。
+func parseImageReference(ref string) (string, string, string, error) {
+	registry := ""
+	rest := ref
+	if first, remainder, ok := strings.Cut(ref, "/"); ok &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry, rest = strings.ToLower(first), remainder
+	}
+	reference := "latest"
+	if name, digest, ok := strings.Cut(rest, "@"); ok {
+		rest, reference = name, digest
+	} else if idx := strings.LastIndex(rest, ":"); idx >= 0 {
+		rest, reference = rest[:idx], rest[idx+1:]
+	}
+	if rest == "" || reference == "" {
+		return "", "", "", fmt.Errorf("invalid image reference %q", ref)
+	}
+	if registry == "docker.io" || registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		registry = ""
+	}
+	return registry, rest, reference, nil
+}
+
+func manifestTarget(registry, repo, reference, platform string) Target {
+	return Target{
+		HubType:  "docker",
+		Registry: registry,
+		Path:     "/v2/" + repo + "/manifests/" + reference,
+		Accept:   dockerManifestAccept,
+		Expand: func(body []byte) []Target {
+			return expandManifest(body, registry, repo, platform)
+		},
+	}
+}
+
+type ociDescriptor struct {
+	Digest   string `json:"digest"`
+	Platform *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+// expandManifest 对镜像索引选出 platform 对应的 manifest，对单平台 manifest 列出 config 与层。
+func expandManifest(body []byte, registry, repo, platform string) []Target {
+	var doc struct {
+		Manifests []ociDescriptor `json:"manifests"`
+		Config    *ociDescriptor  `json:"config"`
+		Layers    []ociDescriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	var targets []Target
+	for _, desc := range doc.Manifests {
+		if desc.Platform == nil || desc.Digest == "" {
+			continue
+		}
+		candidate := desc.Platform.OS + "/" + desc.Platform.Architecture
+		if desc.Platform.Variant != "" && strings.Count(platform, "/") == 2 {
+			candidate += "/" + desc.Platform.Variant
+		}
+		if candidate == platform {
+			targets = append(targets, manifestTarget(registry, repo, desc.Digest, platform))
+		}
+	}
+	blobs := doc.Layers
+	if doc.Config != nil {
+		blobs = append([]ociDescriptor{*doc.Config}, blobs...)
+	}
+	for _, desc := range blobs {
+		if desc.Digest == "" {
+			continue
+		}
+		targets = append(targets, Target{
+			HubType:  "docker",
+			Registry: registry,
+			Path:     "/v2/" + repo + "/blobs/" + desc.Digest,
+		})
+	}
+	return targets
+}
+
+// pypiFilesForVersion 从改写后的 simple 页面（HTML 或 JSON）中挑出 name==version 的分发文件。
+func pypiFilesForVersion(body []byte, name, version string) []Target {
+	var links []string
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc struct {
+			Files []struct {
+				URL string `json:"url"`
+			} `json:"files"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil
+		}
+		for _, file := range doc.Files {
+			links = append(links, file.URL)
+		}
+	} else {
+		for _, match := range hrefPattern.FindAllSubmatch(body, -1) {
+			links = append(links, strings.ReplaceAll(string(match[1]), "&amp;", "&"))
+		}
+	}
+
+	var targets []Target
+	for _, link := range links {
+		parsed, err := url.Parse(link)
+		if err != nil || !strings.HasPrefix(parsed.Path, "/files/") {
+			continue
+		}
+		project, fileVersion, ok := distributionNameVersion(path.Base(parsed.Path))
+		if !ok || normalizePyPIName(project) != name || fileVersion != version {
+			continue
+		}
+		filePath := parsed.EscapedPath()
+		if parsed.RawQuery != "" {
+			filePath += "?" + parsed.RawQuery
+		}
+		targets = append(targets, Target{HubType: "pypi", Path: filePath})
+	}
+	return targets
+}
+
+var hrefPattern = regexp.MustCompile(`href="([^"]+)"`)
+
+// distributionNameVersion 从 wheel（name-version-tags.whl）或 sdist（name-version.tar.gz 等）文件名中取出项目名与版本。
+func distributionNameVersion(file string) (string, string, bool) {
+	if stem, ok := strings.CutSuffix(file, ".whl"); ok {
+		parts := strings.Split(stem, "-")
+		if len(parts) < 5 {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+	for _, ext := range []string{".tar.gz", ".tar.bz2", ".tgz", ".zip"} {
+		if stem, ok := strings.CutSuffix(file, ext); ok {
+			idx := strings.LastIndex(stem, "-")
+			if idx <= 0 {
+				return "", "", false
+			}
+			return stem[:idx], stem[idx+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// pypiFilePath 把 files.pythonhosted.org 等分发地址映射为 pypi 模块的 /files/<scheme>/<host>/<path>。
+func pypiFilePath(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return ""
+	}
+	return "/files/" + parsed.Scheme + "/" + parsed.Host + parsed.EscapedPath()
+}
+
+// normalizePyPIName 按 PEP 503 规范化项目名。
+func normalizePyPIName(name string) string {
+	return strings.ToLower(pypiSeparators.ReplaceAllString(name, "-"))
+}
+
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// escapeModulePath 按 GOPROXY 协议把大写字母转义为 "!" + 小写字母。
+func escapeModulePath(p string) (string, error) {
+	var b strings.Builder
+	for _, r := range p {
+		switch {
+		case r == '!':
+			return "", fmt.Errorf("invalid module path %q", p)
+		case r >= 'A' && r <= 'Z':
+			b.WriteByte('!')
+			b.WriteRune(r + ('a' - 'A'))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := values[:0]
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
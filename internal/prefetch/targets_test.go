@@ -0,0 +1,173 @@
+package prefetch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePackageLock(t *testing.T) {
+	data := []byte(`{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "demo"},
+    "node_modules/lodash": {"resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz"},
+    "node_modules/@babel/core": {"resolved": "https://registry.npmjs.org/@babel/core/-/core-7.24.0.tgz"},
+    "node_modules/a/node_modules/lodash": {"resolved": "https://registry.npmjs.org/lodash/-/lodash-3.10.1.tgz"},
+    "node_modules/local": {"link": true, "resolved": "../local"}
+  }
+}`)
+	targets, err := ParsePackageLock(data)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 packuments, got %+v", targets)
+	}
+	if targets[0].Path != "/@babel/core" || targets[0].Next[0].Path != "/@babel/core/-/core-7.24.0.tgz" {
+		t.Fatalf("unexpected scoped target: %+v", targets[0])
+	}
+	if targets[1].Path != "/lodash" || len(targets[1].Next) != 2 {
+		t.Fatalf("expected both lodash tarballs after packument: %+v", targets[1])
+	}
+}
+
+func TestParsePackageLockV1(t *testing.T) {
+	data := []byte(`{"lockfileVersion": 1, "dependencies": {
+  "debug": {"resolved": "https://registry.npmjs.org/debug/-/debug-4.3.4.tgz",
+    "dependencies": {"ms": {"resolved": "https://registry.npmjs.org/ms/-/ms-2.1.2.tgz"}}}
+}}`)
+	targets, err := ParsePackageLock(data)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 2 || targets[1].Path != "/ms" {
+		t.Fatalf("expected nested dependencies, got %+v", targets)
+	}
+}
+
+func TestParseRequirementsExpandsPinnedVersions(t *testing.T) {
+	input := strings.Join([]string{
+		"# comment",
+		"--index-url https://example.com/simple",
+		"Requests[socks]==2.31.0 ; python_version > '3.7' \\",
+		"    --hash=sha256:abc",
+		"zope.interface>=6",
+		"",
+	}, "\n")
+	targets, err := ParseRequirements(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Path != "/simple/requests/" || targets[1].Path != "/simple/zope-interface/" {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+	if targets[1].Expand != nil {
+		t.Fatalf("unpinned requirement should not expand")
+	}
+	page := []byte(`<a href="https://pypi.local/files/https/files.pythonhosted.org/packages/aa/requests-2.31.0-py3-none-any.whl#sha256=1">w</a>
+<a href="https://pypi.local/files/https/files.pythonhosted.org/packages/bb/requests-2.31.0.tar.gz#sha256=2">s</a>
+<a href="https://pypi.local/files/https/files.pythonhosted.org/packages/cc/requests-2.30.0.tar.gz#sha256=3">old</a>`)
+	files := targets[0].Expand(page)
+	if len(files) != 2 {
+		t.Fatalf("expected wheel and sdist for pinned version, got %+v", files)
+	}
+	if files[0].Path != "/files/https/files.pythonhosted.org/packages/aa/requests-2.31.0-py3-none-any.whl" {
+		t.Fatalf("unexpected file path: %s", files[0].Path)
+	}
+}
+
+func TestParsePylock(t *testing.T) {
+	data := []byte(`lock-version = "1.0"
+[[packages]]
+name = "Attrs"
+version = "25.1.0"
+sdist = {url = "https://files.pythonhosted.org/packages/aa/attrs-25.1.0.tar.gz", hashes = {sha256 = "00"}}
+wheels = [{url = "https://files.pythonhosted.org/packages/bb/attrs-25.1.0-py3-none-any.whl", hashes = {sha256 = "11"}}]
+`)
+	targets, err := ParsePylock(data)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 1 || targets[0].Path != "/simple/attrs/" || len(targets[0].Next) != 2 {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+	if targets[0].Next[1].Path != "/files/https/files.pythonhosted.org/packages/aa/attrs-25.1.0.tar.gz" {
+		t.Fatalf("unexpected sdist path: %s", targets[0].Next[1].Path)
+	}
+}
+
+func TestParseGoSum(t *testing.T) {
+	input := strings.Join([]string{
+		"github.com/BurntSushi/toml v1.3.2 h1:aaa=",
+		"github.com/BurntSushi/toml v1.3.2/go.mod h1:bbb=",
+		"golang.org/x/text v0.3.0/go.mod h1:ccc=",
+	}, "\n")
+	targets, err := ParseGoSum(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected one target per module version, got %+v", targets)
+	}
+	if targets[0].Path != "/sumdb/sum.golang.org/lookup/github.com/!burnt!sushi/toml@v1.3.2" || len(targets[0].Next) != 3 {
+		t.Fatalf("unexpected module target: %+v", targets[0])
+	}
+	if targets[0].Next[2].Path != "/github.com/!burnt!sushi/toml/@v/v1.3.2.zip" {
+		t.Fatalf("unexpected zip path: %s", targets[0].Next[2].Path)
+	}
+	if len(targets[1].Next) != 1 || targets[1].Next[0].Path != "/golang.org/x/text/@v/v0.3.0.mod" {
+		t.Fatalf("go.mod-only versions should only fetch .mod: %+v", targets[1])
+	}
+}
+
+func TestParseComposerLock(t *testing.T) {
+	data := []byte(`{"packages": [{"name": "Monolog/Monolog", "dist": {"type": "zip",
+  "url": "https://api.github.com/repos/Seldaek/monolog/zipball/abc123", "reference": "abc123"}}],
+  "packages-dev": [{"name": "phpunit/phpunit", "dist": {"type": "zip", "url": "", "reference": "def"}}]}`)
+	targets, err := ParseComposerLock(data)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Path != "/p2/monolog/monolog.json" {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+	dist := targets[0].Next[0]
+	if dist.Path != "/dists/monolog/monolog/abc123.zip" ||
+		dist.Fallback != "/dist/https/api.github.com/repos/Seldaek/monolog/zipball/abc123" {
+		t.Fatalf("unexpected dist target: %+v", dist)
+	}
+}
+
+func TestParseImageList(t *testing.T) {
+	input := "nginx:1.25\n# skip\nghcr.io/acme/app@sha256:abc\nlocalhost:5000/team/tool\n"
+	targets, err := ParseImageList(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	want := []struct{ registry, path string }{
+		{"", "/v2/nginx/manifests/1.25"},
+		{"ghcr.io", "/v2/acme/app/manifests/sha256:abc"},
+		{"localhost:5000", "/v2/team/tool/manifests/latest"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+	for i, w := range want {
+		if targets[i].Registry != w.registry || targets[i].Path != w.path {
+			t.Fatalf("target %d = %+v, want %+v", i, targets[i], w)
+		}
+	}
+
+	index := []byte(`{"manifests": [
+  {"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}},
+  {"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}}]}`)
+	children := targets[0].Expand(index)
+	if len(children) != 1 || children[0].Path != "/v2/nginx/manifests/sha256:amd" {
+		t.Fatalf("expected linux/amd64 manifest, got %+v", children)
+	}
+	manifest := []byte(`{"config": {"digest": "sha256:cfg"}, "layers": [{"digest": "sha256:l1"}, {"digest": "sha256:l2"}]}`)
+	blobs := children[0].Expand(manifest)
+	if len(blobs) != 3 || blobs[0].Path != "/v2/nginx/blobs/sha256:cfg" || blobs[2].Path != "/v2/nginx/blobs/sha256:l2" {
+		t.Fatalf("unexpected blobs: %+v", blobs)
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/metrics"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// coalescedRequests 统计因同一条目已有回源在途而等待其结果的请求数。
+var coalescedRequests = metrics.NewCounter(
+	"anyhub_coalesced_requests_total",
+	"Requests that waited for an in-flight upstream fetch of the same cache entry instead of fetching it again.",
+)
+
+// flight 表示某个 Locator 的一次在途回源；done 关闭后 status/stale/err 只读。
+type flight struct {
+	done   chan struct{}
+	status int
+	// stale 表示 leader 因上游失败回放了 StaleIfError 窗口内的缓存副本。
+	stale bool
+	err   error
+}
+
+// flightGroup 以 Locator 为键合并并发回源：首个未命中的请求负责下载并写缓存，
+// 其余请求等待其完成后直接读取缓存条目，失败结果同样广播给所有等待者。
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[cache.Locator]*flight
+}
+
+// join 返回 Locator 当前的在途回源；leader 为 true 表示调用方需要负责回源并调用 finish。
+func (g *flightGroup) join(locator cache.Locator) (*flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.flights == nil {
+		g.flights = make(map[cache.Locator]*flight)
+	}
+	if existing, ok := g.flights[locator]; ok {
+		return existing, false
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[locator] = f
+	return f, true
+}
+
+func (g *flightGroup) finish(locator cache.Locator, f *flight, status int, stale bool, err error) {
+	g.mu.Lock()
+	if g.flights[locator] == f {
+		delete(g.flights, locator)
+	}
+	g.mu.Unlock()
+	f.status = status
+	f.stale = stale
+	f.err = err
+	close(f.done)
+}
+
+// shouldCoalesce 仅合并会写入缓存的 GET 回源，其余请求（HEAD、不缓存路径）各自直连上游。
+func shouldCoalesce(c fiber.Ctx, policy cachePolicy, writer cache.StrategyWriter) bool {
+	return c.Method() == http.MethodGet && policy.allowStore && writer.Enabled()
+}
+
+// fetchCoalesced 在 fetchAndStream 外包一层单飞逻辑。等待者在回源成功后直接命中缓存；
+// 回源出错或上游返回 5xx 时等待者同样得到失败（StaleIfError 窗口内或熔断期间改为回放缓存副本）；其他未落盘的结果（如 401、未启用负缓存时的 404）由等待者自行回源，
+// 以保留上游的原始响应头与正文。
+func (h *Handler) fetchCoalesced(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	policy cachePolicy,
+	writer cache.StrategyWriter,
+	requestID string,
+	started time.Time,
+	ctx context.Context,
+	hook *hookState,
+) (err error) {
+	f, leader := h.flights.join(locator)
+	if leader {
+		defer func() {
+			stale := string(c.Response().Header.Peek(staleHeader)) == "true"
+			h.flights.finish(locator, f, c.Response().StatusCode(), stale, err)
+		}()
+		return h.fetchAndStream(c, route, locator, policy, writer, requestID, started, ctx, hook)
+	}
+
+	coalescedRequests.Inc(route.Config.Name)
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if f.stale || f.err != nil || f.status >= http.StatusInternalServerError {
+		var reason string
+		switch {
+		case f.err != nil:
+			reason = f.err.Error()
+		case f.stale:
+			reason = "coalesced upstream fetch failed"
+		default:
+			reason = fmt.Sprintf("coalesced upstream status %d", f.status)
+		}
+		// 领头请求已回放缓存副本（例如熔断期间不受 StaleIfError 限制）时，等待者沿用同一判断。
+		if stale := h.lookupStale(ctx, route, locator, f.stale || circuitOpen(f.err)); stale != nil {
+			return h.serveStale(c, route, stale, requestID, started, hook, reason)
+		}
+		status := f.status
+		if f.err != nil || status < http.StatusInternalServerError {
+			status = fiber.StatusBadGateway
+		}
+		h.logResult(c, route, route.PrimaryUpstream().String(), requestID, status, false, started, f.err)
+		return h.writeError(c, status, "upstream_failed")
+	}
+	if result, getErr := h.store.Get(readContext(ctx, policy), locator); getErr == nil {
+		if !variantMatches(result.Entry, headerGetter(c)) ||
+			result.Entry.Negative() && !(policy.allowNegative && withinNegativeTTL(route, result.Entry, time.Now())) {
+			result.Reader.Close()
+			return h.fetchAndStream(c, route, locator, policy, writer, requestID, started, ctx, hook)
+		}
+		defer result.Reader.Close()
+		return h.serveCache(c, route, result, requestID, started, hook, true)
+	}
+	return h.fetchAndStream(c, route, locator, policy, writer, requestID, started, ctx, hook)
+}
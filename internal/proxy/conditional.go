@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+// entryValidators 返回缓存命中时对客户端公布的校验器：ETag 依次取上游 ETag、Docker-Content-Digest、
+// 写入时的内容摘要；Last-Modified 取上游值，缺失时回退为条目的写入时间。
+func entryValidators(entry cache.Entry) (string, time.Time) {
+	etag := strings.TrimSpace(entry.Response.ETag)
+	if etag == "" {
+		if digest := normalizeETag(entry.Response.DockerContentDigest); digest != "" {
+			etag = `"` + digest + `"`
+		} else if entry.Digest != "" {
+			etag = `"` + entry.Digest + `"`
+		}
+	}
+	lastModified := entry.ModTime
+	if entry.Response.LastModified != "" {
+		if value, err := http.ParseTime(entry.Response.LastModified); err == nil {
+			lastModified = value
+		}
+	}
+	return etag, lastModified.UTC().Truncate(time.Second)
+}
+
+// setValidators 为缓存命中补齐 ETag 与 Last-Modified，上游原有的头部（已由 applyCachedHeaders 回放）保持不变。
+func setValidators(c fiber.Ctx, etag string, lastModified time.Time) {
+	if etag != "" && len(c.Response().Header.Peek(fiber.HeaderETag)) == 0 {
+		c.Set(fiber.HeaderETag, etag)
+	}
+	if !lastModified.IsZero() && len(c.Response().Header.Peek(fiber.HeaderLastModified)) == 0 {
+		c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	}
+}
+
+// notModified 按 RFC 9110 第 13.2.2 节判断 GET/HEAD 条件请求能否以 304 应答：
+// 存在 If-None-Match 时只做弱比较并忽略 If-Modified-Since；否则比较 If-Modified-Since 与 Last-Modified。
+func notModified(c fiber.Ctx, etag string, lastModified time.Time) bool {
+	if method := c.Method(); method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		return etagListMatches(ifNoneMatch, etag)
+	}
+	ifModifiedSince := c.Get(fiber.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
+
+// etagListMatches 对 If-None-Match 的取值列表做弱比较，"*" 匹配任何存在的表示。
+func etagListMatches(list, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for candidate := range strings.SplitSeq(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || weakETag(candidate) == weakETag(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func weakETag(value string) string {
+	return strings.TrimPrefix(value, "W/")
+}
+
+// writeNotModified 以 304 应答条件请求：保留校验器与缓存相关头部，去掉正文相关头部。
+func writeNotModified(c fiber.Ctx) {
+	c.Response().Header.Del(fiber.HeaderContentType)
+	c.Response().Header.Del(fiber.HeaderContentLength)
+	c.Response().Header.Del(fiber.HeaderAcceptRanges)
+	c.Status(fiber.StatusNotModified)
+	c.Response().ResetBody()
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+func TestEntryValidatorsFallbacks(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	cases := []struct {
+		name  string
+		entry cache.Entry
+		etag  string
+		last  time.Time
+	}{
+		{
+			name:  "upstream validators",
+			entry: cache.Entry{ModTime: modTime, Digest: "sha256:abc", Response: cache.ResponseMetadata{ETag: `W/"v1"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}},
+			etag:  `W/"v1"`,
+			last:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "docker digest",
+			entry: cache.Entry{ModTime: modTime, Response: cache.ResponseMetadata{DockerContentDigest: "sha256:def"}},
+			etag:  `"sha256:def"`,
+			last:  modTime.Truncate(time.Second),
+		},
+		{
+			name:  "content digest",
+			entry: cache.Entry{ModTime: modTime, Digest: "sha256:abc"},
+			etag:  `"sha256:abc"`,
+			last:  modTime.Truncate(time.Second),
+		},
+		{
+			name:  "mtime only",
+			entry: cache.Entry{ModTime: modTime},
+			last:  modTime.Truncate(time.Second),
+		},
+	}
+	for _, tc := range cases {
+		etag, last := entryValidators(tc.entry)
+		if etag != tc.etag || !last.Equal(tc.last) {
+			t.Fatalf("%s: got (%q, %s), want (%q, %s)", tc.name, etag, last.Format(http.TimeFormat), tc.etag, tc.last.Format(http.TimeFormat))
+		}
+	}
+}
+
+func TestETagListMatchesUsesWeakComparison(t *testing.T) {
+	cases := []struct {
+		list string
+		etag string
+		want bool
+	}{
+		{`"v1"`, `"v1"`, true},
+		{`W/"v1"`, `"v1"`, true},
+		{`"v0", "v1"`, `W/"v1"`, true},
+		{`*`, `"v1"`, true},
+		{`*`, ``, false},
+		{`"v2"`, `"v1"`, false},
+	}
+	for _, tc := range cases {
+		if got := etagListMatches(tc.list, tc.etag); got != tc.want {
+			t.Fatalf("etagListMatches(%q, %q) = %v, want %v", tc.list, tc.etag, got, tc.want)
+		}
+	}
+}
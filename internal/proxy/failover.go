@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/logging"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// shouldFailover 判断本次上游结果是否应切换到下一个上游：连接错误、超时、5xx 与 429。
+// 404 等客户端错误视为上游的确定答复，不切换镜像。
+func shouldFailover(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// throttled 判断上游是否只是要求稍后再试：429 或携带 Retry-After 的响应（如维护中的 503）。
+// 这类响应仍切换镜像，但上游本身是健康的，不计入熔断器失败，以免限流把镜像整体熔断。
+func throttled(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	_, ok := retryAfter(resp.Header, time.Now())
+	return ok
+}
+
+// circuitOpenError 表示 Hub 的所有上游都处于熔断状态，请求未发往任何上游。
+type circuitOpenError struct {
+	// until 为最早一个上游结束冷却、可以探测的时间。
+	until time.Time
+}
+
+func (e *circuitOpenError) Error() string {
+	return "upstream circuit breaker open"
+}
+
+// doUpstream 按 route.Candidates() 的顺序依次尝试上游，build 为给定上游地址构造请求。
+// 熔断器打开的上游直接跳过；还有可用的后备上游时失败立即切换，不在当前上游上重试，
+// 最后一个可用上游按 Hub 的重试参数重试。每次尝试的结果计入对应上游的熔断器。
+// 所有上游均被熔断时返回 *circuitOpenError，不发出任何请求。
+func (h *Handler) doUpstream(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	build func(base *url.URL) (*http.Request, error),
+) (*http.Response, *url.URL, error) {
+	candidates := route.Candidates()
+	if len(candidates) == 0 {
+		return nil, nil, errors.New("hub has no upstream")
+	}
+	open := &circuitOpenError{}
+	for i, upstream := range candidates {
+		req, err := build(upstream.URL)
+		if err != nil {
+			return nil, upstream.URL, err
+		}
+		// 模块 ResolveUpstream 可能指向与上游无关的地址（如 PyPI 的 files 主机），
+		// 此时结果与该上游无关：不切换镜像，也不计入熔断器。
+		foreign := req.URL.Host != upstream.URL.Host
+		now := time.Now()
+		if !foreign {
+			allowed, change := upstream.Allow(now)
+			h.logBreaker(route, upstream, change)
+			if !allowed {
+				if until := upstream.Status(now).OpenUntil; open.until.IsZero() || until.Before(open.until) {
+					open.until = until
+				}
+				continue
+			}
+		}
+		next := nextAvailable(candidates[i+1:], now)
+		last := foreign || next == nil
+
+		retries := 0
+		if last {
+			retries = route.MaxRetries
+		}
+		resp, err := h.doRequest(c, req, route, retries)
+		if err != nil && req.Context().Err() != nil {
+			// 客户端取消不代表上游故障。
+			if !foreign {
+				upstream.Release()
+			}
+			return resp, req.URL, err
+		}
+		failed := shouldFailover(resp, err)
+		if !foreign {
+			switch {
+			case failed && throttled(resp):
+				upstream.Release()
+			case failed:
+				h.logBreaker(route, upstream, upstream.MarkFailure(time.Now()))
+			default:
+				h.logBreaker(route, upstream, upstream.MarkSuccess())
+			}
+		}
+		if !failed || last {
+			return resp, req.URL, err
+		}
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+		h.logFailover(route, req, resp, err, next.URL.String())
+	}
+	return nil, candidates[0].URL, open
+}
+
+// nextAvailable 返回剩余候选中第一个熔断器允许请求的上游。
+func nextAvailable(candidates []*server.Upstream, now time.Time) *server.Upstream {
+	for _, upstream := range candidates {
+		if upstream.Healthy(now) {
+			return upstream
+		}
+	}
+	return nil
+}
+
+func (h *Handler) logFailover(route *server.HubRoute, req *http.Request, resp *http.Response, err error, next string) {
+	fields := logging.RequestFields(
+		route.Config.Name,
+		route.Config.Domain,
+		route.Config.Type,
+		route.Config.AuthMode(),
+		route.Module.Key,
+		false,
+	)
+	fields["action"] = "proxy_failover"
+	fields["upstream"] = req.URL.String()
+	fields["next_upstream"] = next
+	fields["method"] = req.Method
+	if err != nil {
+		fields["reason"] = "upstream_error"
+		fields["error"] = err.Error()
+	} else {
+		fields["reason"] = "upstream_status"
+		fields["upstream_status"] = resp.StatusCode
+	}
+	h.logger.WithFields(fields).Warn("proxy_upstream_failover")
+}
+
+// logBreaker 在熔断器状态变化时输出 action=circuit_breaker 日志，打开时为 Warn。
+func (h *Handler) logBreaker(route *server.HubRoute, upstream *server.Upstream, change server.BreakerTransition) {
+	if !change.Changed() {
+		return
+	}
+	status := upstream.Status(time.Now())
+	fields := logging.RequestFields(
+		route.Config.Name,
+		route.Config.Domain,
+		route.Config.Type,
+		route.Config.AuthMode(),
+		route.Module.Key,
+		false,
+	)
+	fields["action"] = "circuit_breaker"
+	fields["upstream"] = upstream.URL.String()
+	fields["from"] = string(change.From)
+	fields["to"] = string(change.To)
+	fields["consecutive_failures"] = status.ConsecutiveFailures
+	fields["error_rate"] = status.ErrorRate
+	if change.To == server.BreakerOpen {
+		fields["open_until"] = status.OpenUntil.UTC().Format(time.RFC3339)
+		h.logger.WithFields(fields).Warn("upstream_circuit_breaker")
+		return
+	}
+	h.logger.WithFields(fields).Info("upstream_circuit_breaker")
+}
@@ -10,11 +10,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -34,7 +35,12 @@ type Handler struct {
 	client *http.Client
 	logger *logrus.Logger
 	store  cache.Store
-	etags  sync.Map // key: hub+path, value: etag/digest string
+	// flights 合并同一 Locator 的并发回源，避免同时拉取同一层文件时重复下载。
+	flights flightGroup
+	// refresher 执行 StaleWhileRevalidate 的后台再验证。
+	refresher refresher
+	// tokens 缓存 registry bearer token，避免每次 401 都重新向 realm 申请。
+	tokens tokenCache
 }
 
 type hookState struct {
@@ -43,6 +49,8 @@ type hookState struct {
 	hasHooks bool
 	clean    string
 	rawQuery []byte
+	// rangeOverride 非空时覆盖回源请求的 Range/If-Range，见 applyUpstreamRange。
+	rangeOverride *upstreamRange
 }
 
 // NewHandler constructs a proxy handler with shared HTTP client/logger/store.
@@ -55,12 +63,18 @@ func NewHandler(client *http.Client, logger *logrus.Logger, store cache.Store) *
 }
 
 func buildHookContext(route *server.HubRoute, c fiber.Ctx) *hooks.RequestContext {
+	ctx := newHookContext(route, c.Method())
+	ctx.Path = requestPath(c)
+	return ctx
+}
+
+func newHookContext(route *server.HubRoute, method string) *hooks.RequestContext {
 	if route == nil {
-		return &hooks.RequestContext{Method: c.Method()}
+		return &hooks.RequestContext{Method: method}
 	}
 	baseHost := ""
-	if route.UpstreamURL != nil {
-		baseHost = route.UpstreamURL.Host
+	if upstream := route.PrimaryUpstream(); upstream != nil {
+		baseHost = upstream.Host
 	}
 	return &hooks.RequestContext{
 		HubName:      route.Config.Name,
@@ -68,7 +82,7 @@ func buildHookContext(route *server.HubRoute, c fiber.Ctx) *hooks.RequestContext
 		HubType:      route.Config.Type,
 		ModuleKey:    route.Module.Key,
 		UpstreamHost: baseHost,
-		Method:       c.Method(),
+		Method:       method,
 	}
 }
 
@@ -76,8 +90,13 @@ func hasHook(def hooks.Hooks) bool {
 	return def.NormalizePath != nil ||
 		def.ResolveUpstream != nil ||
 		def.RewriteResponse != nil ||
+		def.RewriteStream != nil ||
+		def.ObserveStream != nil ||
 		def.CachePolicy != nil ||
-		def.ContentType != nil
+		def.ContentType != nil ||
+		def.ContentDigest != nil ||
+		def.Integrity != nil ||
+		def.Vary != nil
 }
 
 // Handle 执行缓存查找、条件回源和最终 streaming 逻辑，任何阶段出错都会输出结构化日志。
@@ -104,6 +123,8 @@ func (h *Handler) Handle(c fiber.Ctx, route *server.HubRoute) error {
 		clean:    cleanPath,
 		rawQuery: rawQuery,
 	}
+	// 模块声明的变体请求头（如 Accept）不同的请求各自缓存一份，策略按未带变体的路径判断。
+	locator = withVariant(locator, variantKey(variantHeaders(&hookState, locator), headerGetter(c)))
 	strategyWriter := cache.NewStrategyWriter(h.store, route.CacheStrategy)
 
 	ctx := c.Context()
@@ -113,7 +134,7 @@ func (h *Handler) Handle(c fiber.Ctx, route *server.HubRoute) error {
 
 	var cached *cache.ReadResult
 	if strategyWriter.Enabled() && policy.allowCache {
-		result, err := h.store.Get(ctx, locator)
+		result, err := h.store.Get(readContext(ctx, policy), locator)
 		switch {
 		case err == nil:
 			cached = result
@@ -126,17 +147,37 @@ func (h *Handler) Handle(c fiber.Ctx, route *server.HubRoute) error {
 		}
 	}
 
+	if cached != nil && !variantMatches(cached.Entry, headerGetter(c)) {
+		// 上游 Vary 列出的请求头与写入时不同：该条目是另一种表示，按未命中回源并覆盖。
+		cached.Reader.Close()
+		cached = nil
+	}
+
+	if cached != nil && cached.Entry.Negative() {
+		if policy.allowNegative && withinNegativeTTL(route, cached.Entry, time.Now()) {
+			return h.serveNegative(c, route, cached, requestID, started)
+		}
+		// 过期或被模块排除的负缓存标记不回放，按未命中回源。
+		cached.Reader.Close()
+		cached = nil
+	}
+
 	if cached != nil {
 		serve := true
 		if policy.requireRevalidate {
 			if strategyWriter.ShouldBypassValidation(cached.Entry) {
 				serve = true
 			} else if strategyWriter.SupportsValidation() {
-				fresh, err := h.isCacheFresh(c, route, locator, cached.Entry, &hookState)
-				if err != nil {
+				if h.scheduleRefresh(c, route, locator, policy, strategyWriter, cached.Entry, &hookState, requestID) {
+					// StaleWhileRevalidate 窗口内先返回缓存，再验证在后台完成。
+					serve = true
+				} else if fresh, err := h.isCacheFresh(c, route, locator, cached.Entry, &hookState); err != nil {
 					h.logger.WithError(err).
 						WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key}).
 						Warn("cache_revalidate_failed")
+					if circuitOpen(err) || withinStaleWindow(route, cached.Entry, time.Now()) {
+						return h.serveStale(c, route, cached, requestID, started, &hookState, err.Error())
+					}
 					serve = false
 				} else if !fresh {
 					serve = false
@@ -147,11 +188,14 @@ func (h *Handler) Handle(c fiber.Ctx, route *server.HubRoute) error {
 		}
 		if serve {
 			defer cached.Reader.Close()
-			return h.serveCache(c, route, cached, requestID, started, &hookState)
+			return h.serveCache(c, route, cached, requestID, started, &hookState, true)
 		}
 		cached.Reader.Close()
 	}
 
+	if shouldCoalesce(c, policy, strategyWriter) {
+		return h.fetchCoalesced(c, route, locator, policy, strategyWriter, requestID, started, ctx, &hookState)
+	}
 	return h.fetchAndStream(c, route, locator, policy, strategyWriter, requestID, started, ctx, &hookState)
 }
 
@@ -162,7 +206,20 @@ func (h *Handler) serveCache(
 	requestID string,
 	started time.Time,
 	hook *hookState,
+	cacheHit bool,
 ) error {
+	if storeOnly(c) {
+		// 后台任务：条目已在缓存中，无需把正文读入响应缓冲。
+		result.Reader.Close()
+		return nil
+	}
+	if result.Entry.Negative() {
+		return h.serveNegative(c, route, result, requestID, started)
+	}
+	if cacheHit {
+		h.recordAccess(c, route, result.Entry.Locator)
+	}
+
 	var readSeeker io.ReadSeeker
 	switch reader := result.Reader.(type) {
 	case io.ReadSeeker:
@@ -174,7 +231,10 @@ func (h *Handler) serveCache(
 
 	method := c.Method()
 
-	contentType := resolveContentType(route, result.Entry.Locator, hook)
+	contentType := result.Entry.Response.ContentType
+	if contentType == "" {
+		contentType = resolveContentType(route, result.Entry.Locator, hook)
+	}
 	if contentType == "" && shouldSniffDockerManifest(result.Entry.Locator) {
 		if sniffed := sniffDockerManifestContentType(readSeeker); sniffed != "" {
 			contentType = sniffed
@@ -193,20 +253,34 @@ func (h *Handler) serveCache(
 		c.Response().Header.Del("Content-Length")
 	}
 
-	c.Set("X-Any-Hub-Upstream", route.UpstreamURL.String())
-	c.Set("X-Any-Hub-Cache-Hit", "true")
+	upstream := servedUpstream(route, result.Entry)
+	applyCachedHeaders(c, result.Entry.Response)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set("X-Any-Hub-Upstream", upstream)
+	c.Set("X-Any-Hub-Cache-Hit", strconv.FormatBool(cacheHit))
 	if requestID != "" {
 		c.Set("X-Request-ID", requestID)
 	}
 
+	etag, lastModified := entryValidators(result.Entry)
+	setValidators(c, etag, lastModified)
+
+	// 客户端的校验器与缓存条目一致时直接 304，不发送正文，也不触发 HEAD 再验证。
+	if notModified(c, etag, lastModified) {
+		result.Reader.Close()
+		writeNotModified(c)
+		h.logResult(c, route, upstream, requestID, fiber.StatusNotModified, cacheHit, started, nil)
+		return nil
+	}
+
 	status := fiber.StatusOK
 	c.Status(status)
 
 	if method == http.MethodHead {
-		if route != nil && route.UpstreamURL != nil {
+		if route != nil && route.PrimaryUpstream() != nil {
 			shouldRevalidate := true
 			if hook != nil && hook.hasHooks && hook.def.CachePolicy != nil {
-				policy := hook.def.CachePolicy(hook.ctx, result.Entry.Locator.Path, hooks.CachePolicy{
+				policy := hook.def.CachePolicy(hook.ctx, stripVariantMarker(result.Entry.Locator.Path), hooks.CachePolicy{
 					AllowCache:        true,
 					AllowStore:        true,
 					RequireRevalidate: true,
@@ -219,25 +293,54 @@ func (h *Handler) serveCache(
 			}
 
 			if shouldRevalidate {
-				if resp, err := h.revalidateRequest(c, route, effectiveRevalidateURL(route, c, result.Entry, hook), result.Entry.Locator, ""); err == nil {
+				if resp, err := h.revalidateRequest(c, route, result.Entry, hook, h.cachedAuth(route, hook)); err == nil {
 					resp.Body.Close()
 				}
 			}
 		}
 		result.Reader.Close()
-		h.logResult(route, route.UpstreamURL.String(), requestID, status, true, started, nil)
+		h.logResult(c, route, upstream, requestID, status, cacheHit, started, nil)
 		return nil
 	}
 
+	if readSeeker != nil {
+		rangeStatus, handled, err := serveRanges(c, readSeeker, result.Entry, contentType)
+		if handled {
+			result.Reader.Close()
+			h.logResult(c, route, upstream, requestID, rangeStatus, cacheHit, started, err)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("read cache failed: %v", err))
+			}
+			return nil
+		}
+	}
+
 	_, err := io.Copy(c.Response().BodyWriter(), result.Reader)
 	result.Reader.Close()
-	h.logResult(route, route.UpstreamURL.String(), requestID, status, true, started, err)
+	h.logResult(c, route, upstream, requestID, status, cacheHit, started, err)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("read cache failed: %v", err))
 	}
 	return nil
 }
 
+// recordAccess 在缓存命中时刷新条目访问时间，供磁盘淘汰器维护 LRU 顺序。
+func (h *Handler) recordAccess(c fiber.Ctx, route *server.HubRoute, locator cache.Locator) {
+	recorder, ok := h.store.(cache.AccessRecorder)
+	if !ok {
+		return
+	}
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := recorder.Touch(ctx, locator); err != nil && !errors.Is(err, cache.ErrNotFound) {
+		h.logger.WithError(err).
+			WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key}).
+			Warn("cache_touch_failed")
+	}
+}
+
 func (h *Handler) fetchAndStream(
 	c fiber.Ctx,
 	route *server.HubRoute,
@@ -249,16 +352,35 @@ func (h *Handler) fetchAndStream(
 	ctx context.Context,
 	hook *hookState,
 ) error {
+	storable := policy.allowStore && writer.Enabled() && c.Method() == http.MethodGet
+	var partial *cache.PartialEntry
+	if storable && hook != nil {
+		// 可缓存的回源总是拉取完整正文；存在中断保留的前缀时通过 Range 续传。
+		hook.rangeOverride = &upstreamRange{}
+		if partial = h.lookupPartial(ctx, route, locator); partial != nil {
+			hook.rangeOverride.from = partial.SizeBytes
+			hook.rangeOverride.ifRange = partialValidator(partial.Response)
+		}
+	}
+	// 客户端自带 Range 的未命中：向上游转发所需范围直接回写，完整正文由后台任务写入缓存，
+	// 客户端不必等待整个正文下载完成。续传前缀与需要改写的元数据仍先完整写入缓存再切片。
+	if storable && partial == nil && c.Get(fiber.HeaderRange) != "" && !shouldRewrite(hook, locator) && !storeFirst(c) &&
+		h.scheduleFill(c, route, locator, policy, writer, hook, requestID) {
+		storable = false
+		hook.rangeOverride = nil
+	}
+
+	// 上游不可用时可回放 StaleIfError 窗口内的缓存副本（例如再验证失败后落到这里），熔断期间不限窗口。
+	allowStale := policy.allowCache && writer.Enabled()
+
 	resp, upstreamURL, err := h.executeRequest(c, route, hook)
 	if err != nil {
-		h.logResult(route, upstreamURL.String(), requestID, 0, false, started, err)
-		return h.writeError(c, fiber.StatusBadGateway, "upstream_failed")
+		return h.upstreamFailed(c, route, locator, allowStale, requestID, started, ctx, hook, upstreamURL.String(), err)
 	}
 
 	resp, upstreamURL, err = h.retryOnAuthFailure(c, route, requestID, started, resp, upstreamURL, hook)
 	if err != nil {
-		h.logResult(route, upstreamURL.String(), requestID, 0, false, started, err)
-		return h.writeError(c, fiber.StatusBadGateway, "upstream_failed")
+		return h.upstreamFailed(c, route, locator, allowStale, requestID, started, ctx, hook, upstreamURL.String(), err)
 	}
 	effectiveUpstreamPath := ""
 	originalStatus := resp.StatusCode
@@ -268,63 +390,99 @@ func (h *Handler) fetchAndStream(
 	}
 	resp, upstreamURL, effectiveUpstreamPath, err = h.retryRegistryK8sManifestFallback(c, route, requestID, resp, upstreamURL, hook, originalStatus, originalPath)
 	if err != nil {
-		h.logResult(route, upstreamURL.String(), requestID, 0, false, started, err)
-		return h.writeError(c, fiber.StatusBadGateway, "upstream_failed")
+		return h.upstreamFailed(c, route, locator, allowStale, requestID, started, ctx, hook, upstreamURL.String(), err)
 	}
-	if hook != nil && hook.hasHooks && hook.def.RewriteResponse != nil {
-		if rewritten, rewriteErr := applyHookRewrite(hook, resp, requestPath(c)); rewriteErr == nil {
-			resp = rewritten
+	if allowStale && resp.StatusCode >= http.StatusInternalServerError {
+		if stale := h.lookupStale(ctx, route, locator, false); stale != nil {
+			resp.Body.Close()
+			return h.serveStale(c, route, stale, requestID, started, hook, fmt.Sprintf("upstream status %d", resp.StatusCode))
+		}
+	}
+	var resumeFrom int64
+	if partial != nil {
+		if start, ok := resumedAt(resp); ok && start == partial.SizeBytes {
+			// 上游确认续传：剩余正文接在前缀之后写入，状态按完整正文处理。
+			resumeFrom = start
+			resp.StatusCode = http.StatusOK
+			resp.Header.Del("Content-Range")
 		} else {
+			h.discardPartial(ctx, locator)
+			if resp.StatusCode != http.StatusOK {
+				// 前缀已失效（例如 416）：丢弃后重新完整回源一次。
+				resp.Body.Close()
+				return h.fetchAndStream(c, route, locator, policy, writer, requestID, started, ctx, hook)
+			}
+		}
+	}
+	if resumeFrom == 0 && shouldRewrite(hook, locator) {
+		rewritten, rewriteErr := applyHookRewrite(hook, resp, requestPath(c), streamRewrite(hook, locator))
+		switch {
+		case rewritten == nil:
+			// 正文读取中断：不能把截断后的空正文当作成功响应返回。
+			return h.upstreamFailed(c, route, locator, allowStale, requestID, started, ctx, hook, upstreamURL.String(), rewriteErr)
+		case rewriteErr == nil:
+			resp = rewritten
+		default:
 			h.logger.WithError(rewriteErr).WithFields(logrus.Fields{
 				"action": "hook_rewrite",
 				"hub":    route.Config.Name,
 			}).Warn("hook_rewrite_failed")
 		}
 	}
+	if resumeFrom == 0 {
+		resp = observeStream(hook, resp, requestPath(c))
+	}
 	defer resp.Body.Close()
 
-	shouldStore := policy.allowStore && writer.Enabled() && isCacheableStatus(resp.StatusCode) &&
-		c.Method() == http.MethodGet
-	return h.consumeUpstream(c, route, locator, resp, shouldStore, writer, requestID, started, ctx, effectiveUpstreamPath)
-}
-
-func applyHookRewrite(hook *hookState, resp *http.Response, path string) (*http.Response, error) {
-	if hook == nil || hook.def.RewriteResponse == nil {
-		return resp, nil
+	if storable && policy.allowNegative && route.NegativeCacheTTL > 0 && resumeFrom == 0 && isNegativeStatus(resp.StatusCode) && !varyAll(resp.Header) {
+		return h.storeNegative(c, route, locator, resp, writer, requestID, started, ctx)
 	}
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		resp.Body = io.NopCloser(bytes.NewReader(nil))
-		return resp, err
-	}
-	headers := make(map[string]string, len(resp.Header))
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-	status, newHeaders, newBody, rewriteErr := hook.def.RewriteResponse(hook.ctx, resp.StatusCode, headers, body, path)
-	if rewriteErr != nil {
-		resp.Body = io.NopCloser(bytes.NewReader(body))
-		resp.ContentLength = int64(len(body))
-		return resp, rewriteErr
+	shouldStore := storable && isCacheableStatus(resp.StatusCode) && !varyAll(resp.Header)
+	opts := cache.PutOptions{
+		EffectiveUpstreamPath: effectiveUpstreamPath,
+		Digest:                resolveContentDigest(locator, hook),
+		Verifier:              resolveVerifier(locator, hook),
+		ResumeFrom:            resumeFrom,
 	}
-	if newHeaders == nil {
-		newHeaders = headers
+	if shouldStore && (resumeFrom > 0 || c.Get(fiber.HeaderRange) != "") {
+		// 续传，或后台队列已满时的 Range 未命中。
+		opts.ModTime = extractModTime(resp.Header)
+		opts.Response = storedResponseMetadata(c, resp)
+		opts.KeepPartial = partialValidator(opts.Response) != "" && !isRewritten(resp)
+		return h.storeThenServe(c, route, locator, resp, writer, requestID, started, ctx, resp.Request.URL.String(), opts, hook)
 	}
-	if newBody == nil {
-		newBody = body
+	return h.consumeUpstream(c, route, locator, resp, shouldStore, writer, requestID, started, ctx, opts)
+}
+
+// upstreamFailed 处理无法得到上游响应的情况：窗口内存在缓存副本时回放，否则返回 502 upstream_failed。
+// 所有上游均被熔断时回放任何已有的缓存副本（不受 StaleIfError 限制），没有副本才以 503 upstream_circuit_open
+// 快速失败，并用 Retry-After 提示冷却剩余时间。
+func (h *Handler) upstreamFailed(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	allowStale bool,
+	requestID string,
+	started time.Time,
+	ctx context.Context,
+	hook *hookState,
+	upstreamURL string,
+	err error,
+) error {
+	if allowStale {
+		if stale := h.lookupStale(ctx, route, locator, circuitOpen(err)); stale != nil {
+			return h.serveStale(c, route, stale, requestID, started, hook, err.Error())
+		}
 	}
-	cloned := *resp
-	cloned.StatusCode = status
-	cloned.Header = make(http.Header, len(newHeaders))
-	for key, value := range newHeaders {
-		cloned.Header.Set(key, value)
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		h.logResult(c, route, upstreamURL, requestID, 0, false, started, err)
+		retryAfter := max(int(math.Ceil(time.Until(open.until).Seconds())), 1)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return h.writeError(c, fiber.StatusServiceUnavailable, "upstream_circuit_open")
 	}
-	cloned.Body = io.NopCloser(bytes.NewReader(newBody))
-	cloned.ContentLength = int64(len(newBody))
-	return &cloned, nil
+	h.logResult(c, route, upstreamURL, requestID, 0, false, started, err)
+	return h.writeError(c, fiber.StatusBadGateway, "upstream_failed")
 }
 
 func (h *Handler) consumeUpstream(
@@ -337,14 +495,14 @@ func (h *Handler) consumeUpstream(
 	requestID string,
 	started time.Time,
 	ctx context.Context,
-	effectiveUpstreamPath string,
+	opts cache.PutOptions,
 ) error {
 	upstreamURL := resp.Request.URL.String()
 	method := c.Method()
 	authFailure := isAuthFailure(resp.StatusCode) && route.Config.HasCredentials()
 
 	if shouldStore {
-		return h.cacheAndStream(c, route, locator, resp, writer, requestID, started, ctx, upstreamURL, effectiveUpstreamPath)
+		return h.cacheAndStream(c, route, locator, resp, writer, requestID, started, ctx, upstreamURL, opts)
 	}
 
 	copyResponseHeaders(c, resp.Header)
@@ -359,13 +517,13 @@ func (h *Handler) consumeUpstream(
 		h.logAuthFailure(route, upstreamURL, requestID, resp.StatusCode)
 	}
 
-	if method == http.MethodHead {
-		h.logResult(route, upstreamURL, requestID, resp.StatusCode, false, started, nil)
+	if method == http.MethodHead || storeOnly(c) {
+		h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, nil)
 		return nil
 	}
 
 	_, err := io.Copy(c.Response().BodyWriter(), resp.Body)
-	h.logResult(route, upstreamURL, requestID, resp.StatusCode, false, started, err)
+	h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, err)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("proxy stream failed: %v", err))
 	}
@@ -382,7 +540,7 @@ func (h *Handler) cacheAndStream(
 	started time.Time,
 	ctx context.Context,
 	upstreamURL string,
-	effectiveUpstreamPath string,
+	opts cache.PutOptions,
 ) error {
 	copyResponseHeaders(c, resp.Header)
 	c.Set("X-Any-Hub-Upstream", upstreamURL)
@@ -392,17 +550,30 @@ func (h *Handler) cacheAndStream(
 	}
 	c.Status(resp.StatusCode)
 
-	// 使用 TeeReader 边向客户端回写边落盘，避免大文件在内存中完整缓冲。
-	reader := io.TeeReader(resp.Body, c.Response().BodyWriter())
-
-	opts := cache.PutOptions{ModTime: extractModTime(resp.Header), EffectiveUpstreamPath: effectiveUpstreamPath}
-	entry, err := writer.Put(ctx, locator, reader, opts)
-	h.logResult(route, upstreamURL, requestID, resp.StatusCode, false, started, err)
+	// 使用 TeeReader 边向客户端回写边落盘；后台任务没有客户端，正文只写入缓存。
+	var reader io.Reader = resp.Body
+	if !storeOnly(c) {
+		reader = io.TeeReader(resp.Body, c.Response().BodyWriter())
+	}
+
+	opts.ModTime = extractModTime(resp.Header)
+	opts.Response = storedResponseMetadata(c, resp)
+	opts.KeepPartial = partialValidator(opts.Response) != "" && !isRewritten(resp)
+	_, err := writer.Put(ctx, locator, reader, opts)
+	h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, err)
+	var integrityErr *cache.IntegrityError
+	if errors.As(err, &integrityErr) {
+		// 正文已写入响应缓冲但尚未发送，丢弃后改为 502，避免客户端拿到被篡改或截断的内容。
+		h.logIntegrityMismatch(route, locator, upstreamURL, requestID, integrityErr)
+		c.Response().Reset()
+		if requestID != "" {
+			c.Set("X-Request-ID", requestID)
+		}
+		return h.writeError(c, fiber.StatusBadGateway, "integrity_mismatch")
+	}
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("cache_write_failed: %v", err))
 	}
-	h.rememberETag(route, locator, resp)
-	_ = entry
 	return nil
 }
 
@@ -424,23 +595,19 @@ func (h *Handler) retryOnAuthFailure(
 	resp.Body.Close()
 
 	if ok {
-		ctx := c.Context()
-		if ctx == nil {
-			ctx = context.Background()
-		}
-		token, err := h.fetchBearerToken(ctx, challenge, route)
+		authHeader, err := h.bearerAuth(c, route, challenge, resp)
 		if err != nil {
 			return nil, upstreamURL, err
 		}
-		authHeader := "Bearer " + token
-		retryResp, retryURL, err := h.executeRequestWithAuth(c, route, hook, authHeader)
+		retryResp, retryURL, err := h.executeRequestWithAuth(c, route, hook, fixedAuth(authHeader))
 		if err != nil {
 			return nil, upstreamURL, err
 		}
 		return retryResp, retryURL, nil
 	}
 
-	retryResp, retryURL, err := h.executeRequest(c, route, hook)
+	// 没有 bearer 质询时回退到 Basic 凭证，不再携带可能已失效的缓存 token。
+	retryResp, retryURL, err := h.executeRequestWithAuth(c, route, hook, nil)
 	if err != nil {
 		return nil, upstreamURL, err
 	}
@@ -448,24 +615,25 @@ func (h *Handler) retryOnAuthFailure(
 }
 
 func (h *Handler) executeRequest(c fiber.Ctx, route *server.HubRoute, hook *hookState) (*http.Response, *url.URL, error) {
-	return h.executeRequestWithAuth(c, route, hook, "")
+	return h.executeRequestWithAuth(c, route, hook, h.cachedAuth(route, hook))
 }
 
 func (h *Handler) executeRequestWithAuth(
 	c fiber.Ctx,
 	route *server.HubRoute,
 	hook *hookState,
-	authHeader string,
+	auth upstreamAuth,
 ) (*http.Response, *url.URL, error) {
-	upstreamURL := resolveUpstreamURL(route, route.UpstreamURL, c, hook)
-	body := bytesReader(c.Body())
-	req, err := h.buildUpstreamRequest(c, upstreamURL, route, c.Method(), body, authHeader)
-	if err != nil {
-		return nil, upstreamURL, err
-	}
-
-	resp, err := h.doRequest(req, route)
-	return resp, upstreamURL, err
+	return h.doUpstream(c, route, func(base *url.URL) (*http.Request, error) {
+		upstreamURL := resolveUpstreamURL(route, base, c, hook)
+		body := bytesReader(c.Body())
+		req, err := h.buildUpstreamRequest(c, upstreamURL, route, c.Method(), body, auth.header(upstreamURL.Host))
+		if err != nil {
+			return nil, err
+		}
+		applyUpstreamRange(req, hook)
+		return req, nil
+	})
 }
 
 func (h *Handler) buildUpstreamRequest(
@@ -514,18 +682,22 @@ func (h *Handler) buildUpstreamRequest(
 	return req, nil
 }
 
-func (h *Handler) doRequest(req *http.Request, route *server.HubRoute) (*http.Response, error) {
-	if route.ProxyURL == nil {
-		return h.client.Do(req)
-	}
-	transport := http.Transport{}
-	if base, ok := h.client.Transport.(*http.Transport); ok && base != nil {
-		transport = *base.Clone()
-	}
-	transport.Proxy = http.ProxyURL(route.ProxyURL)
-	client := *h.client
-	client.Transport = &transport
-	return client.Do(req)
+// doRequest 经 Hub 的代理发送回源请求，幂等请求最多重试 maxRetries 次，重试次数计入本次请求。
+func (h *Handler) doRequest(c fiber.Ctx, req *http.Request, route *server.HubRoute, maxRetries int) (*http.Response, error) {
+	client := h.client
+	if route.ProxyURL != nil {
+		transport := http.Transport{}
+		if base, ok := h.client.Transport.(*http.Transport); ok && base != nil {
+			transport = *base.Clone()
+		}
+		transport.Proxy = http.ProxyURL(route.ProxyURL)
+		proxied := *h.client
+		proxied.Transport = &transport
+		client = &proxied
+	}
+	resp, retries, err := h.sendWithRetry(req, route, maxRetries, client.Do)
+	addUpstreamRetries(c, retries)
+	return resp, err
 }
 
 func (h *Handler) writeError(c fiber.Ctx, status int, code string) error {
@@ -533,6 +705,7 @@ func (h *Handler) writeError(c fiber.Ctx, status int, code string) error {
 }
 
 func (h *Handler) logResult(
+	c fiber.Ctx,
 	route *server.HubRoute,
 	upstream string,
 	requestID string,
@@ -553,6 +726,7 @@ func (h *Handler) logResult(
 	fields["upstream"] = upstream
 	fields["upstream_status"] = status
 	fields["elapsed_ms"] = time.Since(started).Milliseconds()
+	fields["retries"] = upstreamRetries(c)
 	if requestID != "" {
 		fields["request_id"] = requestID
 	}
@@ -597,6 +771,30 @@ func resolveContentType(route *server.HubRoute, locator cache.Locator, hook *hoo
 	return inferCachedContentType(route, locator)
 }
 
+// resolveContentDigest 询问模块 hook 当前条目的内容摘要，供缓存层跨 Hub 去重。
+func resolveContentDigest(locator cache.Locator, hook *hookState) string {
+	if hook == nil || !hook.hasHooks || hook.def.ContentDigest == nil {
+		return ""
+	}
+	return hook.def.ContentDigest(hook.ctx, stripQueryMarker(locator.Path))
+}
+
+// resolveVerifier 根据模块 hook 声明的期望摘要构建校验器；算法不受支持时不校验。
+func resolveVerifier(locator cache.Locator, hook *hookState) cache.Verifier {
+	if hook == nil || !hook.hasHooks || hook.def.Integrity == nil {
+		return nil
+	}
+	spec, ok := hook.def.Integrity(hook.ctx, stripQueryMarker(locator.Path))
+	if !ok {
+		return nil
+	}
+	verifier, err := cache.NewVerifier(spec.Algorithm, spec.Expected)
+	if err != nil {
+		return nil
+	}
+	return verifier
+}
+
 func buildLocator(route *server.HubRoute, c fiber.Ctx, clean string, rawQuery []byte) cache.Locator {
 	query := rawQuery
 	if len(query) > 0 {
@@ -625,10 +823,19 @@ func buildLocator(route *server.HubRoute, c fiber.Ctx, clean string, rawQuery []
 	return loc
 }
 
+// stripQueryMarker 去掉定位路径中的查询摘要与变体键，得到模块 hook 看到的路径。
 func stripQueryMarker(p string) string {
 	if idx := strings.Index(p, "/__qs/"); idx >= 0 {
 		return p[:idx]
 	}
+	return stripVariantMarker(p)
+}
+
+// stripVariantMarker 仅去掉变体键，保留查询摘要。
+func stripVariantMarker(p string) string {
+	if idx := strings.Index(p, variantMarker); idx >= 0 {
+		return p[:idx]
+	}
 	return p
 }
 
@@ -702,11 +909,16 @@ func resolveUpstreamURL(route *server.HubRoute, base *url.URL, c fiber.Ctx, hook
 		if hook.rawQuery != nil {
 			rawQuery = hook.rawQuery
 		}
-		if hook.hasHooks && hook.def.ResolveUpstream != nil {
-			if u := hook.def.ResolveUpstream(hook.ctx, base.String(), clean, rawQuery); u != "" {
-				if parsed, err := url.Parse(u); err == nil {
-					return parsed
-				}
+	}
+	return resolveUpstreamPath(base, clean, rawQuery, hook)
+}
+
+// resolveUpstreamPath 优先采用模块 ResolveUpstream 给出的地址，否则把 clean 拼到 base 上。
+func resolveUpstreamPath(base *url.URL, clean string, rawQuery []byte, hook *hookState) *url.URL {
+	if hook != nil && hook.hasHooks && hook.def.ResolveUpstream != nil {
+		if u := hook.def.ResolveUpstream(hook.ctx, base.String(), clean, rawQuery); u != "" {
+			if parsed, err := url.Parse(u); err == nil {
+				return parsed
 			}
 		}
 	}
@@ -743,10 +955,19 @@ func routePort(route *server.HubRoute) string {
 	return fmt.Sprintf("%d", route.ListenPort)
 }
 
+// readContext 让需要再验证的元数据首次读取即进入内存层，其余条目按读取次数接纳。
+func readContext(ctx context.Context, policy cachePolicy) context.Context {
+	if policy.requireRevalidate {
+		return cache.WithRevalidation(ctx)
+	}
+	return ctx
+}
+
 type cachePolicy struct {
 	allowCache        bool
 	allowStore        bool
 	requireRevalidate bool
+	allowNegative     bool
 }
 
 func determineCachePolicyWithHook(route *server.HubRoute, locator cache.Locator, method string, def hooks.Hooks, enabled bool, ctx *hooks.RequestContext) cachePolicy {
@@ -754,14 +975,16 @@ func determineCachePolicyWithHook(route *server.HubRoute, locator cache.Locator,
 	if !enabled || def.CachePolicy == nil {
 		return base
 	}
-	updated := def.CachePolicy(ctx, locator.Path, hooks.CachePolicy{
-		AllowCache:        base.allowCache,
-		AllowStore:        base.allowStore,
-		RequireRevalidate: base.requireRevalidate,
+	updated := def.CachePolicy(ctx, stripVariantMarker(locator.Path), hooks.CachePolicy{
+		AllowCache:         base.allowCache,
+		AllowStore:         base.allowStore,
+		RequireRevalidate:  base.requireRevalidate,
+		AllowNegativeCache: base.allowNegative,
 	})
 	base.allowCache = updated.AllowCache
 	base.allowStore = updated.AllowStore
 	base.requireRevalidate = updated.RequireRevalidate
+	base.allowNegative = updated.AllowNegativeCache
 	return base
 }
 
@@ -769,7 +992,7 @@ func determineCachePolicy(route *server.HubRoute, locator cache.Locator, method
 	if method != http.MethodGet && method != http.MethodHead {
 		return cachePolicy{}
 	}
-	return cachePolicy{allowCache: true, allowStore: true}
+	return cachePolicy{allowCache: true, allowStore: true, allowNegative: true}
 }
 
 func isCacheableStatus(status int) bool {
@@ -788,8 +1011,7 @@ func (h *Handler) isCacheFresh(
 		ctx = context.Background()
 	}
 
-	upstreamURL := effectiveRevalidateURL(route, c, entry, hook)
-	resp, err := h.revalidateRequest(c, route, upstreamURL, locator, "")
+	resp, err := h.revalidateRequest(c, route, entry, hook, h.cachedAuth(route, hook))
 	if err != nil {
 		return false, err
 	}
@@ -800,14 +1022,13 @@ func (h *Handler) isCacheFresh(
 
 		authHeader := ""
 		if ok {
-			token, err := h.fetchBearerToken(ctx, challenge, route)
+			authHeader, err = h.bearerAuth(c, route, challenge, resp)
 			if err != nil {
 				return false, err
 			}
-			authHeader = "Bearer " + token
 		}
 
-		resp, err = h.revalidateRequest(c, route, upstreamURL, locator, authHeader)
+		resp, err = h.revalidateRequest(c, route, entry, hook, fixedAuth(authHeader))
 		if err != nil {
 			return false, err
 		}
@@ -817,14 +1038,15 @@ func (h *Handler) isCacheFresh(
 
 	switch resp.StatusCode {
 	case http.StatusNotModified:
+		h.refreshValidators(ctx, route, entry, resp.Header)
 		return true, nil
 	case http.StatusOK:
 		if resp.Header.Get("Etag") == "" && resp.Header.Get("Docker-Content-Digest") == "" && resp.Header.Get("Last-Modified") == "" {
 			return false, nil
 		}
-		h.rememberETag(route, locator, resp)
 		remote := extractModTime(resp.Header)
 		if !remote.After(entry.ModTime) {
+			h.refreshValidators(ctx, route, entry, resp.Header)
 			return true, nil
 		}
 		return false, nil
@@ -832,18 +1054,20 @@ func (h *Handler) isCacheFresh(
 		if h.store != nil {
 			_ = h.store.Remove(ctx, locator)
 		}
-		h.forgetETag(route, locator)
 		return false, nil
 	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return false, fmt.Errorf("revalidate upstream status %d", resp.StatusCode)
+		}
 		return false, nil
 	}
 }
 
-func effectiveRevalidateURL(route *server.HubRoute, c fiber.Ctx, entry cache.Entry, hook *hookState) *url.URL {
-	if route == nil || route.UpstreamURL == nil || entry.EffectiveUpstreamPath == "" {
-		return resolveUpstreamURL(route, route.UpstreamURL, c, hook)
+func effectiveRevalidateURL(route *server.HubRoute, base *url.URL, c fiber.Ctx, entry cache.Entry, hook *hookState) *url.URL {
+	if base == nil || entry.EffectiveUpstreamPath == "" {
+		return resolveUpstreamURL(route, base, c, hook)
 	}
-	clone := *route.UpstreamURL
+	clone := *base
 	clone.Path = entry.EffectiveUpstreamPath
 	clone.RawPath = entry.EffectiveUpstreamPath
 	return &clone
@@ -897,8 +1121,8 @@ func (h *Handler) logRegistryK8sFallback(route *server.HubRoute, requestID strin
 		false,
 	)
 	fields["action"] = "proxy_fallback"
-	if route.UpstreamURL != nil {
-		fields["upstream_host"] = route.UpstreamURL.Hostname()
+	if upstream := route.PrimaryUpstream(); upstream != nil {
+		fields["upstream_host"] = upstream.Hostname()
 	}
 	fields["original_path"] = originalPath
 	fields["fallback_path"] = fallbackPath
@@ -910,21 +1134,27 @@ func (h *Handler) logRegistryK8sFallback(route *server.HubRoute, requestID strin
 	h.logger.WithFields(fields).Info("proxy_registry_k8s_fallback")
 }
 
+// revalidateRequest 以 HEAD 携带缓存的校验值向上游确认条目是否变化，多个上游时同样按顺序切换。
 func (h *Handler) revalidateRequest(
 	c fiber.Ctx,
 	route *server.HubRoute,
-	upstreamURL *url.URL,
-	locator cache.Locator,
-	overrideAuth string,
+	entry cache.Entry,
+	hook *hookState,
+	auth upstreamAuth,
 ) (*http.Response, error) {
-	req, err := h.buildUpstreamRequest(c, upstreamURL, route, http.MethodHead, http.NoBody, overrideAuth)
-	if err != nil {
-		return nil, err
-	}
-	if etag := h.cachedETag(route, locator); etag != "" {
-		req.Header.Set("If-None-Match", etag)
-	}
-	return h.doRequest(req, route)
+	validator := cachedValidator(entry)
+	resp, _, err := h.doUpstream(c, route, func(base *url.URL) (*http.Request, error) {
+		upstreamURL := effectiveRevalidateURL(route, base, c, entry, hook)
+		req, err := h.buildUpstreamRequest(c, upstreamURL, route, http.MethodHead, http.NoBody, auth.header(upstreamURL.Host))
+		if err != nil {
+			return nil, err
+		}
+		if validator != "" {
+			req.Header.Set("If-None-Match", validator)
+		}
+		return req, nil
+	})
+	return resp, err
 }
 
 func extractModTime(header http.Header) time.Time {
@@ -984,44 +1214,53 @@ func parseAuthParams(input string) map[string]string {
 	return params
 }
 
+// fetchBearerToken 向质询给出的 realm 申请 token，返回 token 与按 expires_in/issued_at 计算的本地过期时间。
 func (h *Handler) fetchBearerToken(
-	ctx context.Context,
+	c fiber.Ctx,
 	challenge bearerChallenge,
 	route *server.HubRoute,
-) (string, error) {
+) (string, time.Time, error) {
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if challenge.Realm == "" {
-		return "", errors.New("bearer realm missing")
+		return "", time.Time{}, errors.New("bearer realm missing")
 	}
 	tokenURL, err := url.Parse(challenge.Realm)
 	if err != nil {
-		return "", fmt.Errorf("invalid bearer realm: %w", err)
+		return "", time.Time{}, fmt.Errorf("invalid bearer realm: %w", err)
 	}
 	query := tokenURL.Query()
 	if challenge.Service != "" {
 		query.Set("service", challenge.Service)
 	}
-	if challenge.Scope != "" {
-		query.Set("scope", challenge.Scope)
+	// 跨仓库请求的质询会列出多个 scope，按规范逐个作为 scope 参数申请。
+	for _, scope := range strings.Fields(challenge.Scope) {
+		query.Add("scope", scope)
 	}
 	tokenURL.RawQuery = query.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	if route.Config.Username != "" && route.Config.Password != "" {
 		req.SetBasicAuth(route.Config.Username, route.Config.Password)
 	}
 
-	resp, err := h.client.Do(req)
+	tokenRequests.Inc(route.Config.Name)
+	resp, retries, err := h.sendWithRetry(req, route, route.MaxRetries, h.client.Do)
+	addUpstreamRetries(c, retries)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	defer resp.Body.Close()
+	received := time.Now()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return "", fmt.Errorf(
+		return "", time.Time{}, fmt.Errorf(
 			"token request failed: status=%d body=%s",
 			resp.StatusCode,
 			strings.TrimSpace(string(body)),
@@ -1031,9 +1270,11 @@ func (h *Handler) fetchBearerToken(
 	var tokenResp struct {
 		Token       string `json:"token"`
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		IssuedAt    string `json:"issued_at"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return "", fmt.Errorf("decode token response: %w", err)
+		return "", time.Time{}, fmt.Errorf("decode token response: %w", err)
 	}
 
 	token := tokenResp.Token
@@ -1041,9 +1282,9 @@ func (h *Handler) fetchBearerToken(
 		token = tokenResp.AccessToken
 	}
 	if token == "" {
-		return "", errors.New("token response missing token value")
+		return "", time.Time{}, errors.New("token response missing token value")
 	}
-	return token, nil
+	return token, tokenExpiry(received, tokenResp.IssuedAt, tokenResp.ExpiresIn), nil
 }
 
 func buildCredentialHeader(username, password string) string {
@@ -1100,40 +1341,141 @@ func (h *Handler) logAuthFailure(route *server.HubRoute, upstream string, reques
 	h.logger.WithFields(fields).Error("proxy_auth_failed")
 }
 
-func (h *Handler) rememberETag(route *server.HubRoute, locator cache.Locator, resp *http.Response) {
-	if resp == nil {
-		return
-	}
-	etag := resp.Header.Get("Docker-Content-Digest")
-	if etag == "" {
-		etag = resp.Header.Get("Etag")
+func (h *Handler) logIntegrityMismatch(route *server.HubRoute, locator cache.Locator, upstream string, requestID string, mismatch *cache.IntegrityError) {
+	fields := logging.RequestFields(
+		route.Config.Name,
+		route.Config.Domain,
+		route.Config.Type,
+		route.Config.AuthMode(),
+		route.Module.Key,
+		false,
+	)
+	fields["action"] = "proxy"
+	fields["upstream"] = upstream
+	fields["path"] = locator.Path
+	fields["error"] = "integrity_mismatch"
+	fields["algorithm"] = mismatch.Algorithm
+	fields["expected"] = mismatch.Expected
+	fields["actual"] = mismatch.Actual
+	if requestID != "" {
+		fields["request_id"] = requestID
 	}
-	etag = normalizeETag(etag)
-	if etag == "" {
-		return
+	h.logger.WithFields(fields).Error("integrity_mismatch")
+}
+
+// replayedHeaders 为随缓存持久化、命中时原样回放的上游头部白名单；
+// 校验器与 Content-Type/Content-Encoding 在 ResponseMetadata 中单独记录。
+var replayedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Language",
+	"Docker-Distribution-Api-Version",
+	"Link",
+	"Vary",
+}
+
+// storedResponseMetadata 在 responseMetadata 基础上记录本次请求在上游 Vary 头部上的取值，
+// 以及实际提供响应的上游（多镜像时可能不是主上游）。
+func storedResponseMetadata(c fiber.Ctx, resp *http.Response) cache.ResponseMetadata {
+	meta := responseMetadata(resp.Header)
+	meta.Variant = requestVariant(resp.Header, headerGetter(c))
+	if resp.Request != nil && resp.Request.URL != nil {
+		served := *resp.Request.URL
+		// 查询参数可能携带签名等一次性凭据，不写入元数据。
+		served.User, served.RawQuery, served.ForceQuery, served.Fragment = nil, "", false, ""
+		meta.Upstream = served.String()
+	}
+	return meta
+}
+
+// servedUpstream 返回缓存条目写入时的上游，旧条目未记录时回退到主上游。
+func servedUpstream(route *server.HubRoute, entry cache.Entry) string {
+	if entry.Response.Upstream != "" {
+		return entry.Response.Upstream
 	}
-	h.etags.Store(h.locatorKey(route, locator), etag)
+	return route.PrimaryUpstream().String()
 }
 
-func (h *Handler) cachedETag(route *server.HubRoute, locator cache.Locator) string {
-	if value, ok := h.etags.Load(h.locatorKey(route, locator)); ok {
-		if etag, ok := value.(string); ok {
-			return etag
+// responseMetadata 从上游响应头中提取需要写入 .meta 的校验器与回放头部。
+func responseMetadata(header http.Header) cache.ResponseMetadata {
+	meta := cache.ResponseMetadata{
+		ETag:                header.Get("Etag"),
+		DockerContentDigest: header.Get("Docker-Content-Digest"),
+		LastModified:        header.Get("Last-Modified"),
+		ContentType:         header.Get("Content-Type"),
+		ContentEncoding:     header.Get("Content-Encoding"),
+		ValidatedAt:         time.Now().UTC(),
+	}
+	for _, key := range replayedHeaders {
+		if value := header.Get(key); value != "" {
+			if meta.Headers == nil {
+				meta.Headers = make(map[string]string, len(replayedHeaders))
+			}
+			meta.Headers[key] = value
 		}
 	}
-	return ""
+	return meta
 }
 
-func (h *Handler) forgetETag(route *server.HubRoute, locator cache.Locator) {
-	h.etags.Delete(h.locatorKey(route, locator))
+// applyCachedHeaders 在缓存命中时回放首次回源记录的上游头部。
+func applyCachedHeaders(c fiber.Ctx, meta cache.ResponseMetadata) {
+	for key, value := range meta.Headers {
+		c.Set(key, value)
+	}
+	if meta.ETag != "" {
+		c.Set("ETag", meta.ETag)
+	}
+	if meta.DockerContentDigest != "" {
+		c.Set("Docker-Content-Digest", meta.DockerContentDigest)
+	}
+	if meta.LastModified != "" {
+		c.Set("Last-Modified", meta.LastModified)
+	}
+	if meta.ContentEncoding != "" {
+		c.Set("Content-Encoding", meta.ContentEncoding)
+	}
 }
 
-func (h *Handler) locatorKey(route *server.HubRoute, locator cache.Locator) string {
-	hub := locator.HubName
-	if route != nil && route.Config.Name != "" {
-		hub = route.Config.Name
+// cachedValidator 返回再验证时携带的 If-None-Match 值，Docker-Content-Digest 优先于 ETag。
+func cachedValidator(entry cache.Entry) string {
+	if digest := normalizeETag(entry.Response.DockerContentDigest); digest != "" {
+		return digest
+	}
+	return normalizeETag(entry.Response.ETag)
+}
+
+// refreshValidators 在再验证确认正文未变更时写回新的校验器，使下一次再验证可以得到 304；
+// 启用 StaleIfError 或 StaleWhileRevalidate 的 Hub 同时按 validatedAtRefreshInterval 刷新 ValidatedAt。
+func (h *Handler) refreshValidators(ctx context.Context, route *server.HubRoute, entry cache.Entry, header http.Header) {
+	updater, ok := h.store.(cache.MetadataUpdater)
+	if !ok {
+		return
+	}
+	updated := entry.Response
+	latest := responseMetadata(header)
+	if latest.ETag != "" {
+		updated.ETag = latest.ETag
+	}
+	if latest.DockerContentDigest != "" {
+		updated.DockerContentDigest = latest.DockerContentDigest
+	}
+	if latest.LastModified != "" {
+		updated.LastModified = latest.LastModified
+	}
+	if (route.StaleIfError > 0 || route.StaleWhileRevalidate > 0) && time.Since(entry.Response.ValidatedAt) >= validatedAtRefreshInterval {
+		updated.ValidatedAt = latest.ValidatedAt
+	}
+	if updated.ETag == entry.Response.ETag &&
+		updated.DockerContentDigest == entry.Response.DockerContentDigest &&
+		updated.LastModified == entry.Response.LastModified &&
+		updated.ValidatedAt.Equal(entry.Response.ValidatedAt) {
+		return
+	}
+	if err := updater.UpdateResponse(ctx, entry.Locator, updated); err != nil && !errors.Is(err, cache.ErrNotFound) {
+		h.logger.WithError(err).
+			WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key}).
+			Warn("cache_metadata_update_failed")
 	}
-	return hub + "::" + locator.Path
 }
 
 func normalizeETag(value string) string {
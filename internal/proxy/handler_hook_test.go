@@ -27,7 +27,7 @@ func TestResolveUpstreamPrefersHook(t *testing.T) {
 			Name: "demo",
 			Type: "custom",
 		},
-		UpstreamURL: base,
+		Upstreams: []*server.Upstream{{URL: base}},
 	}
 	hook := &hookState{
 		ctx: &hooks.RequestContext{},
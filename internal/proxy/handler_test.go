@@ -2,12 +2,20 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gofiber/fiber/v3"
 	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
 
+	"github.com/any-hub/any-hub/internal/cache"
 	"github.com/any-hub/any-hub/internal/config"
 	"github.com/any-hub/any-hub/internal/hubmodule"
 	"github.com/any-hub/any-hub/internal/server"
@@ -31,8 +39,8 @@ func TestRegistryK8sManifestFallbackLogsStructuredEvent(t *testing.T) {
 			Domain: "k8s.hub.local",
 			Type:   "docker",
 		},
-		Module:      hubmodule.ModuleMetadata{Key: "docker"},
-		UpstreamURL: upstreamURL,
+		Module:    hubmodule.ModuleMetadata{Key: "docker"},
+		Upstreams: []*server.Upstream{{URL: upstreamURL}},
 	}
 
 	h.logRegistryK8sFallback(route, "req-1", "/v2/coredns/manifests/v1.13.1", "/v2/coredns/coredns/manifests/v1.13.1", 404, "GET")
@@ -53,3 +61,97 @@ func TestRegistryK8sManifestFallbackLogsStructuredEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestWithinStaleWindowUsesValidatedAt(t *testing.T) {
+	now := time.Now()
+	route := &server.HubRoute{StaleIfError: time.Hour}
+	entry := cache.Entry{
+		ModTime:  now.Add(-30 * 24 * time.Hour),
+		Response: cache.ResponseMetadata{ValidatedAt: now.Add(-10 * time.Minute)},
+	}
+	if !withinStaleWindow(route, entry, now) {
+		t.Fatalf("entry validated 10 minutes ago should be servable within a 1h window")
+	}
+	entry.Response.ValidatedAt = time.Time{}
+	if withinStaleWindow(route, entry, now) {
+		t.Fatalf("without ValidatedAt the window should fall back to ModTime")
+	}
+	if withinStaleWindow(&server.HubRoute{}, cache.Entry{ModTime: now}, now) {
+		t.Fatalf("StaleIfError=0 disables stale serving")
+	}
+}
+
+func TestRefresherDeduplicatesAndBoundsQueue(t *testing.T) {
+	var r refresher
+	block := make(chan struct{})
+	started := make(chan struct{}, refreshWorkers)
+	run := func(refreshJob) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-block
+	}
+	defer close(block)
+
+	// 先占满全部 worker，之后的任务只能排队。
+	for i := range refreshWorkers {
+		if !r.submit(refreshJob{locator: cache.Locator{HubName: "npm", Path: fmt.Sprintf("/busy-%d", i)}}, run) {
+			t.Fatalf("submit %d should be accepted", i)
+		}
+		<-started
+	}
+	dup := refreshJob{locator: cache.Locator{HubName: "npm", Path: "/demo"}}
+	if !r.submit(dup, run) || !r.submit(dup, run) {
+		t.Fatalf("queued locator should be reported as submitted")
+	}
+	if len(r.jobs) != 1 {
+		t.Fatalf("duplicate submission should not be queued twice, queue=%d", len(r.jobs))
+	}
+	for i := 1; i < refreshQueueSize; i++ {
+		r.submit(refreshJob{locator: cache.Locator{HubName: "npm", Path: fmt.Sprintf("/q-%d", i)}}, run)
+	}
+	if r.submit(refreshJob{locator: cache.Locator{HubName: "npm", Path: "/overflow"}}, run) {
+		t.Fatalf("full queue should reject new work so the caller revalidates synchronously")
+	}
+}
+
+func TestCacheAndStreamStoreOnlySkipsResponseBuffer(t *testing.T) {
+	store, err := cache.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	h := NewHandler(nil, logger, store)
+	route := &server.HubRoute{
+		Config: config.HubConfig{Name: "pypi", Domain: "pypi.hub.local", Type: "pypi"},
+		Module: hubmodule.ModuleMetadata{Key: "pypi"},
+	}
+	locator := cache.Locator{HubName: "pypi", Path: "/files/pkg-1.0.whl"}
+
+	app := fiber.New()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("http://pypi.hub.local/files/pkg-1.0.whl")
+	fctx.SetUserValue(storeOnlyKey, true)
+	c := app.AcquireCtx(fctx)
+	defer app.ReleaseCtx(c)
+
+	body := strings.Repeat("w", 64<<10)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+	writer := cache.NewStrategyWriter(store, hubmodule.CacheStrategyProfile{})
+	if err := h.cacheAndStream(c, route, locator, resp, writer, "", time.Now(), context.Background(), "http://upstream/files/pkg-1.0.whl", cache.PutOptions{}); err != nil {
+		t.Fatalf("cacheAndStream error: %v", err)
+	}
+	if n := len(c.Response().Body()); n != 0 {
+		t.Fatalf("background fills must not buffer the body in the response, got %d bytes", n)
+	}
+	result, err := store.Get(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("entry should be stored: %v", err)
+	}
+	defer result.Reader.Close()
+	if stored, _ := io.ReadAll(result.Reader); string(stored) != body {
+		t.Fatalf("stored body mismatch: %d bytes", len(stored))
+	}
+}
@@ -1,10 +1,19 @@
 package hooks
 
+import (
+	"io"
+	"net/http"
+)
+
 // CachePolicy mirrors the proxy cache policy structure.
 type CachePolicy struct {
 	AllowCache        bool
 	AllowStore        bool
 	RequireRevalidate bool
+	// AllowNegativeCache lets 404/410 answers for the path be remembered for
+	// the hub's NegativeCacheTTL. It arrives true; modules clear it for paths
+	// whose absence is expected to be transient.
+	AllowNegativeCache bool
 }
 
 // RequestContext exposes route/request details without importing server internals.
@@ -15,13 +24,55 @@ type RequestContext struct {
 	ModuleKey    string
 	UpstreamHost string
 	Method       string
+	// Path is the client request path, as passed to RewriteResponse.
+	Path string
+}
+
+// Integrity describes the expected digest of a body. Algorithm is one of
+// "sha1", "sha256", "sha512" (Expected in hex or base64), "h1-zip" or
+// "h1-mod" (Expected as a go.sum "h1:" hash).
+type Integrity struct {
+	Algorithm string
+	Expected  string
 }
 
 // Hooks describes customization points for module-specific behavior.
 type Hooks struct {
 	NormalizePath   func(ctx *RequestContext, cleanPath string, rawQuery []byte) (string, []byte)
 	ResolveUpstream func(ctx *RequestContext, baseURL string, path string, rawQuery []byte) string
+	// RewriteResponse receives the buffered upstream body of every path.
+	// Rewriters keep the upstream ETag and Last-Modified: the rewritten body
+	// is a deterministic function of the upstream body and the hub domain, so
+	// the upstream validator still identifies it when the cached copy is
+	// revalidated.
 	RewriteResponse func(ctx *RequestContext, status int, headers map[string]string, body []byte, path string) (int, map[string]string, []byte, error)
-	CachePolicy     func(ctx *RequestContext, locatorPath string, current CachePolicy) CachePolicy
-	ContentType     func(ctx *RequestContext, locatorPath string) string
+	// RewriteStream is the streaming variant of RewriteResponse and is tried
+	// first, except for paths SkipStreamRewrite reports. It wraps resp.Body
+	// and returns the replacement body with its headers (nil keeps
+	// resp.Header); the proxy drops Content-Length. A nil body declines the path, which then falls
+	// back to RewriteResponse. Errors must be returned before reading
+	// resp.Body, because the proxy then serves the original body unchanged.
+	RewriteStream func(ctx *RequestContext, resp *http.Response) (io.ReadCloser, http.Header, error)
+	// SkipStreamRewrite reports paths RewriteStream never rewrites, typically
+	// immutable artifacts. On modules without RewriteResponse their bodies
+	// stream straight into the cache, keeping resumable partials and background
+	// Range fills.
+	SkipStreamRewrite func(ctx *RequestContext, locatorPath string) bool
+	// ObserveStream inspects a fresh upstream body without changing it: the
+	// returned writer receives a copy of every byte the proxy serves and
+	// caches, and is closed when the body ends or is abandoned. Write errors
+	// only stop the copy. A nil writer skips the response.
+	ObserveStream func(ctx *RequestContext, resp *http.Response) io.WriteCloser
+	CachePolicy   func(ctx *RequestContext, locatorPath string, current CachePolicy) CachePolicy
+	ContentType   func(ctx *RequestContext, locatorPath string) string
+	// ContentDigest reports the sha256:<hex> digest of an immutable body so the
+	// cache can share it across hubs; it returns "" when the digest is unknown.
+	ContentDigest func(ctx *RequestContext, locatorPath string) string
+	// Integrity reports the expected digest of a body; the cache verifies it
+	// while streaming and refuses to commit the entry on mismatch.
+	Integrity func(ctx *RequestContext, locatorPath string) (Integrity, bool)
+	// Vary lists the request headers that select between representations of
+	// the path (e.g. Accept for manifests). Each normalized combination of
+	// their values is cached as a separate entry.
+	Vary func(ctx *RequestContext, locatorPath string) []string
 }
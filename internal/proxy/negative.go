@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// maxNegativeBody 限制负缓存标记保存的上游错误正文大小，超出时不缓存、直接透传。
+const maxNegativeBody = 64 << 10
+
+// isNegativeStatus 判断上游状态是否表示资源不存在，可作为负缓存保存。
+func isNegativeStatus(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone
+}
+
+// withinNegativeTTL 判断负缓存标记是否仍在 Hub 的 NegativeCacheTTL 内。
+func withinNegativeTTL(route *server.HubRoute, entry cache.Entry, now time.Time) bool {
+	if route == nil || route.NegativeCacheTTL <= 0 {
+		return false
+	}
+	return now.Sub(entry.LastValidated()) < route.NegativeCacheTTL
+}
+
+// storeNegative 将上游 404/410 写成负缓存标记后按原状态应答。正文超过 maxNegativeBody 时放弃缓存，
+// 已读取的前缀与剩余部分一并透传给客户端。
+func (h *Handler) storeNegative(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	resp *http.Response,
+	writer cache.StrategyWriter,
+	requestID string,
+	started time.Time,
+	ctx context.Context,
+) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxNegativeBody+1))
+	if err != nil {
+		return h.upstreamFailed(c, route, locator, false, requestID, started, ctx, nil, resp.Request.URL.String(), err)
+	}
+	if len(body) > maxNegativeBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return h.consumeUpstream(c, route, locator, resp, false, writer, requestID, started, ctx, cache.PutOptions{})
+	}
+
+	response := storedResponseMetadata(c, resp)
+	response.Status = resp.StatusCode
+	if _, err := writer.Put(ctx, locator, bytes.NewReader(body), cache.PutOptions{Response: response}); err != nil {
+		h.logger.WithError(err).
+			WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key, "path": locator.Path}).
+			Warn("cache_negative_write_failed")
+	}
+
+	upstreamURL := resp.Request.URL.String()
+	copyResponseHeaders(c, resp.Header)
+	c.Set("X-Any-Hub-Upstream", upstreamURL)
+	c.Set("X-Any-Hub-Cache-Hit", "false")
+	if requestID != "" {
+		c.Set("X-Request-ID", requestID)
+	}
+	c.Status(resp.StatusCode)
+	c.Response().SetBody(body)
+	h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, nil)
+	return nil
+}
+
+// serveNegative 回放负缓存标记：返回记录的 404/410 状态、响应头与错误正文，不访问上游。
+func (h *Handler) serveNegative(c fiber.Ctx, route *server.HubRoute, result *cache.ReadResult, requestID string, started time.Time) error {
+	defer result.Reader.Close()
+	status := result.Entry.Response.Status
+
+	if result.Entry.Response.ContentType != "" {
+		c.Set("Content-Type", result.Entry.Response.ContentType)
+	} else {
+		c.Response().Header.Del("Content-Type")
+	}
+	upstream := servedUpstream(route, result.Entry)
+	applyCachedHeaders(c, result.Entry.Response)
+	c.Set("X-Any-Hub-Upstream", upstream)
+	c.Set("X-Any-Hub-Cache-Hit", strconv.FormatBool(true))
+	if requestID != "" {
+		c.Set("X-Request-ID", requestID)
+	}
+	c.Status(status)
+
+	if c.Method() == http.MethodHead {
+		h.logResult(c, route, upstream, requestID, status, true, started, nil)
+		return nil
+	}
+	_, err := io.Copy(c.Response().BodyWriter(), result.Reader)
+	h.logResult(c, route, upstream, requestID, status, true, started, err)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("read cache failed: %v", err))
+	}
+	return nil
+}
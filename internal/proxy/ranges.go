@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+// errRangeNotSatisfiable 表示 Range 语法合法但与正文没有交集，应返回 416。
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange 为闭区间 [start, start+length) 的字节范围。
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRangeHeader 解析 "bytes=0-99,200-" 形式的 Range 头。语法错误返回 nil, nil（忽略 Range，
+// 按完整正文响应）；所有范围都不可满足时返回 errRangeNotSatisfiable。
+func parseRangeHeader(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok {
+		return nil, nil
+	}
+	var ranges []byteRange
+	noOverlap := false
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		startText, endText, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, nil
+		}
+		startText, endText = strings.TrimSpace(startText), strings.TrimSpace(endText)
+		var r byteRange
+		if startText == "" {
+			// 后缀范围 "-N" 表示最后 N 个字节。
+			n, err := strconv.ParseInt(endText, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			if n == 0 {
+				noOverlap = true
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(startText, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			end := size - 1
+			if endText != "" {
+				end, err = strconv.ParseInt(endText, 10, 64)
+				if err != nil || end < start {
+					return nil, nil
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			r = byteRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 && noOverlap {
+		return nil, errRangeNotSatisfiable
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches 判断 If-Range 是否仍指向缓存中的表示：强 ETag 须完全一致，日期须与 Last-Modified 相同。
+func ifRangeMatches(ifRange string, entry cache.Entry) bool {
+	ifRange = strings.TrimSpace(ifRange)
+	if ifRange == "" {
+		return true
+	}
+	etag, lastModified := entryValidators(entry)
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etag != "" && !strings.HasPrefix(etag, "W/") && !strings.HasPrefix(ifRange, "W/") && etag == ifRange
+	}
+	parsed, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return lastModified.Equal(parsed)
+}
+
+// serveRanges 按请求的 Range 回写缓存正文，返回实际响应状态码。handled 为 false 时调用方应发送完整正文。
+func serveRanges(c fiber.Ctx, reader io.ReadSeeker, entry cache.Entry, contentType string) (status int, handled bool, err error) {
+	header := c.Get(fiber.HeaderRange)
+	if header == "" || !ifRangeMatches(c.Get(fiber.HeaderIfRange), entry) {
+		return fiber.StatusOK, false, nil
+	}
+	size := entry.SizeBytes
+	ranges, err := parseRangeHeader(header, size)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+		c.Response().Header.SetContentLength(0)
+		c.Status(fiber.StatusRequestedRangeNotSatisfiable)
+		return fiber.StatusRequestedRangeNotSatisfiable, true, nil
+	}
+	var total int64
+	for _, r := range ranges {
+		total += r.length
+	}
+	// 与 net/http 一致：范围总和超过正文时视为滥用，直接返回完整正文。
+	if len(ranges) == 0 || total > size {
+		return fiber.StatusOK, false, nil
+	}
+
+	if len(ranges) == 1 {
+		r := ranges[0]
+		c.Set(fiber.HeaderContentRange, r.contentRange(size))
+		c.Response().Header.SetContentLength(int(r.length))
+		c.Status(fiber.StatusPartialContent)
+		if _, err := reader.Seek(r.start, io.SeekStart); err != nil {
+			return fiber.StatusPartialContent, true, err
+		}
+		_, err := io.CopyN(c.Response().BodyWriter(), reader, r.length)
+		return fiber.StatusPartialContent, true, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, r := range ranges {
+		partHeader := textproto.MIMEHeader{}
+		if contentType != "" {
+			partHeader.Set(fiber.HeaderContentType, contentType)
+		}
+		partHeader.Set(fiber.HeaderContentRange, r.contentRange(size))
+		part, err := writer.CreatePart(partHeader)
+		if err != nil {
+			return fiber.StatusPartialContent, true, err
+		}
+		if _, err := reader.Seek(r.start, io.SeekStart); err != nil {
+			return fiber.StatusPartialContent, true, err
+		}
+		if _, err := io.CopyN(part, reader, r.length); err != nil {
+			return fiber.StatusPartialContent, true, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fiber.StatusPartialContent, true, err
+	}
+	c.Set(fiber.HeaderContentType, "multipart/byteranges; boundary="+writer.Boundary())
+	c.Response().Header.SetContentLength(body.Len())
+	c.Status(fiber.StatusPartialContent)
+	_, err = c.Response().BodyWriter().Write(body.Bytes())
+	return fiber.StatusPartialContent, true, err
+}
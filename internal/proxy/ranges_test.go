@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+func TestParseRangeHeader(t *testing.T) {
+	cases := []struct {
+		header string
+		want   []byteRange
+	}{
+		{"bytes=0-4", []byteRange{{0, 5}}},
+		{"bytes=5-", []byteRange{{5, 5}}},
+		{"bytes=-3", []byteRange{{7, 3}}},
+		{"bytes=8-100", []byteRange{{8, 2}}},
+		{"bytes=0-1, 4-5", []byteRange{{0, 2}, {4, 2}}},
+		{"items=0-1", nil},
+		{"bytes=5-1", nil},
+	}
+	for _, tc := range cases {
+		got, err := parseRangeHeader(tc.header, 10)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.header, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.header, tc.want, got)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: expected %v, got %v", tc.header, tc.want, got)
+			}
+		}
+	}
+	if _, err := parseRangeHeader("bytes=20-30", 10); !errors.Is(err, errRangeNotSatisfiable) {
+		t.Fatalf("expected unsatisfiable range, got %v", err)
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := cache.Entry{
+		ModTime:  modTime,
+		Response: cache.ResponseMetadata{ETag: `"v1"`},
+	}
+	if !ifRangeMatches(`"v1"`, entry) {
+		t.Fatalf("expected matching strong etag")
+	}
+	if ifRangeMatches(`"v2"`, entry) || ifRangeMatches(`W/"v1"`, entry) {
+		t.Fatalf("expected mismatched or weak etag to fail")
+	}
+	if !ifRangeMatches(modTime.Format("Mon, 02 Jan 2006 15:04:05 GMT"), entry) {
+		t.Fatalf("expected matching date")
+	}
+	if ifRangeMatches(modTime.Add(time.Hour).Format("Mon, 02 Jan 2006 15:04:05 GMT"), entry) {
+		t.Fatalf("expected different date to fail")
+	}
+}
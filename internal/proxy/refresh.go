@@ -0,0 +1,224 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/metrics"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	// refreshWorkers 为后台再验证的并发上限。
+	refreshWorkers = 4
+	// refreshQueueSize 为等待后台再验证的条目上限，队列满时请求退回同步再验证。
+	refreshQueueSize = 256
+	// refreshTimeout 限制单次后台再验证（含必要的完整回源）的耗时。
+	refreshTimeout = 5 * time.Minute
+)
+
+// backgroundRefreshes 统计因 StaleWhileRevalidate 先返回缓存、转入后台再验证的次数。
+var backgroundRefreshes = metrics.NewCounter(
+	"anyhub_background_refreshes_total",
+	"Cache hits served immediately while the entry was revalidated in the background (StaleWhileRevalidate).",
+)
+
+// backgroundFills 统计客户端自带 Range 的未命中请求转入后台完整回源的次数。
+var backgroundFills = metrics.NewCounter(
+	"anyhub_background_fills_total",
+	"Range requests that missed the cache and were answered with the upstream range while the full body was fetched in the background.",
+)
+
+// storeOnlyKey 为 fasthttp.RequestCtx 的 UserValue 键，标记后台任务的请求：没有客户端读取响应，
+// 回源正文直接写入缓存而不写入响应缓冲，已缓存的条目也不再回写。
+const storeOnlyKey = "anyhub.store_only"
+
+func storeOnly(c fiber.Ctx) bool {
+	return c.RequestCtx().UserValue(storeOnlyKey) != nil
+}
+
+// refreshJob 保存后台任务所需的请求快照；原始 fiber.Ctx 在响应返回后即被回收，不能跨 goroutine 使用。
+type refreshJob struct {
+	app        *fiber.App
+	request    *fasthttp.Request
+	remoteAddr net.Addr
+	route      *server.HubRoute
+	locator    cache.Locator
+	policy     cachePolicy
+	writer     cache.StrategyWriter
+	hook       hookState
+	entry      cache.Entry
+	requestID  string
+	// fill 表示不做再验证，直接完整回源写入缓存（Range 未命中的后台填充）。
+	fill bool
+}
+
+// refresher 是有界的后台再验证池：同一 Locator 同时只排队一次，worker 在首次提交时启动。
+type refresher struct {
+	once    sync.Once
+	jobs    chan refreshJob
+	mu      sync.Mutex
+	pending map[cache.Locator]struct{}
+}
+
+// submit 将任务放入队列；该条目已在排队或执行时视为已提交。队列已满时返回 false。
+func (r *refresher) submit(job refreshJob, run func(refreshJob)) bool {
+	r.once.Do(func() {
+		r.jobs = make(chan refreshJob, refreshQueueSize)
+		r.pending = make(map[cache.Locator]struct{})
+		for range refreshWorkers {
+			go r.work(run)
+		}
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[job.locator]; ok {
+		return true
+	}
+	select {
+	case r.jobs <- job:
+		r.pending[job.locator] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *refresher) work(run func(refreshJob)) {
+	for job := range r.jobs {
+		run(job)
+		r.mu.Lock()
+		delete(r.pending, job.locator)
+		r.mu.Unlock()
+	}
+}
+
+// withinRevalidateWindow 判断条目是否可以先返回、再在后台再验证，起点为最近一次与上游确认的时间。
+func withinRevalidateWindow(route *server.HubRoute, entry cache.Entry, now time.Time) bool {
+	if route == nil || route.StaleWhileRevalidate <= 0 {
+		return false
+	}
+	return now.Sub(entry.LastValidated()) <= route.StaleWhileRevalidate
+}
+
+// scheduleRefresh 为 GET 命中安排后台再验证，返回 true 表示调用方可直接返回缓存。
+func (h *Handler) scheduleRefresh(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	policy cachePolicy,
+	writer cache.StrategyWriter,
+	entry cache.Entry,
+	hook *hookState,
+	requestID string,
+) bool {
+	if c.Method() != http.MethodGet || c.App() == nil || !withinRevalidateWindow(route, entry, time.Now()) {
+		return false
+	}
+	job := newBackgroundJob(c, route, locator, policy, writer, hook, requestID)
+	job.entry = entry
+	if !h.refresher.submit(job, h.runRefresh) {
+		return false
+	}
+	backgroundRefreshes.Inc(route.Config.Name)
+	return true
+}
+
+// scheduleFill 为客户端自带 Range 的 GET 未命中安排后台完整回源写入缓存，返回 true 表示调用方
+// 只需向上游转发客户端的 Range。队列已满时返回 false，调用方退回先完整写入缓存再切片。
+func (h *Handler) scheduleFill(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	policy cachePolicy,
+	writer cache.StrategyWriter,
+	hook *hookState,
+	requestID string,
+) bool {
+	if c.Method() != http.MethodGet || c.App() == nil || hook == nil {
+		return false
+	}
+	job := newBackgroundJob(c, route, locator, policy, writer, hook, requestID)
+	job.fill = true
+	if !h.refresher.submit(job, h.runRefresh) {
+		return false
+	}
+	backgroundFills.Inc(route.Config.Name)
+	return true
+}
+
+func newBackgroundJob(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	policy cachePolicy,
+	writer cache.StrategyWriter,
+	hook *hookState,
+	requestID string,
+) refreshJob {
+	request := &fasthttp.Request{}
+	c.Request().CopyTo(request)
+	// 后台任务总是完整回源，不继承客户端的 Range 与条件请求头。
+	for _, header := range []string{fiber.HeaderRange, fiber.HeaderIfRange, fiber.HeaderIfNoneMatch, fiber.HeaderIfModifiedSince} {
+		request.Header.Del(header)
+	}
+	snapshot := *hook
+	snapshot.rangeOverride = nil
+	if hook.ctx != nil {
+		hookCtx := *hook.ctx
+		snapshot.ctx = &hookCtx
+	}
+
+	return refreshJob{
+		app:        c.App(),
+		request:    request,
+		remoteAddr: c.RequestCtx().RemoteAddr(),
+		route:      route,
+		locator:    locator,
+		policy:     policy,
+		writer:     writer,
+		hook:       snapshot,
+		requestID:  requestID,
+	}
+}
+
+// runRefresh 在独立的 fiber.Ctx 上执行再验证（填充任务跳过再验证）；条目已变更或尚未缓存时经 fetchCoalesced
+// 回源写缓存，与同时到达的前台回源合并。使用独立的 context，客户端断开不会中断后台任务；
+// 请求标记为只写缓存，多 GB 的正文不会在响应缓冲中完整驻留。
+func (h *Handler) runRefresh(job refreshJob) {
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init(job.request, job.remoteAddr, nil)
+	fctx.SetUserValue(storeOnlyKey, true)
+	c := job.app.AcquireCtx(fctx)
+	defer job.app.ReleaseCtx(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	c.SetContext(ctx)
+
+	hook := job.hook
+	var (
+		fresh bool
+		err   error
+	)
+	if !job.fill {
+		fresh, err = h.isCacheFresh(c, job.route, job.locator, job.entry, &hook)
+	}
+	if err == nil && !fresh {
+		err = h.fetchCoalesced(c, job.route, job.locator, job.policy, job.writer, job.requestID, time.Now(), ctx, &hook)
+	}
+	if err != nil {
+		h.logger.WithError(err).
+			WithFields(logrus.Fields{"hub": job.route.Config.Name, "module_key": job.route.Module.Key, "path": job.locator.Path}).
+			Warn("cache_background_refresh_failed")
+	}
+}
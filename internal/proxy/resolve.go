@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/proxy/hooks"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// ResolveRequest 按 Handle 相同的顺序（NormalizePath → LocatorRewrite → Vary → ResolveUpstream）
+// 计算 GET requestURI 在 route 上对应的缓存 Locator 与回源地址，
+// 供 prefetch 等离线命令在不发起请求的情况下判断条目是否已缓存。header 为将要发送的请求头，可为 nil。
+func ResolveRequest(route *server.HubRoute, requestURI string, header http.Header) (cache.Locator, *url.URL, error) {
+	if route == nil || route.PrimaryUpstream() == nil {
+		return cache.Locator{}, nil, errors.New("route is required")
+	}
+	parsed, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return cache.Locator{}, nil, err
+	}
+	hooksDef, ok := hooks.Fetch(route.Module.Key)
+	hookCtx := newHookContext(route, http.MethodGet)
+	var rawQuery []byte
+	if parsed.RawQuery != "" {
+		rawQuery = []byte(parsed.RawQuery)
+	}
+	cleanPath := normalizeRequestPath(route, parsed.Path)
+	if hasHook(hooksDef) && hooksDef.NormalizePath != nil {
+		newPath, newQuery := hooksDef.NormalizePath(hookCtx, cleanPath, rawQuery)
+		if newPath != "" {
+			cleanPath = newPath
+		}
+		rawQuery = newQuery
+	}
+	state := hookState{
+		ctx:      hookCtx,
+		def:      hooksDef,
+		hasHooks: ok && hasHook(hooksDef),
+		clean:    cleanPath,
+		rawQuery: rawQuery,
+	}
+	locator := buildLocator(route, nil, cleanPath, rawQuery)
+	locator = withVariant(locator, variantKey(variantHeaders(&state, locator), header.Get))
+	return locator, resolveUpstreamPath(route.PrimaryUpstream(), cleanPath, rawQuery, &state), nil
+}
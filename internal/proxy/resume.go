@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// upstreamRange 覆盖写入缓存的回源请求的 Range/If-Range：客户端的 Range 不再透传，回源总是拉取完整正文；
+// from 大于 0 时从保留的前缀处续传，ifRange 保证上游内容未变更。
+type upstreamRange struct {
+	from    int64
+	ifRange string
+}
+
+func applyUpstreamRange(req *http.Request, hook *hookState) {
+	if hook == nil || hook.rangeOverride == nil {
+		return
+	}
+	req.Header.Del("Range")
+	req.Header.Del("If-Range")
+	if hook.rangeOverride.from > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", hook.rangeOverride.from))
+		req.Header.Set("If-Range", hook.rangeOverride.ifRange)
+	}
+}
+
+// storeFirstKey 为 fasthttp.RequestCtx 的 UserValue 键，见 RequireStoreBeforeRange。
+const storeFirstKey = "anyhub.store_first"
+
+// RequireStoreBeforeRange 使该请求的 Range 未命中先把完整正文写入缓存再切片，而不是转发 Range 并转入后台填充；
+// cache prefetch 据此在处理链返回时确认条目已经落盘。
+func RequireStoreBeforeRange(fctx *fasthttp.RequestCtx) {
+	fctx.SetUserValue(storeFirstKey, true)
+}
+
+func storeFirst(c fiber.Ctx) bool {
+	return c.RequestCtx().UserValue(storeFirstKey) != nil
+}
+
+// lookupPartial 返回可续传的前缀；缺少强校验器的前缀无法保证与上游一致，直接丢弃。
+func (h *Handler) lookupPartial(ctx context.Context, route *server.HubRoute, locator cache.Locator) *cache.PartialEntry {
+	store, ok := h.store.(cache.PartialStore)
+	if !ok {
+		return nil
+	}
+	partial, err := store.Partial(ctx, locator)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			h.logger.WithError(err).
+				WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key}).
+				Warn("cache_partial_lookup_failed")
+		}
+		return nil
+	}
+	if partialValidator(partial.Response) == "" {
+		h.discardPartial(ctx, locator)
+		return nil
+	}
+	return partial
+}
+
+func (h *Handler) discardPartial(ctx context.Context, locator cache.Locator) {
+	if store, ok := h.store.(cache.PartialStore); ok {
+		_ = store.DiscardPartial(ctx, locator)
+	}
+}
+
+// partialValidator 选择续传时的 If-Range 值：优先强 ETag，其次 Last-Modified。
+func partialValidator(meta cache.ResponseMetadata) string {
+	if meta.ETag != "" && !strings.HasPrefix(meta.ETag, "W/") {
+		return meta.ETag
+	}
+	return meta.LastModified
+}
+
+// resumedAt 解析 206 响应的 Content-Range 起始偏移，仅接受延续到正文末尾的范围。
+func resumedAt(resp *http.Response) (int64, bool) {
+	if resp == nil || resp.StatusCode != http.StatusPartialContent {
+		return 0, false
+	}
+	spec, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, false
+	}
+	startText, endText, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if total != "*" {
+		end, err1 := strconv.ParseInt(endText, 10, 64)
+		size, err2 := strconv.ParseInt(total, 10, 64)
+		if err1 != nil || err2 != nil || end != size-1 {
+			return 0, false
+		}
+	}
+	return start, true
+}
+
+// storeThenServe 先完整写入缓存再经命中路径回写，用于续传（客户端需要完整正文而上游只返回剩余部分）
+// 以及无法转入后台填充的 Range 未命中请求（由缓存按 Range 切片）。
+func (h *Handler) storeThenServe(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	locator cache.Locator,
+	resp *http.Response,
+	writer cache.StrategyWriter,
+	requestID string,
+	started time.Time,
+	ctx context.Context,
+	upstreamURL string,
+	opts cache.PutOptions,
+	hook *hookState,
+) error {
+	_, err := writer.Put(ctx, locator, resp.Body, opts)
+	if err != nil {
+		h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, err)
+		var integrityErr *cache.IntegrityError
+		if errors.As(err, &integrityErr) {
+			h.logIntegrityMismatch(route, locator, upstreamURL, requestID, integrityErr)
+			return h.writeError(c, fiber.StatusBadGateway, "integrity_mismatch")
+		}
+		return h.writeError(c, fiber.StatusBadGateway, "cache_write_failed")
+	}
+	if storeOnly(c) {
+		h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, nil)
+		return nil
+	}
+	result, err := h.store.Get(ctx, locator)
+	if err != nil {
+		h.logResult(c, route, upstreamURL, requestID, resp.StatusCode, false, started, err)
+		return h.writeError(c, fiber.StatusBadGateway, "cache_read_failed")
+	}
+	defer result.Reader.Close()
+	return h.serveCache(c, route, result, requestID, started, hook, false)
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/logging"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// maxRetryDelay 为单次重试等待的上限；上游 Retry-After 超过该值时不再重试，直接返回其响应。
+const maxRetryDelay = 30 * time.Second
+
+// retryDrainLimit 为丢弃失败响应正文时最多读取的字节数，读完可复用底层连接。
+const retryDrainLimit = 64 << 10
+
+// upstreamRetriesKey 为 fiber Locals 中累计本次请求回源重试次数的键。
+const upstreamRetriesKey = "anyhub.upstream_retries"
+
+// isRetryableMethod 仅重试幂等请求，避免重复提交有副作用的上游请求。
+func isRetryableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// isRetryableStatus 判断上游状态是否为可重试的暂时性错误：限流与网关类 5xx。
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendWithRetry 发送请求，遇到连接错误或可重试状态时按指数退避加抖动重试，最多 maxRetries 次。
+// 返回最终响应与实际重试次数；客户端取消请求时立即停止。
+func (h *Handler) sendWithRetry(
+	req *http.Request,
+	route *server.HubRoute,
+	maxRetries int,
+	send func(*http.Request) (*http.Response, error),
+) (*http.Response, int, error) {
+	if route == nil || !isRetryableMethod(req.Method) || !rewindable(req) {
+		maxRetries = 0
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, attempt, err
+			}
+			req.Body = body
+		}
+		resp, err := send(req)
+		if attempt >= maxRetries || ctx.Err() != nil {
+			return resp, attempt, err
+		}
+		var delay time.Duration
+		switch {
+		case err != nil:
+			delay = retryBackoff(route.InitialBackoff, attempt)
+		case isRetryableStatus(resp.StatusCode):
+			var ok bool
+			if delay, ok = retryAfter(resp.Header, time.Now()); !ok {
+				delay = retryBackoff(route.InitialBackoff, attempt)
+			} else if delay > maxRetryDelay {
+				return resp, attempt, nil
+			}
+			drainAndClose(resp.Body)
+		default:
+			return resp, attempt, nil
+		}
+
+		h.logUpstreamRetry(route, req, resp, err, attempt+1, maxRetries, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rewindable 判断请求正文可以在重试时重新读取。
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryBackoff 返回第 attempt 次重试（从 0 起）前的等待：InitialBackoff 逐次翻倍并封顶，
+// 在后一半区间内随机抖动，避免大量请求同时重试。
+func retryBackoff(initial time.Duration, attempt int) time.Duration {
+	if initial <= 0 {
+		initial = time.Second
+	}
+	backoff := initial
+	for i := 0; i < attempt && backoff < maxRetryDelay; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxRetryDelay)
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter 解析 Retry-After（秒数或 HTTP 日期），缺失或无法解析时返回 false。
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, retryDrainLimit))
+	body.Close()
+}
+
+// addUpstreamRetries 累计本次请求的回源重试次数，由 logResult 输出为 retries 字段。
+func addUpstreamRetries(c fiber.Ctx, n int) {
+	if c == nil || n == 0 {
+		return
+	}
+	c.Locals(upstreamRetriesKey, upstreamRetries(c)+n)
+}
+
+func upstreamRetries(c fiber.Ctx) int {
+	if c == nil {
+		return 0
+	}
+	if value, ok := c.Locals(upstreamRetriesKey).(int); ok {
+		return value
+	}
+	return 0
+}
+
+func (h *Handler) logUpstreamRetry(route *server.HubRoute, req *http.Request, resp *http.Response, err error, attempt, maxRetries int, delay time.Duration) {
+	fields := logging.RequestFields(
+		route.Config.Name,
+		route.Config.Domain,
+		route.Config.Type,
+		route.Config.AuthMode(),
+		route.Module.Key,
+		false,
+	)
+	fields["action"] = "proxy_retry"
+	fields["upstream"] = req.URL.String()
+	fields["method"] = req.Method
+	fields["attempt"] = attempt
+	fields["max_retries"] = maxRetries
+	fields["delay_ms"] = delay.Milliseconds()
+	if err != nil {
+		fields["reason"] = "upstream_error"
+		fields["error"] = err.Error()
+	} else {
+		fields["reason"] = "upstream_status"
+		fields["upstream_status"] = resp.StatusCode
+	}
+	h.logger.WithFields(fields).Warn("proxy_upstream_retry")
+}
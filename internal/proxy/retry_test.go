@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoffDoublesWithJitterAndCap(t *testing.T) {
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for range 20 {
+			delay := retryBackoff(100*time.Millisecond, attempt)
+			if delay < base/2 || delay > base {
+				t.Fatalf("attempt %d: delay %s outside [%s, %s]", attempt, delay, base/2, base)
+			}
+		}
+	}
+	if delay := retryBackoff(time.Second, 20); delay > maxRetryDelay {
+		t.Fatalf("delay must be capped at %s, got %s", maxRetryDelay, delay)
+	}
+}
+
+func TestRetryAfterParsesSecondsAndDates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		header := http.Header{}
+		if tc.value != "" {
+			header.Set("Retry-After", tc.value)
+		}
+		got, ok := retryAfter(header, now)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("Retry-After %q: got (%s, %v), want (%s, %v)", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
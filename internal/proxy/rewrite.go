@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+// shouldRewrite 判断是否把回源正文交给 RewriteStream/RewriteResponse。RewriteResponse 对所有路径生效；
+// 只注册 RewriteStream 的模块由 SkipStreamRewrite 声明不改写的路径（如不可变制品），这些正文直接流式写缓存，
+// 保留断点续传与 Range 后台填充能力。
+func shouldRewrite(hook *hookState, locator cache.Locator) bool {
+	if hook == nil || !hook.hasHooks {
+		return false
+	}
+	return hook.def.RewriteResponse != nil || streamRewrite(hook, locator)
+}
+
+// streamRewrite 判断是否对该条目尝试 RewriteStream。
+func streamRewrite(hook *hookState, locator cache.Locator) bool {
+	if hook == nil || !hook.hasHooks || hook.def.RewriteStream == nil {
+		return false
+	}
+	return hook.def.SkipStreamRewrite == nil || !hook.def.SkipStreamRewrite(hook.ctx, stripQueryMarker(locator.Path))
+}
+
+// applyHookRewrite 在 stream 为 true 时优先调用 RewriteStream 边读边改写；模块放弃该路径时读取整个正文并调用
+// RewriteResponse。读取失败时返回 nil 响应，调用方需放弃本次回源。
+func applyHookRewrite(hook *hookState, resp *http.Response, path string, stream bool) (*http.Response, error) {
+	if hook == nil {
+		return resp, nil
+	}
+	if stream {
+		ctx := *hook.ctx
+		ctx.Path = path
+		body, headers, err := hook.def.RewriteStream(&ctx, resp)
+		if err != nil {
+			return resp, err
+		}
+		if body != nil {
+			if headers == nil {
+				headers = resp.Header
+			}
+			cloned := *resp
+			cloned.Header = headers.Clone()
+			cloned.Header.Del("Content-Length")
+			cloned.ContentLength = -1
+			cloned.Body = &rewrittenBody{ReadCloser: body}
+			return &cloned, nil
+		}
+	}
+	if hook.def.RewriteResponse == nil {
+		return resp, nil
+	}
+	return applyBufferedRewrite(hook, resp, path)
+}
+
+func applyBufferedRewrite(hook *hookState, resp *http.Response, path string) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	status, newHeaders, newBody, rewriteErr := hook.def.RewriteResponse(hook.ctx, resp.StatusCode, headers, body, path)
+	if rewriteErr != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		return resp, rewriteErr
+	}
+	if newHeaders == nil {
+		newHeaders = headers
+	}
+	if newBody == nil {
+		newBody = body
+	}
+	cloned := *resp
+	cloned.StatusCode = status
+	cloned.Header = make(http.Header, len(newHeaders))
+	for key, value := range newHeaders {
+		// 模块未改动的头部保留上游的全部取值，map[string]string 只能携带第一个。
+		if original := resp.Header.Values(key); len(original) > 1 && original[0] == value {
+			cloned.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), original...)
+			continue
+		}
+		cloned.Header.Set(key, value)
+	}
+	cloned.Body = io.NopCloser(bytes.NewReader(newBody))
+	cloned.ContentLength = int64(len(newBody))
+	return &cloned, nil
+}
+
+// rewrittenBody 标记经 RewriteStream 改写的正文。其字节与上游偏移不再对应，
+// 中途失败时不能保留前缀供 Range 续传。
+type rewrittenBody struct {
+	io.ReadCloser
+}
+
+func isRewritten(resp *http.Response) bool {
+	_, ok := resp.Body.(*rewrittenBody)
+	return ok
+}
+
+// observeStream 把回源正文的副本交给 ObserveStream，客户端与缓存收到的字节和响应头保持不变。
+func observeStream(hook *hookState, resp *http.Response, path string) *http.Response {
+	if hook == nil || !hook.hasHooks || hook.def.ObserveStream == nil {
+		return resp
+	}
+	ctx := *hook.ctx
+	ctx.Path = path
+	sink := hook.def.ObserveStream(&ctx, resp)
+	if sink == nil {
+		return resp
+	}
+	cloned := *resp
+	cloned.Body = &observedBody{ReadCloser: resp.Body, sink: sink}
+	return &cloned
+}
+
+// observedBody 在读取时把字节抄送给观察者；观察者出错后只停止抄送，不影响正文本身。
+type observedBody struct {
+	io.ReadCloser
+	sink   io.WriteCloser
+	failed bool
+	once   sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.failed {
+		if _, werr := b.sink.Write(p[:n]); werr != nil {
+			b.failed = true
+		}
+	}
+	if err != nil {
+		b.closeSink()
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	b.closeSink()
+	return b.ReadCloser.Close()
+}
+
+func (b *observedBody) closeSink() {
+	b.once.Do(func() { b.sink.Close() })
+}
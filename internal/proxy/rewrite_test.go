@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/proxy/hooks"
+)
+
+func upstreamResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Length": {"5"}, "Link": {"<a>", "<b>"}, "Content-Type": {"text/plain"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+func TestApplyHookRewritePrefersStream(t *testing.T) {
+	var streamPath string
+	hook := &hookState{
+		ctx: &hooks.RequestContext{},
+		def: hooks.Hooks{
+			RewriteStream: func(ctx *hooks.RequestContext, resp *http.Response) (io.ReadCloser, http.Header, error) {
+				streamPath = ctx.Path
+				return io.NopCloser(io.MultiReader(strings.NewReader("<"), resp.Body, strings.NewReader(">"))), nil, nil
+			},
+			RewriteResponse: func(*hooks.RequestContext, int, map[string]string, []byte, string) (int, map[string]string, []byte, error) {
+				t.Fatalf("buffered hook must not run when the stream hook accepts the path")
+				return 0, nil, nil, nil
+			},
+		},
+		hasHooks: true,
+	}
+	resp, err := applyHookRewrite(hook, upstreamResponse("hello"), "/meta", true)
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "<hello>" || streamPath != "/meta" {
+		t.Fatalf("unexpected stream rewrite: body=%q path=%q", body, streamPath)
+	}
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" || len(resp.Header.Values("Link")) != 2 {
+		t.Fatalf("unexpected headers after stream rewrite: %v (length %d)", resp.Header, resp.ContentLength)
+	}
+	if !isRewritten(resp) {
+		t.Fatalf("stream-rewritten body must not be kept as a resumable partial")
+	}
+}
+
+func TestApplyHookRewriteFallsBackToBufferedHook(t *testing.T) {
+	hook := &hookState{
+		ctx: &hooks.RequestContext{},
+		def: hooks.Hooks{
+			RewriteStream: func(*hooks.RequestContext, *http.Response) (io.ReadCloser, http.Header, error) {
+				return nil, nil, nil
+			},
+			RewriteResponse: func(_ *hooks.RequestContext, status int, headers map[string]string, body []byte, _ string) (int, map[string]string, []byte, error) {
+				headers["Content-Type"] = "application/json"
+				return status, headers, []byte(strings.ToUpper(string(body))), nil
+			},
+		},
+		hasHooks: true,
+	}
+	resp, err := applyHookRewrite(hook, upstreamResponse("hello"), "/packages.json", true)
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HELLO" || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected buffered rewrite: body=%q headers=%v", body, resp.Header)
+	}
+	if links := resp.Header.Values("Link"); len(links) != 2 {
+		t.Fatalf("headers left unchanged by the hook should keep every value, got %v", links)
+	}
+	if isRewritten(resp) {
+		t.Fatalf("buffered rewrite should not be marked as streamed")
+	}
+}
+
+func TestShouldRewriteKeepsBufferedHookOnEveryPath(t *testing.T) {
+	buffered := func(_ *hooks.RequestContext, status int, headers map[string]string, body []byte, _ string) (int, map[string]string, []byte, error) {
+		return status, headers, body, nil
+	}
+	stream := func(*hooks.RequestContext, *http.Response) (io.ReadCloser, http.Header, error) {
+		return nil, nil, nil
+	}
+	skipTarballs := func(_ *hooks.RequestContext, locatorPath string) bool {
+		return strings.HasSuffix(locatorPath, ".tgz")
+	}
+	tarball := cache.Locator{HubName: "npm", Path: "/demo/-/demo-1.0.0.tgz"}
+	packument := cache.Locator{HubName: "npm", Path: "/demo"}
+
+	withBuffered := &hookState{ctx: &hooks.RequestContext{}, hasHooks: true, def: hooks.Hooks{
+		RewriteResponse: buffered, RewriteStream: stream, SkipStreamRewrite: skipTarballs,
+	}}
+	if !shouldRewrite(withBuffered, tarball) || streamRewrite(withBuffered, tarball) {
+		t.Fatalf("RewriteResponse must still see paths skipped by the stream hook")
+	}
+
+	streamOnly := &hookState{ctx: &hooks.RequestContext{}, hasHooks: true, def: hooks.Hooks{
+		RewriteStream: stream, SkipStreamRewrite: skipTarballs,
+	}}
+	if shouldRewrite(streamOnly, tarball) {
+		t.Fatalf("paths skipped by SkipStreamRewrite should stream straight into the cache")
+	}
+	if !shouldRewrite(streamOnly, packument) || !streamRewrite(streamOnly, packument) {
+		t.Fatalf("other paths should go through RewriteStream")
+	}
+}
+
+func TestObserveStreamKeepsBodyAndHeaders(t *testing.T) {
+	var seen strings.Builder
+	closed := false
+	hook := &hookState{
+		ctx: &hooks.RequestContext{},
+		def: hooks.Hooks{
+			ObserveStream: func(ctx *hooks.RequestContext, resp *http.Response) io.WriteCloser {
+				if ctx.Path != "/meta" {
+					t.Fatalf("unexpected observed path %q", ctx.Path)
+				}
+				return &testSink{Builder: &seen, closed: &closed}
+			},
+		},
+		hasHooks: true,
+	}
+	resp := observeStream(hook, upstreamResponse("hello"), "/meta")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "hello" {
+		t.Fatalf("observed body must pass through: %q err=%v", body, err)
+	}
+	if seen.String() != "hello" || !closed {
+		t.Fatalf("observer should see the whole body and be closed: %q closed=%v", seen.String(), closed)
+	}
+	if resp.ContentLength != 5 || resp.Header.Get("Content-Length") != "5" || isRewritten(resp) {
+		t.Fatalf("observation must not change the response: %v (length %d)", resp.Header, resp.ContentLength)
+	}
+}
+
+func TestObserveStreamIgnoresObserverErrors(t *testing.T) {
+	hook := &hookState{
+		ctx: &hooks.RequestContext{},
+		def: hooks.Hooks{
+			ObserveStream: func(*hooks.RequestContext, *http.Response) io.WriteCloser {
+				pr, pw := io.Pipe()
+				pr.CloseWithError(io.ErrUnexpectedEOF)
+				return pw
+			},
+		},
+		hasHooks: true,
+	}
+	resp := observeStream(hook, upstreamResponse("hello"), "/meta")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "hello" {
+		t.Fatalf("observer failure must not affect the body: %q err=%v", body, err)
+	}
+}
+
+type testSink struct {
+	*strings.Builder
+	closed *bool
+}
+
+func (s *testSink) Close() error {
+	*s.closed = true
+	return nil
+}
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/logging"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	// staleHeader 标记本次响应来自上游失败后回放的陈旧缓存。
+	staleHeader = "X-Any-Hub-Stale"
+	// staleWarning 为 RFC 7234 定义的 110 Response is Stale 警告。
+	staleWarning = `110 - "Response is Stale"`
+	// validatedAtRefreshInterval 限制再验证成功后回写 ValidatedAt 的频率，避免热点条目每次命中都重写元数据。
+	validatedAtRefreshInterval = time.Minute
+)
+
+// withinStaleWindow 判断条目是否仍处于 Hub 的 StaleIfError 窗口内，起点为最近一次与上游确认的时间。
+func withinStaleWindow(route *server.HubRoute, entry cache.Entry, now time.Time) bool {
+	if route == nil || route.StaleIfError <= 0 {
+		return false
+	}
+	return now.Sub(entry.LastValidated()) <= route.StaleIfError
+}
+
+// circuitOpen 判断上游失败是否因为 Hub 的所有上游都处于熔断状态。
+func circuitOpen(err error) bool {
+	var open *circuitOpenError
+	return errors.As(err, &open)
+}
+
+// lookupStale 在上游失败后重新读取缓存，仅返回仍处于 StaleIfError 窗口内的条目（负缓存标记除外）。
+// anyAge 为 true 时（所有上游均被熔断）不限窗口：熔断期间任何缓存副本都好过快速失败。
+func (h *Handler) lookupStale(ctx context.Context, route *server.HubRoute, locator cache.Locator, anyAge bool) *cache.ReadResult {
+	if h.store == nil || (route.StaleIfError <= 0 && !anyAge) {
+		return nil
+	}
+	result, err := h.store.Get(ctx, locator)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotFound) {
+			h.logger.WithError(err).
+				WithFields(logrus.Fields{"hub": route.Config.Name, "module_key": route.Module.Key}).
+				Warn("cache_get_failed")
+		}
+		return nil
+	}
+	if result.Entry.Negative() || (!anyAge && !withinStaleWindow(route, result.Entry, time.Now())) {
+		result.Reader.Close()
+		return nil
+	}
+	return result
+}
+
+// serveStale 以 Warning: 110 与 X-Any-Hub-Stale 标记回放缓存副本，reason 记录触发回放的上游错误。
+func (h *Handler) serveStale(
+	c fiber.Ctx,
+	route *server.HubRoute,
+	result *cache.ReadResult,
+	requestID string,
+	started time.Time,
+	hook *hookState,
+	reason string,
+) error {
+	fields := logging.RequestFields(
+		route.Config.Name,
+		route.Config.Domain,
+		route.Config.Type,
+		route.Config.AuthMode(),
+		route.Module.Key,
+		true,
+	)
+	fields["action"] = "proxy"
+	fields["path"] = result.Entry.Locator.Path
+	fields["stale"] = true
+	fields["stale_age_ms"] = time.Since(result.Entry.LastValidated()).Milliseconds()
+	fields["reason"] = reason
+	if requestID != "" {
+		fields["request_id"] = requestID
+	}
+	h.logger.WithFields(fields).Warn("proxy_stale_served")
+
+	defer result.Reader.Close()
+	c.Set(fiber.HeaderWarning, staleWarning)
+	c.Set(staleHeader, "true")
+	return h.serveCache(c, route, result, requestID, started, hook, true)
+}
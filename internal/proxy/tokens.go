@@ -0,0 +1,318 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	dockermodule "github.com/any-hub/any-hub/internal/hubmodule/docker"
+	"github.com/any-hub/any-hub/internal/metrics"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	// defaultTokenLifetime 为 token 响应缺少 expires_in 时的有效期，与 distribution token 规范的默认值一致。
+	defaultTokenLifetime = 60 * time.Second
+	// tokenExpiryMargin 让 token 提前失效，避免请求途中过期；不超过有效期的一半。
+	tokenExpiryMargin = 10 * time.Second
+	// maxCachedTokens 为缓存 token 数量上限，超出时先清理过期项，再丢弃最早过期的 token。
+	maxCachedTokens = 1024
+)
+
+// tokenRequests 统计向 realm 申请 bearer token 的次数，用于观察上游限流风险。
+var tokenRequests = metrics.NewCounter(
+	"anyhub_bearer_token_requests_total",
+	"Bearer token requests sent to upstream auth realms after cache misses.",
+)
+
+// tokenKey 标识一个 token 来源：同一 Hub、realm 与 service 下的 token 可按 scope 互相复用。
+type tokenKey struct {
+	hub     string
+	realm   string
+	service string
+}
+
+type cachedToken struct {
+	value   string
+	scopes  scopeSet
+	expires time.Time
+}
+
+// challengeKey 标识质询来源：同一 Hub 的多个上游可能使用不同的认证服务。
+type challengeKey struct {
+	hub  string
+	host string
+}
+
+// tokenCache 在进程内缓存 bearer token，并按 Hub 与上游主机记录最近一次质询的 realm/service，
+// 使后续请求可以在收到 401 之前直接携带覆盖所需 scope 的 token。
+type tokenCache struct {
+	mu         sync.Mutex
+	tokens     map[tokenKey][]cachedToken
+	challenges map[challengeKey]bearerChallenge
+	now        func() time.Time
+}
+
+func (c *tokenCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func keyFor(hub string, challenge bearerChallenge) tokenKey {
+	return tokenKey{hub: hub, realm: challenge.Realm, service: challenge.Service}
+}
+
+// remember 记录 Hub 的某个上游主机最近一次质询的 realm 与 service。
+func (c *tokenCache) remember(hub, host string, challenge bearerChallenge) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.challenges == nil {
+		c.challenges = make(map[challengeKey]bearerChallenge)
+	}
+	c.challenges[challengeKey{hub: hub, host: host}] = bearerChallenge{Realm: challenge.Realm, Service: challenge.Service}
+}
+
+// challengeFor 用该上游主机最近一次质询的 realm/service 与给定 scope 组成预期质询；尚未被质询过时返回 false。
+func (c *tokenCache) challengeFor(hub, host, scope string) (bearerChallenge, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	challenge, ok := c.challenges[challengeKey{hub: hub, host: host}]
+	if !ok {
+		return bearerChallenge{}, false
+	}
+	challenge.Scope = scope
+	return challenge, true
+}
+
+// lookup 返回未过期且 scope 覆盖质询要求的 token。
+func (c *tokenCache) lookup(hub string, challenge bearerChallenge) (string, bool) {
+	required := parseScopes(challenge.Scope)
+	now := c.clock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, token := range c.tokens[keyFor(hub, challenge)] {
+		if now.Before(token.expires) && token.scopes.covers(required) {
+			return token.value, true
+		}
+	}
+	return "", false
+}
+
+// store 缓存 token 直到 expires。
+func (c *tokenCache) store(hub string, challenge bearerChallenge, value string, expires time.Time) {
+	now := c.clock()
+	if !now.Before(expires) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = make(map[tokenKey][]cachedToken)
+	}
+	if c.size() >= maxCachedTokens {
+		c.prune(now)
+	}
+	key := keyFor(hub, challenge)
+	c.tokens[key] = append(c.tokens[key], cachedToken{value: value, scopes: parseScopes(challenge.Scope), expires: expires})
+}
+
+// invalidate 丢弃被上游拒绝的 token（例如 scope 不足或已被吊销）。
+func (c *tokenCache) invalidate(hub string, challenge bearerChallenge, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := keyFor(hub, challenge)
+	tokens := c.tokens[key]
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if token.value != value {
+			kept = append(kept, token)
+		}
+	}
+	if len(kept) == 0 {
+		delete(c.tokens, key)
+		return
+	}
+	c.tokens[key] = kept
+}
+
+func (c *tokenCache) size() int {
+	total := 0
+	for _, tokens := range c.tokens {
+		total += len(tokens)
+	}
+	return total
+}
+
+// prune 在持锁状态下清理过期 token；仍超出上限时丢弃最早过期的一个。
+func (c *tokenCache) prune(now time.Time) {
+	var (
+		oldestKey tokenKey
+		oldestIdx = -1
+		oldestAt  time.Time
+	)
+	for key, tokens := range c.tokens {
+		kept := tokens[:0]
+		for _, token := range tokens {
+			if now.Before(token.expires) {
+				kept = append(kept, token)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.tokens, key)
+			continue
+		}
+		c.tokens[key] = kept
+		for i, token := range kept {
+			if oldestIdx < 0 || token.expires.Before(oldestAt) {
+				oldestKey, oldestIdx, oldestAt = key, i, token.expires
+			}
+		}
+	}
+	if c.size() < maxCachedTokens || oldestIdx < 0 {
+		return
+	}
+	tokens := c.tokens[oldestKey]
+	c.tokens[oldestKey] = append(tokens[:oldestIdx], tokens[oldestIdx+1:]...)
+	if len(c.tokens[oldestKey]) == 0 {
+		delete(c.tokens, oldestKey)
+	}
+}
+
+// tokenExpiry 根据 token 响应的 issued_at 与 expires_in 计算本地过期时间。issued_at 缺失、
+// 无法解析或与本地时钟偏差过大时以收到响应的时间为准。
+func tokenExpiry(received time.Time, issuedAt string, expiresIn int) time.Time {
+	lifetime := defaultTokenLifetime
+	if expiresIn > 0 {
+		lifetime = time.Duration(expiresIn) * time.Second
+	}
+	issued := received
+	if parsed, err := time.Parse(time.RFC3339, issuedAt); err == nil && !parsed.After(received) && received.Sub(parsed) < lifetime {
+		issued = parsed
+	}
+	margin := min(tokenExpiryMargin, lifetime/2)
+	return issued.Add(lifetime - margin)
+}
+
+// scopeSet 将 "repository:library/nginx:pull,push" 形式的 scope 解析为 资源 → 动作集合。
+type scopeSet map[string]map[string]struct{}
+
+// parseScopes 解析以空格分隔的多个 scope；资源名可能包含冒号（如 host:port/repo），动作取最后一个冒号之后的部分。
+func parseScopes(raw string) scopeSet {
+	set := make(scopeSet)
+	for _, scope := range strings.Fields(raw) {
+		idx := strings.LastIndexByte(scope, ':')
+		if idx <= 0 {
+			set[scope] = map[string]struct{}{}
+			continue
+		}
+		resource, actions := scope[:idx], scope[idx+1:]
+		if set[resource] == nil {
+			set[resource] = map[string]struct{}{}
+		}
+		for action := range strings.SplitSeq(actions, ",") {
+			if action != "" {
+				set[resource][action] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+// covers 判断当前集合是否包含 required 中的全部资源与动作。
+func (s scopeSet) covers(required scopeSet) bool {
+	for resource, actions := range required {
+		granted, ok := s[resource]
+		if !ok {
+			return false
+		}
+		for action := range actions {
+			if _, ok := granted[action]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bearerAuth 为上游 resp 的质询返回 Authorization 值：优先复用缓存中覆盖所需 scope 的 token，否则向 realm 申请并缓存。
+// 刚被上游拒绝的 token（scope 不足或已失效）会先从缓存中移除，随后按质询要求的 scope 重新申请。
+func (h *Handler) bearerAuth(c fiber.Ctx, route *server.HubRoute, challenge bearerChallenge, resp *http.Response) (string, error) {
+	hub := route.Config.Name
+	h.tokens.remember(hub, requestHost(resp), challenge)
+	rejected := sentBearerToken(resp)
+	if rejected != "" {
+		h.tokens.invalidate(hub, challenge, rejected)
+	}
+	if token, ok := h.tokens.lookup(hub, challenge); ok {
+		return "Bearer " + token, nil
+	}
+	token, expires, err := h.fetchBearerToken(c, challenge, route)
+	if err != nil {
+		return "", err
+	}
+	h.tokens.store(hub, challenge, token, expires)
+	return "Bearer " + token, nil
+}
+
+// upstreamAuth 返回发往某个上游主机的请求应携带的 Authorization 覆盖值；nil 或空串表示沿用 Basic 凭证。
+type upstreamAuth func(host string) string
+
+// fixedAuth 对所有上游使用同一个 Authorization 值。
+func fixedAuth(value string) upstreamAuth {
+	return func(string) string { return value }
+}
+
+func (a upstreamAuth) header(host string) string {
+	if a == nil {
+		return ""
+	}
+	return a(host)
+}
+
+// cachedAuth 在收到质询之前为请求挑选已缓存的 token。仅对配置了凭证、已被质询过的上游主机生效，
+// scope 由 docker 模块按仓库路径推断；无可用 token 时返回空串，沿用 Basic 凭证。
+func (h *Handler) cachedAuth(route *server.HubRoute, hook *hookState) upstreamAuth {
+	if route == nil || hook == nil || !route.Config.HasCredentials() {
+		return nil
+	}
+	scope, ok := dockermodule.PullScope(hook.clean)
+	if !ok {
+		return nil
+	}
+	hub := route.Config.Name
+	return func(host string) string {
+		challenge, ok := h.tokens.challengeFor(hub, host, scope)
+		if !ok {
+			return ""
+		}
+		if token, ok := h.tokens.lookup(hub, challenge); ok {
+			return "Bearer " + token
+		}
+		return ""
+	}
+}
+
+// requestHost 返回上游响应对应请求的主机。
+func requestHost(resp *http.Response) string {
+	if resp == nil || resp.Request == nil || resp.Request.URL == nil {
+		return ""
+	}
+	return resp.Request.URL.Host
+}
+
+// sentBearerToken 返回上游响应对应请求携带的 bearer token。
+func sentBearerToken(resp *http.Response) string {
+	if resp == nil || resp.Request == nil {
+		return ""
+	}
+	value := resp.Request.Header.Get("Authorization")
+	if len(value) < len("Bearer ") || !strings.EqualFold(value[:len("Bearer ")], "Bearer ") {
+		return ""
+	}
+	return value[len("Bearer "):]
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScopeSetCovers(t *testing.T) {
+	granted := parseScopes("repository:library/nginx:pull,push repository:registry.local:5000/app:pull")
+	cases := []struct {
+		required string
+		want     bool
+	}{
+		{"repository:library/nginx:pull", true},
+		{"repository:library/nginx:pull,push", true},
+		{"repository:registry.local:5000/app:pull", true},
+		{"repository:library/nginx:delete", false},
+		{"repository:library/nginx:pull repository:library/redis:pull", false},
+		{"", true},
+	}
+	for _, tc := range cases {
+		if got := granted.covers(parseScopes(tc.required)); got != tc.want {
+			t.Fatalf("covers(%q) = %v, want %v", tc.required, got, tc.want)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	received := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	if got := tokenExpiry(received, "", 0); !got.Equal(received.Add(defaultTokenLifetime - tokenExpiryMargin)) {
+		t.Fatalf("missing expires_in should use the default lifetime, got %s", got)
+	}
+	issued := received.Add(-30 * time.Second).Format(time.RFC3339)
+	if got := tokenExpiry(received, issued, 300); !got.Equal(received.Add(260 * time.Second)) {
+		t.Fatalf("expiry should count from issued_at, got %s", got)
+	}
+	future := received.Add(time.Hour).Format(time.RFC3339)
+	if got := tokenExpiry(received, future, 300); !got.Equal(received.Add(290 * time.Second)) {
+		t.Fatalf("issued_at ahead of the local clock should be ignored, got %s", got)
+	}
+	if got := tokenExpiry(received, "", 4); !got.Equal(received.Add(2 * time.Second)) {
+		t.Fatalf("margin must not exceed half the lifetime, got %s", got)
+	}
+}
+
+func TestTokenCacheLookupAndInvalidate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := tokenCache{now: func() time.Time { return now }}
+	challenge := bearerChallenge{Realm: "https://auth.example/token", Service: "registry", Scope: "repository:a:pull repository:b:pull"}
+
+	if _, ok := cache.challengeFor("docker", "registry.example", "repository:a:pull"); ok {
+		t.Fatalf("hub without a recorded challenge must not yield one")
+	}
+	cache.remember("docker", "registry.example", challenge)
+	cache.store("docker", challenge, "tok", now.Add(time.Minute))
+
+	narrow, ok := cache.challengeFor("docker", "registry.example", "repository:b:pull")
+	if !ok {
+		t.Fatalf("expected recorded realm for hub")
+	}
+	if _, ok := cache.challengeFor("docker", "mirror.example", "repository:b:pull"); ok {
+		t.Fatalf("challenges must not leak across upstream hosts of a hub")
+	}
+	if token, ok := cache.lookup("docker", narrow); !ok || token != "tok" {
+		t.Fatalf("token covering the scope should be reused, got %q ok=%v", token, ok)
+	}
+	if _, ok := cache.lookup("other", narrow); ok {
+		t.Fatalf("tokens must not leak across hubs")
+	}
+	if _, ok := cache.lookup("docker", bearerChallenge{Realm: challenge.Realm, Service: challenge.Service, Scope: "repository:c:pull"}); ok {
+		t.Fatalf("token must not satisfy scopes it was not issued for")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.lookup("docker", narrow); ok {
+		t.Fatalf("expired token must not be returned")
+	}
+
+	cache.store("docker", challenge, "fresh", now.Add(time.Minute))
+	cache.invalidate("docker", challenge, "fresh")
+	if _, ok := cache.lookup("docker", narrow); ok {
+		t.Fatalf("invalidated token must not be returned")
+	}
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+// variantMarker 分隔定位路径与变体键，位于 /__qs/ 之后。
+const variantMarker = "/__vary/"
+
+// variantKeyLength 为变体键保留的十六进制字符数。
+const variantKeyLength = 16
+
+// variantHeaders 返回模块为该路径声明的、会改变响应表示的请求头。
+func variantHeaders(hook *hookState, locator cache.Locator) []string {
+	if hook == nil || !hook.hasHooks || hook.def.Vary == nil {
+		return nil
+	}
+	return hook.def.Vary(hook.ctx, stripQueryMarker(locator.Path))
+}
+
+// variantKey 根据声明的请求头计算变体键；所有请求头均为空时返回空串，沿用未区分变体的条目。
+func variantKey(names []string, get func(string) string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	canonical := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical = append(canonical, http.CanonicalHeaderKey(strings.TrimSpace(name)))
+	}
+	slices.Sort(canonical)
+	canonical = slices.Compact(canonical)
+
+	var (
+		builder strings.Builder
+		present bool
+	)
+	for _, name := range canonical {
+		value := normalizeHeaderValue(get(name))
+		present = present || value != ""
+		builder.WriteString(name)
+		builder.WriteByte('=')
+		builder.WriteString(value)
+		builder.WriteByte('\n')
+	}
+	if !present {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(builder.String()))
+	return hex.EncodeToString(sum[:])[:variantKeyLength]
+}
+
+// headerGetter 将 fiber 请求头包装为 variantKey/variantMatches 使用的取值函数。
+func headerGetter(c fiber.Ctx) func(string) string {
+	return func(name string) string {
+		return c.Get(name)
+	}
+}
+
+// withVariant 将变体键追加到定位路径。
+func withVariant(locator cache.Locator, key string) cache.Locator {
+	if key != "" {
+		locator.Path += variantMarker + key
+	}
+	return locator
+}
+
+// normalizeHeaderValue 将逗号分隔的头部取值去空白、转小写并排序，使顺序不同的同义 Accept 得到相同结果。
+func normalizeHeaderValue(value string) string {
+	var parts []string
+	for part := range strings.SplitSeq(value, ",") {
+		part = strings.ToLower(strings.Join(strings.Fields(part), ""))
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// varyFields 解析上游 Vary 头中的请求头名称。Accept-Encoding 被忽略：回源时总是去掉该头，
+// 上游返回的表示与客户端的取值无关。
+func varyFields(header http.Header) (fields []string, wildcard bool) {
+	for _, value := range header.Values("Vary") {
+		for field := range strings.SplitSeq(value, ",") {
+			field = strings.TrimSpace(field)
+			switch {
+			case field == "":
+			case field == "*":
+				wildcard = true
+			case strings.EqualFold(field, "Accept-Encoding"):
+			default:
+				fields = append(fields, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+	return fields, wildcard
+}
+
+// varyAll 判断上游以 Vary: * 声明响应不可复用。
+func varyAll(header http.Header) bool {
+	_, wildcard := varyFields(header)
+	return wildcard
+}
+
+// requestVariant 记录写入时请求中被上游 Vary 列出的头部取值（已归一化），命中时与当前请求比对。
+func requestVariant(header http.Header, get func(string) string) map[string]string {
+	fields, _ := varyFields(header)
+	if len(fields) == 0 {
+		return nil
+	}
+	variant := make(map[string]string, len(fields))
+	for _, field := range fields {
+		variant[field] = normalizeHeaderValue(get(field))
+	}
+	return variant
+}
+
+// variantMatches 判断当前请求与条目写入时在上游 Vary 列出的头部上取值一致；不一致时不能复用该条目。
+func variantMatches(entry cache.Entry, get func(string) string) bool {
+	for field, value := range entry.Response.Variant {
+		if normalizeHeaderValue(get(field)) != value {
+			return false
+		}
+	}
+	return true
+}
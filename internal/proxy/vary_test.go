@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/any-hub/any-hub/internal/cache"
+)
+
+func TestVariantKeyNormalizesHeaderValues(t *testing.T) {
+	get := func(values map[string]string) func(string) string {
+		return func(name string) string { return values[name] }
+	}
+	a := variantKey([]string{"accept"}, get(map[string]string{"Accept": "application/json; q=0.8, */*"}))
+	b := variantKey([]string{"Accept", "Accept"}, get(map[string]string{"Accept": "*/*,Application/JSON;q=0.8"}))
+	if a == "" || a != b {
+		t.Fatalf("equivalent Accept values should share a key: %q vs %q", a, b)
+	}
+	if c := variantKey([]string{"Accept"}, get(map[string]string{"Accept": "application/json"})); c == a {
+		t.Fatalf("different Accept values should not share a key")
+	}
+	if key := variantKey([]string{"Accept"}, get(nil)); key != "" {
+		t.Fatalf("absent headers should map to the default variant, got %q", key)
+	}
+
+	locator := withVariant(cache.Locator{HubName: "npm", Path: "/demo/package.json/__qs/abcd"}, a)
+	if stripQueryMarker(locator.Path) != "/demo/package.json" || stripVariantMarker(locator.Path) != "/demo/package.json/__qs/abcd" {
+		t.Fatalf("unexpected marker handling for %q", locator.Path)
+	}
+}
+
+func TestRequestVariantIgnoresAcceptEncoding(t *testing.T) {
+	header := http.Header{"Vary": {"Accept-Encoding, Accept-Language", "origin"}}
+	variant := requestVariant(header, func(name string) string {
+		return map[string]string{"Accept-Language": "EN", "Accept-Encoding": "gzip"}[name]
+	})
+	if len(variant) != 2 || variant["Accept-Language"] != "en" || variant["Origin"] != "" {
+		t.Fatalf("unexpected variant: %v", variant)
+	}
+	entry := cache.Entry{Response: cache.ResponseMetadata{Variant: variant}}
+	if !variantMatches(entry, func(name string) string { return map[string]string{"Accept-Language": "en"}[name] }) {
+		t.Fatalf("same Accept-Language should match")
+	}
+	if variantMatches(entry, func(string) string { return "fr" }) {
+		t.Fatalf("different Accept-Language should not match")
+	}
+	if !varyAll(http.Header{"Vary": {"Accept, *"}}) || varyAll(header) {
+		t.Fatalf("unexpected Vary: * detection")
+	}
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// upstreamFailureThreshold 为连续失败多少次后打开熔断器。
+	upstreamFailureThreshold = 5
+	// upstreamCooldown 为熔断器打开后拒绝请求的时长，期满后放行一个探测请求（half-open）。
+	upstreamCooldown = 30 * time.Second
+	// breakerWindow 为统计错误率的最近请求数。
+	breakerWindow = 20
+	// breakerMinRequests 为按错误率判断前窗口内至少需要的请求数，避免少量样本误判。
+	breakerMinRequests = 10
+	// breakerErrorRate 为窗口内错误率达到多少时打开熔断器。
+	breakerErrorRate = 0.5
+)
+
+// BreakerState 为上游熔断器的状态。
+type BreakerState string
+
+const (
+	// BreakerClosed 正常放行请求并统计失败。
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen 在冷却期内直接拒绝请求。
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen 冷却结束后只放行一个探测请求，成功则关闭，失败则重新打开。
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerTransition 描述一次状态变化，From 与 To 相同表示未变化。
+type BreakerTransition struct {
+	From BreakerState
+	To   BreakerState
+}
+
+// Changed 判断是否发生了状态变化。
+func (t BreakerTransition) Changed() bool {
+	return t.From != t.To
+}
+
+// UpstreamStatus 为上游熔断器状态的快照，供诊断接口输出。
+type UpstreamStatus struct {
+	// Healthy 表示上游当前可以接收请求（关闭状态，或冷却期满可以探测）。
+	Healthy             bool
+	State               BreakerState
+	ConsecutiveFailures int
+	// ErrorRate 为最近 breakerWindow 次请求中的失败比例。
+	ErrorRate float64
+	OpenUntil time.Time
+}
+
+// breaker 是单个上游的熔断器：连续失败达到阈值，或最近窗口内错误率过高时打开。
+type breaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	outcomes  [breakerWindow]bool
+	next      int
+	samples   int
+	failed    int
+	openUntil time.Time
+	probing   bool
+}
+
+// Allow 判断本次请求能否发往该上游，并在冷却期满时转入 half-open、占用唯一的探测名额。
+// 获准的请求之后必须调用 MarkSuccess、MarkFailure 或 Release 之一。
+func (u *Upstream) Allow(now time.Time) (bool, BreakerTransition) {
+	if u == nil || u.breaker == nil {
+		return true, BreakerTransition{}
+	}
+	b := u.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from := b.state
+	switch b.state {
+	case BreakerOpen:
+		if now.Before(b.openUntil) {
+			return false, BreakerTransition{From: from, To: from}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, BreakerTransition{From: from, To: b.state}
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, BreakerTransition{From: from, To: from}
+		}
+		b.probing = true
+	}
+	return true, BreakerTransition{From: from, To: b.state}
+}
+
+// Healthy 判断上游当前是否可以参与正常选择，不占用探测名额。
+func (u *Upstream) Healthy(now time.Time) bool {
+	if u == nil || u.breaker == nil {
+		return true
+	}
+	b := u.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.available(now)
+}
+
+// MarkFailure 记录一次失败：关闭状态下达到阈值或错误率时打开熔断器，探测失败时重新打开。
+func (u *Upstream) MarkFailure(now time.Time) BreakerTransition {
+	if u == nil || u.breaker == nil {
+		return BreakerTransition{}
+	}
+	b := u.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from := b.state
+	switch b.state {
+	case BreakerClosed:
+		b.failures++
+		b.record(true)
+		if b.failures >= upstreamFailureThreshold ||
+			b.samples >= breakerMinRequests && float64(b.failed) >= breakerErrorRate*float64(b.samples) {
+			b.open(now)
+		}
+	case BreakerHalfOpen:
+		b.failures++
+		b.open(now)
+	}
+	return BreakerTransition{From: from, To: b.state}
+}
+
+// MarkSuccess 记录一次成功；探测成功时关闭熔断器并清空统计。
+func (u *Upstream) MarkSuccess() BreakerTransition {
+	if u == nil || u.breaker == nil {
+		return BreakerTransition{}
+	}
+	b := u.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from := b.state
+	switch b.state {
+	case BreakerClosed:
+		b.failures = 0
+		b.record(false)
+	case BreakerHalfOpen:
+		b.reset()
+	}
+	return BreakerTransition{From: from, To: b.state}
+}
+
+// Release 归还未得出结果的探测名额（例如客户端取消了请求）。
+func (u *Upstream) Release() {
+	if u == nil || u.breaker == nil {
+		return
+	}
+	u.breaker.mu.Lock()
+	u.breaker.probing = false
+	u.breaker.mu.Unlock()
+}
+
+// Status 返回当前熔断器状态快照。
+func (u *Upstream) Status(now time.Time) UpstreamStatus {
+	if u == nil || u.breaker == nil {
+		return UpstreamStatus{Healthy: true, State: BreakerClosed}
+	}
+	b := u.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := UpstreamStatus{
+		Healthy:             b.available(now),
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		OpenUntil:           b.openUntil,
+	}
+	if b.samples > 0 {
+		status.ErrorRate = float64(b.failed) / float64(b.samples)
+	}
+	return status
+}
+
+func (b *breaker) available(now time.Time) bool {
+	switch b.state {
+	case BreakerOpen:
+		return !now.Before(b.openUntil)
+	case BreakerHalfOpen:
+		return !b.probing
+	}
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	if b.samples == breakerWindow {
+		if b.outcomes[b.next] {
+			b.failed--
+		}
+	} else {
+		b.samples++
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failed++
+	}
+	b.next = (b.next + 1) % breakerWindow
+}
+
+func (b *breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openUntil = now.Add(upstreamCooldown)
+	b.probing = false
+}
+
+func (b *breaker) reset() {
+	b.state = BreakerClosed
+	b.failures = 0
+	b.outcomes = [breakerWindow]bool{}
+	b.next, b.samples, b.failed = 0, 0, 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestUpstream() *Upstream {
+	return &Upstream{breaker: &breaker{state: BreakerClosed}}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	upstream := newTestUpstream()
+	now := time.Now()
+	for range upstreamFailureThreshold {
+		upstream.MarkFailure(now)
+	}
+	if allowed, _ := upstream.Allow(now); allowed {
+		t.Fatalf("open breaker must reject requests during the cooldown")
+	}
+
+	later := now.Add(upstreamCooldown)
+	allowed, change := upstream.Allow(later)
+	if !allowed || change.From != BreakerOpen || change.To != BreakerHalfOpen {
+		t.Fatalf("expected a half-open probe after the cooldown, got allowed=%v %+v", allowed, change)
+	}
+	if allowed, _ := upstream.Allow(later); allowed {
+		t.Fatalf("half-open breaker must admit a single probe")
+	}
+	if change := upstream.MarkFailure(later); change.To != BreakerOpen {
+		t.Fatalf("failed probe should reopen the breaker, got %+v", change)
+	}
+	if status := upstream.Status(later); status.OpenUntil != later.Add(upstreamCooldown) {
+		t.Fatalf("reopened breaker should start a new cooldown, got %s", status.OpenUntil)
+	}
+
+	again := later.Add(upstreamCooldown)
+	if allowed, _ := upstream.Allow(again); !allowed {
+		t.Fatalf("expected another probe after the second cooldown")
+	}
+	upstream.Release()
+	if allowed, _ := upstream.Allow(again); !allowed {
+		t.Fatalf("released probe slot should be available again")
+	}
+	if change := upstream.MarkSuccess(); change.To != BreakerClosed {
+		t.Fatalf("successful probe should close the breaker, got %+v", change)
+	}
+	if status := upstream.Status(again); !status.Healthy || status.ConsecutiveFailures != 0 || status.ErrorRate != 0 {
+		t.Fatalf("closing should reset statistics: %+v", status)
+	}
+}
+
+func TestBreakerOpensOnErrorRate(t *testing.T) {
+	upstream := newTestUpstream()
+	now := time.Now()
+	// 交替成功与失败：连续失败从未达到阈值，但窗口内错误率为 50%。
+	for i := range breakerMinRequests - 1 {
+		if i%2 == 0 {
+			upstream.MarkFailure(now)
+		} else {
+			upstream.MarkSuccess()
+		}
+	}
+	if status := upstream.Status(now); status.State != BreakerClosed {
+		t.Fatalf("breaker must wait for %d samples before judging the error rate: %+v", breakerMinRequests, status)
+	}
+	upstream.MarkSuccess()
+	if change := upstream.MarkFailure(now); change.To != BreakerOpen {
+		t.Fatalf("breaker should open once the error rate reaches %.0f%%: %+v", breakerErrorRate*100, upstream.Status(now))
+	}
+}
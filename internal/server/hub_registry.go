@@ -22,9 +22,19 @@ type HubRoute struct {
 	ListenPort int
 	// CacheTTL 是对当前 Hub 生效的 TTL，若 Hub 未覆盖则等于全局值。
 	CacheTTL time.Duration
-	// UpstreamURL/ProxyURL 在构造 Registry 时提前解析完成，便于后续请求快速复用。
-	UpstreamURL *url.URL
-	ProxyURL    *url.URL
+	// StaleIfError 为上游失败时允许回放缓存副本的窗口，0 表示关闭。
+	StaleIfError time.Duration
+	// NegativeCacheTTL 为上游 404/410 结果的缓存时长，0 表示不缓存。
+	NegativeCacheTTL time.Duration
+	// StaleWhileRevalidate 为先返回缓存、后台再验证的窗口，超出窗口的条目仍同步再验证；0 表示关闭。
+	StaleWhileRevalidate time.Duration
+	// MaxRetries/InitialBackoff 为幂等回源请求的重试次数与初始退避，Hub 未覆盖时等于全局值。
+	MaxRetries     int
+	InitialBackoff time.Duration
+	// Upstreams/ProxyURL 在构造 Registry 时提前解析完成，便于后续请求快速复用；
+	// Upstreams 按配置顺序排列，至少包含一个上游。
+	Upstreams []*Upstream
+	ProxyURL  *url.URL
 	// Module 记录当前 hub 选用的模块元数据，便于日志与观测。
 	Module hubmodule.ModuleMetadata
 	// CacheStrategy 代表模块默认策略与 hub 覆盖后的最终结果。
@@ -111,9 +121,9 @@ func buildHubRoute(cfg *config.Config, hub config.HubConfig) (*HubRoute, error)
 		return nil, fmt.Errorf("hub %s: %w", hub.Name, err)
 	}
 
-	upstreamURL, err := url.Parse(hub.Upstream)
+	upstreams, err := buildUpstreams(hub)
 	if err != nil {
-		return nil, fmt.Errorf("invalid upstream for hub %s: %w", hub.Name, err)
+		return nil, err
 	}
 
 	var proxyURL *url.URL
@@ -125,16 +135,22 @@ func buildHubRoute(cfg *config.Config, hub config.HubConfig) (*HubRoute, error)
 	}
 
 	effectiveTTL := cfg.EffectiveCacheTTL(hub)
+	maxRetries, initialBackoff := cfg.EffectiveRetries(hub)
 	runtime := config.BuildHubRuntime(hub, meta, effectiveTTL)
 
 	return &HubRoute{
-		Config:        hub,
-		ListenPort:    cfg.Global.ListenPort,
-		CacheTTL:      effectiveTTL,
-		UpstreamURL:   upstreamURL,
-		ProxyURL:      proxyURL,
-		Module:        runtime.Module,
-		CacheStrategy: runtime.CacheStrategy,
+		Config:               hub,
+		ListenPort:           cfg.Global.ListenPort,
+		CacheTTL:             effectiveTTL,
+		StaleIfError:         hub.StaleIfError.DurationValue(),
+		NegativeCacheTTL:     hub.NegativeCacheTTL.DurationValue(),
+		StaleWhileRevalidate: hub.StaleWhileRevalidate.DurationValue(),
+		MaxRetries:           maxRetries,
+		InitialBackoff:       initialBackoff,
+		Upstreams:            upstreams,
+		ProxyURL:             proxyURL,
+		Module:               runtime.Module,
+		CacheStrategy:        runtime.CacheStrategy,
 	}, nil
 }
 
@@ -57,8 +57,8 @@ func TestHubRegistryLookupByHost(t *testing.T) {
 		t.Fatalf("expected docker module, got %s", route.Module.Key)
 	}
 
-	if route.UpstreamURL.String() != "https://registry-1.docker.io" {
-		t.Errorf("unexpected upstream URL: %s", route.UpstreamURL)
+	if route.PrimaryUpstream().String() != "https://registry-1.docker.io" {
+		t.Errorf("unexpected upstream URL: %s", route.PrimaryUpstream())
 	}
 
 	if route.ProxyURL != nil {
@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	// cacheRoutePrefix 为缓存管理接口的路由前缀。
+	cacheRoutePrefix = "/-/cache"
+	// defaultListLimit/maxListLimit 限制单次列举返回的条目数。
+	defaultListLimit = 1000
+	maxListLimit     = 10000
+)
+
+// CacheRouteOptions 为 /-/cache 管理接口的依赖。
+type CacheRouteOptions struct {
+	Registry *server.HubRegistry
+	Store    cache.Store
+	Logger   *logrus.Logger
+	// Token 为空时不注册接口。
+	Token string
+	// Retention 中 Pinned 规则匹配的条目不会被清除。
+	Retention *cache.RetentionPolicy
+}
+
+// RegisterCacheRoutes 暴露 /-/cache 管理接口：列举、查看、固定与清除缓存条目。所有请求需携带
+// Authorization: Bearer <Token>；清除经 cache.Store.Remove 执行，遵循条目锁，跳过固定的条目，并输出 action=cache_purge 日志。
+func RegisterCacheRoutes(app *fiber.App, opts CacheRouteOptions) {
+	if app == nil || opts.Registry == nil || opts.Store == nil || opts.Logger == nil || opts.Token == "" {
+		return
+	}
+	api := &cacheAPI{CacheRouteOptions: opts}
+
+	group := app.Group(cacheRoutePrefix, api.authorize)
+	group.Get("/:hub", api.list)
+	group.Get("/:hub/*", api.stat)
+	group.Patch("/:hub/*", api.pin)
+	group.Delete("/:hub", api.purge)
+	group.Delete("/:hub/*", api.purge)
+}
+
+type cacheAPI struct {
+	CacheRouteOptions
+}
+
+type cacheEntryPayload struct {
+	Path      string    `json:"path"`
+	SizeBytes int64     `json:"size_bytes"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+type cacheEntryDetailPayload struct {
+	Hub                   string                 `json:"hub"`
+	Path                  string                 `json:"path"`
+	SizeBytes             int64                  `json:"size_bytes"`
+	ModTime               time.Time              `json:"mod_time"`
+	LastValidated         time.Time              `json:"last_validated"`
+	Digest                string                 `json:"digest,omitempty"`
+	EffectiveUpstreamPath string                 `json:"effective_upstream_path,omitempty"`
+	Negative              bool                   `json:"negative,omitempty"`
+	Pinned                bool                   `json:"pinned,omitempty"`
+	Response              cache.ResponseMetadata `json:"response"`
+}
+
+type pinRequest struct {
+	Pinned *bool `json:"pinned"`
+}
+
+func (a *cacheAPI) authorize(c fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.Token)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	return c.Next()
+}
+
+// list 处理 GET /-/cache/<hub>?prefix=&limit=。
+func (a *cacheAPI) list(c fiber.Ctx) error {
+	hub, ok := a.hub(c)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "hub_not_found"})
+	}
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_limit"})
+		}
+		limit = min(parsed, maxListLimit)
+	}
+	prefix := c.Query("prefix")
+
+	entries := []cacheEntryPayload{}
+	var totalBytes int64
+	truncated := false
+	err := cache.WalkEntries(c.Context(), a.Store, hub, func(info cache.EntryInfo) error {
+		if !strings.HasPrefix(info.Locator.Path, prefix) {
+			return nil
+		}
+		if len(entries) == limit {
+			truncated = true
+			return errStopWalk
+		}
+		entries = append(entries, cacheEntryPayload{Path: info.Locator.Path, SizeBytes: info.SizeBytes, ModTime: info.ModTime})
+		totalBytes += info.SizeBytes
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return a.walkError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"hub":         hub,
+		"prefix":      prefix,
+		"entries":     entries,
+		"total_bytes": totalBytes,
+		"truncated":   truncated,
+	})
+}
+
+// stat 处理 GET /-/cache/<hub>/<path>，返回条目及其持久化的响应元数据。
+func (a *cacheAPI) stat(c fiber.Ctx) error {
+	hub, ok := a.hub(c)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "hub_not_found"})
+	}
+	entryPath, err := wildcardPath(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_path"})
+	}
+	result, err := a.Store.Get(c.Context(), cache.Locator{HubName: hub, Path: entryPath})
+	if errors.Is(err, cache.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "entry_not_found"})
+	}
+	if err != nil {
+		return err
+	}
+	result.Reader.Close()
+	entry := result.Entry
+	return c.JSON(cacheEntryDetailPayload{
+		Hub:                   hub,
+		Path:                  entryPath,
+		SizeBytes:             entry.SizeBytes,
+		ModTime:               entry.ModTime,
+		LastValidated:         entry.LastValidated(),
+		Digest:                entry.Digest,
+		EffectiveUpstreamPath: entry.EffectiveUpstreamPath,
+		Negative:              entry.Negative(),
+		Pinned:                entry.Pinned || a.Retention.Pinned(entry.Locator),
+		Response:              entry.Response,
+	})
+}
+
+// pin 处理 PATCH /-/cache/<hub>/<path>，请求体 {"pinned": true|false} 固定或取消固定条目。
+// 由 Pinned 保留规则保护的条目取消固定后仍受规则保护。
+func (a *cacheAPI) pin(c fiber.Ctx) error {
+	hub, ok := a.hub(c)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "hub_not_found"})
+	}
+	entryPath, err := wildcardPath(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_path"})
+	}
+	var req pinRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil || req.Pinned == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_body"})
+	}
+	pinner, ok := a.Store.(cache.Pinner)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "pinning_unsupported"})
+	}
+	locator := cache.Locator{HubName: hub, Path: entryPath}
+	switch err := pinner.Pin(c.Context(), locator, *req.Pinned); {
+	case errors.Is(err, cache.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "entry_not_found"})
+	case errors.Is(err, cache.ErrPinUnsupported):
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "pinning_unsupported"})
+	case err != nil:
+		return err
+	}
+
+	fields := logrus.Fields{
+		"action": "cache_pin",
+		"hub":    hub,
+		"path":   entryPath,
+		"pinned": *req.Pinned,
+		"remote": c.IP(),
+	}
+	if requestID := server.RequestID(c); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	a.Logger.WithFields(fields).Info("cache_pin")
+	return c.JSON(fiber.Map{
+		"hub":         hub,
+		"path":        entryPath,
+		"pinned":      *req.Pinned,
+		"rule_pinned": a.Retention.Pinned(locator),
+	})
+}
+
+// purge 处理 DELETE /-/cache/<hub>/<path>（精确路径）与 DELETE /-/cache/<hub>?prefix= 或 ?glob=。
+func (a *cacheAPI) purge(c fiber.Ctx) error {
+	hub, ok := a.hub(c)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "hub_not_found"})
+	}
+
+	var (
+		mode   string
+		target string
+		match  func(string) bool
+	)
+	if c.Params("*") != "" {
+		entryPath, err := wildcardPath(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_path"})
+		}
+		mode, target = "exact", entryPath
+	} else {
+		prefix, glob := c.Query("prefix"), c.Query("glob")
+		switch {
+		case prefix != "" && glob == "":
+			mode, target = "prefix", prefix
+			match = func(p string) bool { return strings.HasPrefix(p, prefix) }
+		case glob != "" && prefix == "":
+			if _, err := path.Match(glob, ""); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_glob"})
+			}
+			mode, target = "glob", glob
+			match = func(p string) bool {
+				matched, _ := path.Match(glob, p)
+				return matched
+			}
+		default:
+			// 不允许无条件清空整个 Hub，避免误操作。
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "purge_target_required"})
+		}
+	}
+
+	var locators []cache.Locator
+	if match == nil {
+		locator := cache.Locator{HubName: hub, Path: target}
+		result, err := a.Store.Get(c.Context(), locator)
+		if errors.Is(err, cache.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "entry_not_found"})
+		}
+		if err != nil {
+			return err
+		}
+		result.Reader.Close()
+		locators = append(locators, locator)
+	} else {
+		// 先收集再删除：索引遍历处于只读事务中，不能在回调内写索引。
+		err := cache.WalkEntries(c.Context(), a.Store, hub, func(info cache.EntryInfo) error {
+			if match(info.Locator.Path) {
+				locators = append(locators, info.Locator)
+			}
+			return nil
+		})
+		if err != nil {
+			return a.walkError(c, err)
+		}
+	}
+
+	removed := make([]string, 0, len(locators))
+	pinned := []string{}
+	var purgeErr error
+	for _, locator := range locators {
+		// 固定状态在条目锁内检查，避免与并发的 pin 请求交错。
+		if err := cache.RemoveUnpinned(c.Context(), a.Store, a.Retention, locator); err != nil {
+			if errors.Is(err, cache.ErrPinned) {
+				if match == nil {
+					return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "entry_pinned"})
+				}
+				pinned = append(pinned, locator.Path)
+				continue
+			}
+			purgeErr = err
+			break
+		}
+		removed = append(removed, locator.Path)
+	}
+
+	fields := logrus.Fields{
+		"action":  "cache_purge",
+		"hub":     hub,
+		"mode":    mode,
+		"target":  target,
+		"removed": len(removed),
+		"pinned":  len(pinned),
+		"remote":  c.IP(),
+	}
+	if requestID := server.RequestID(c); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	if purgeErr != nil {
+		a.Logger.WithFields(fields).WithError(purgeErr).Error("cache_purge_failed")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "purge_failed", "removed": removed})
+	}
+	a.Logger.WithFields(fields).Info("cache_purge")
+	return c.JSON(fiber.Map{"hub": hub, "mode": mode, "removed": removed, "pinned": pinned})
+}
+
+func (a *cacheAPI) hub(c fiber.Ctx) (string, bool) {
+	name := c.Params("hub")
+	for _, route := range a.Registry.List() {
+		if route.Config.Name == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (a *cacheAPI) walkError(c fiber.Ctx, err error) error {
+	if errors.Is(err, cache.ErrWalkUnsupported) {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "listing_unsupported"})
+	}
+	return err
+}
+
+// errStopWalk 用于列举达到上限时提前结束遍历。
+var errStopWalk = errors.New("stop walk")
+
+// wildcardPath 将通配段还原为缓存定位路径（以 / 开头、已解码）。通配段取自原始请求路径并只解码一次：
+// Params 的取值是否已解码取决于 Fiber 的配置，在其基础上再解码会把 %2525 误还原为 %。
+func wildcardPath(c fiber.Ctx) (string, error) {
+	rest := strings.TrimPrefix(string(c.Request().URI().PathOriginal()), cacheRoutePrefix+"/")
+	_, raw, _ := strings.Cut(rest, "/")
+	decoded, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", err
+	}
+	return "/" + strings.TrimPrefix(decoded, "/"), nil
+}
@@ -0,0 +1,241 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const testAdminToken = "s3cret-admin-token"
+
+func TestCacheRoutesRequireToken(t *testing.T) {
+	app, _, _ := newCacheTestApp(t)
+	for _, auth := range []string{"", "Bearer wrong"} {
+		resp := doCacheRequest(t, app, http.MethodGet, "/-/cache/npm", auth)
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("expected 401 for %q, got %d", auth, resp.StatusCode)
+		}
+	}
+}
+
+func TestCacheRoutesListAndStat(t *testing.T) {
+	app, store, _ := newCacheTestApp(t)
+	putCacheEntry(t, store, "/lodash", "packument", cache.ResponseMetadata{ETag: `"l1"`})
+	putCacheEntry(t, store, "/@scope/pkg", "scoped", cache.ResponseMetadata{})
+	putCacheEntry(t, store, "/@scope/other", "other", cache.ResponseMetadata{})
+
+	var listed struct {
+		Entries []cacheEntryPayload `json:"entries"`
+		Total   int64               `json:"total_bytes"`
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodGet, "/-/cache/npm?prefix=/@scope/", "Bearer "+testAdminToken), &listed)
+	if len(listed.Entries) != 2 || listed.Entries[0].Path != "/@scope/other" || listed.Total != int64(len("scoped")+len("other")) {
+		t.Fatalf("unexpected listing: %+v", listed)
+	}
+
+	var detail cacheEntryDetailPayload
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodGet, "/-/cache/npm/lodash", "Bearer "+testAdminToken), &detail)
+	if detail.Path != "/lodash" || detail.SizeBytes != int64(len("packument")) || detail.Response.ETag != `"l1"` {
+		t.Fatalf("unexpected detail: %+v", detail)
+	}
+
+	if resp := doCacheRequest(t, app, http.MethodGet, "/-/cache/npm/missing", "Bearer "+testAdminToken); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 for missing entry, got %d", resp.StatusCode)
+	}
+	if resp := doCacheRequest(t, app, http.MethodGet, "/-/cache/unknown", "Bearer "+testAdminToken); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected 404 for unknown hub, got %d", resp.StatusCode)
+	}
+}
+
+func TestCacheRoutesDecodeEntryPathOnce(t *testing.T) {
+	app, store, _ := newCacheTestApp(t)
+	putCacheEntry(t, store, "/@scope/pkg", "scoped", cache.ResponseMetadata{})
+	putCacheEntry(t, store, "/pkg/%41-1.0.0.tgz", "escaped", cache.ResponseMetadata{})
+	auth := "Bearer " + testAdminToken
+
+	var detail cacheEntryDetailPayload
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodGet, "/-/cache/npm/%40scope%2Fpkg?verbose=1", auth), &detail)
+	if detail.Path != "/@scope/pkg" {
+		t.Fatalf("unexpected detail for encoded path: %+v", detail)
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodGet, "/-/cache/npm/pkg/%2541-1.0.0.tgz", auth), &detail)
+	if detail.Path != "/pkg/%41-1.0.0.tgz" || detail.SizeBytes != int64(len("escaped")) {
+		t.Fatalf("%%25 must be decoded exactly once: %+v", detail)
+	}
+}
+
+func TestCacheRoutesPurge(t *testing.T) {
+	app, store, logs := newCacheTestApp(t)
+	for _, p := range []string{"/lodash", "/@scope/pkg", "/@scope/other", "/react/-/react-18.tgz", "/vue/-/vue-3.tgz"} {
+		putCacheEntry(t, store, p, "body", cache.ResponseMetadata{})
+	}
+	auth := "Bearer " + testAdminToken
+
+	if resp := doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm", auth); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("purge without target should be rejected, got %d", resp.StatusCode)
+	}
+
+	var purged struct {
+		Removed []string `json:"removed"`
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm/lodash", auth), &purged)
+	if len(purged.Removed) != 1 || purged.Removed[0] != "/lodash" {
+		t.Fatalf("unexpected exact purge: %+v", purged)
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm?prefix=/@scope/", auth), &purged)
+	if len(purged.Removed) != 2 {
+		t.Fatalf("unexpected prefix purge: %+v", purged)
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm?glob=/*/-/react-*.tgz", auth), &purged)
+	if len(purged.Removed) != 1 || purged.Removed[0] != "/react/-/react-18.tgz" {
+		t.Fatalf("unexpected glob purge: %+v", purged)
+	}
+
+	for _, p := range []string{"/lodash", "/@scope/pkg", "/react/-/react-18.tgz"} {
+		if _, err := store.Get(context.Background(), cache.Locator{HubName: "npm", Path: p}); err == nil {
+			t.Fatalf("%s should have been purged", p)
+		}
+	}
+	if _, err := store.Get(context.Background(), cache.Locator{HubName: "npm", Path: "/vue/-/vue-3.tgz"}); err != nil {
+		t.Fatalf("unmatched entry should remain: %v", err)
+	}
+	if got := strings.Count(logs.String(), `"action":"cache_purge"`); got != 3 {
+		t.Fatalf("expected 3 cache_purge logs, got %d: %s", got, logs.String())
+	}
+}
+
+func TestCacheRoutesPinProtectsEntriesFromPurge(t *testing.T) {
+	app, store, logs := newCacheTestApp(t)
+	for _, p := range []string{"/react/-/react-17.tgz", "/react/-/react-18.tgz", "/typescript/-/typescript-5.tgz"} {
+		putCacheEntry(t, store, p, "body", cache.ResponseMetadata{})
+	}
+	auth := "Bearer " + testAdminToken
+
+	pin := func(target, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		return resp
+	}
+	if resp := pin("/-/cache/npm/react/-/react-17.tgz", `{}`); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("pin without flag should be rejected, got %d", resp.StatusCode)
+	}
+	if resp := pin("/-/cache/npm/missing", `{"pinned":true}`); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("pinning a missing entry should 404, got %d", resp.StatusCode)
+	}
+	var pinned struct {
+		Pinned bool `json:"pinned"`
+	}
+	decodeCacheResponse(t, pin("/-/cache/npm/react/-/react-17.tgz", `{"pinned":true}`), &pinned)
+	if !pinned.Pinned || !strings.Contains(logs.String(), `"action":"cache_pin"`) {
+		t.Fatalf("unexpected pin response %+v, logs: %s", pinned, logs.String())
+	}
+
+	var detail cacheEntryDetailPayload
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodGet, "/-/cache/npm/react/-/react-17.tgz", auth), &detail)
+	if !detail.Pinned {
+		t.Fatalf("detail should report the pin: %+v", detail)
+	}
+	for _, target := range []string{"/-/cache/npm/react/-/react-17.tgz", "/-/cache/npm/typescript/-/typescript-5.tgz"} {
+		if resp := doCacheRequest(t, app, http.MethodDelete, target, auth); resp.StatusCode != fiber.StatusConflict {
+			t.Fatalf("exact purge of pinned %s should conflict, got %d", target, resp.StatusCode)
+		}
+	}
+
+	var purged struct {
+		Removed []string `json:"removed"`
+		Pinned  []string `json:"pinned"`
+	}
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm?glob=/*/-/*.tgz", auth), &purged)
+	if len(purged.Removed) != 1 || purged.Removed[0] != "/react/-/react-18.tgz" || len(purged.Pinned) != 2 {
+		t.Fatalf("bulk purge should skip pinned entries: %+v", purged)
+	}
+
+	decodeCacheResponse(t, pin("/-/cache/npm/react/-/react-17.tgz", `{"pinned":false}`), &pinned)
+	decodeCacheResponse(t, doCacheRequest(t, app, http.MethodDelete, "/-/cache/npm/react/-/react-17.tgz", auth), &purged)
+	if len(purged.Removed) != 1 {
+		t.Fatalf("unpinned entry should be purgeable: %+v", purged)
+	}
+}
+
+func newCacheTestApp(t *testing.T) (*fiber.App, cache.Store, *bytes.Buffer) {
+	t.Helper()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{ListenPort: 5000, StoragePath: t.TempDir()},
+		Hubs: []config.HubConfig{
+			{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: "https://registry.npmjs.org"},
+		},
+	}
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	store, err := cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{Index: true})
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	t.Cleanup(func() { store.(cache.Indexed).Index().Close() })
+
+	logs := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(logs)
+
+	app := fiber.New()
+	RegisterCacheRoutes(app, CacheRouteOptions{
+		Registry:  registry,
+		Store:     store,
+		Logger:    logger,
+		Token:     testAdminToken,
+		Retention: cache.NewRetentionPolicy([]cache.RetentionRule{{Hub: "npm", Match: "/typescript/-/*", Pinned: true}}),
+	})
+	return app, store, logs
+}
+
+func putCacheEntry(t *testing.T, store cache.Store, path, body string, response cache.ResponseMetadata) {
+	t.Helper()
+	locator := cache.Locator{HubName: "npm", Path: path}
+	if _, err := store.Put(context.Background(), locator, strings.NewReader(body), cache.PutOptions{Response: response}); err != nil {
+		t.Fatalf("put %s: %v", path, err)
+	}
+}
+
+func doCacheRequest(t *testing.T, app *fiber.App, method, target, auth string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+	return resp
+}
+
+func decodeCacheResponse(t *testing.T, resp *http.Response, out any) {
+	t.Helper()
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+}
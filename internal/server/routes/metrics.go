@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/metrics"
+)
+
+// RegisterMetricsRoutes 暴露 /-/metrics，以 Prometheus 文本格式输出进程内计数器。
+func RegisterMetricsRoutes(app *fiber.App) {
+	if app == nil {
+		return
+	}
+
+	app.Get("/-/metrics", func(c fiber.Ctx) error {
+		var buf bytes.Buffer
+		if err := metrics.WriteText(&buf); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return c.Send(buf.Bytes())
+	})
+}
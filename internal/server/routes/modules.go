@@ -7,6 +7,7 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/any-hub/any-hub/internal/config"
 	"github.com/any-hub/any-hub/internal/hubmodule"
 	"github.com/any-hub/any-hub/internal/proxy/hooks"
 	"github.com/any-hub/any-hub/internal/server"
@@ -61,10 +62,23 @@ type cacheStrategyPayload struct {
 }
 
 type hubBindingPayload struct {
-	HubName   string `json:"hub_name"`
-	ModuleKey string `json:"module_key"`
-	Domain    string `json:"domain"`
-	Port      int    `json:"port"`
+	HubName    string            `json:"hub_name"`
+	ModuleKey  string            `json:"module_key"`
+	Domain     string            `json:"domain"`
+	Port       int               `json:"port"`
+	DiskLayout string            `json:"disk_layout"`
+	Selection  string            `json:"upstream_selection"`
+	Upstreams  []upstreamPayload `json:"upstreams"`
+}
+
+type upstreamPayload struct {
+	URL                 string     `json:"url"`
+	Weight              int        `json:"weight"`
+	Healthy             bool       `json:"healthy"`
+	BreakerState        string     `json:"breaker_state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	ErrorRate           float64    `json:"error_rate"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
 }
 
 func encodeModules(mods []hubmodule.ModuleMetadata, status map[string]string) []modulePayload {
@@ -112,11 +126,42 @@ func encodeHubBindings(routes []server.HubRoute) []hubBindingPayload {
 	result := make([]hubBindingPayload, 0, len(routes))
 	for _, route := range routes {
 		result = append(result, hubBindingPayload{
-			HubName:   route.Config.Name,
-			ModuleKey: route.Module.Key,
-			Domain:    route.Config.Domain,
-			Port:      route.ListenPort,
+			HubName:    route.Config.Name,
+			ModuleKey:  route.Module.Key,
+			Domain:     route.Config.Domain,
+			Port:       route.ListenPort,
+			DiskLayout: route.CacheStrategy.DiskLayout,
+			Selection:  upstreamSelection(route),
+			Upstreams:  encodeUpstreams(route.Upstreams),
 		})
 	}
 	return result
 }
+
+func upstreamSelection(route server.HubRoute) string {
+	if route.Config.UpstreamSelection == "" {
+		return config.UpstreamSelectionPriority
+	}
+	return route.Config.UpstreamSelection
+}
+
+func encodeUpstreams(upstreams []*server.Upstream) []upstreamPayload {
+	now := time.Now()
+	result := make([]upstreamPayload, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		status := upstream.Status(now)
+		item := upstreamPayload{
+			URL:                 upstream.URL.String(),
+			Weight:              upstream.Weight,
+			Healthy:             status.Healthy,
+			BreakerState:        string(status.State),
+			ConsecutiveFailures: status.ConsecutiveFailures,
+			ErrorRate:           status.ErrorRate,
+		}
+		if status.State == server.BreakerOpen {
+			item.OpenUntil = &status.OpenUntil
+		}
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+// Upstream 是 Hub 的一个上游镜像。熔断器以指针保存，HubRoute 的副本共享同一份状态。
+type Upstream struct {
+	URL *url.URL
+	// Weight 为 weighted 选择时的权重，至少为 1。
+	Weight  int
+	breaker *breaker
+}
+
+func newUpstream(entry config.UpstreamEntry) (*Upstream, error) {
+	parsed, err := url.Parse(entry.URL)
+	if err != nil {
+		return nil, err
+	}
+	return &Upstream{URL: parsed, Weight: max(entry.Weight, 1), breaker: &breaker{state: BreakerClosed}}, nil
+}
+
+// buildUpstreams 按配置顺序解析 Hub 的上游列表。
+func buildUpstreams(hub config.HubConfig) ([]*Upstream, error) {
+	entries := hub.UpstreamList()
+	upstreams := make([]*Upstream, 0, len(entries))
+	for _, entry := range entries {
+		upstream, err := newUpstream(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream for hub %s: %w", hub.Name, err)
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams, nil
+}
+
+// PrimaryUpstream 返回配置中的第一个上游，用于缓存命中时的响应头、日志与模块判断上游类型。
+func (r *HubRoute) PrimaryUpstream() *url.URL {
+	if r == nil || len(r.Upstreams) == 0 {
+		return nil
+	}
+	return r.Upstreams[0].URL
+}
+
+// Candidates 返回本次请求依次尝试的上游：可用的在前，priority 模式按配置顺序，
+// weighted 模式按权重随机排列；熔断中的上游按原顺序排在最后，由调用方经 Allow 决定是否跳过。
+func (r *HubRoute) Candidates() []*Upstream {
+	if r == nil {
+		return nil
+	}
+	if len(r.Upstreams) <= 1 {
+		return r.Upstreams
+	}
+	now := time.Now()
+	healthy := make([]*Upstream, 0, len(r.Upstreams))
+	var down []*Upstream
+	for _, upstream := range r.Upstreams {
+		if upstream.Healthy(now) {
+			healthy = append(healthy, upstream)
+		} else {
+			down = append(down, upstream)
+		}
+	}
+	if r.Config.UpstreamSelection == config.UpstreamSelectionWeighted {
+		healthy = weightedOrder(healthy)
+	}
+	return append(healthy, down...)
+}
+
+// weightedOrder 按权重做不放回抽样，得到一次随机排列。
+func weightedOrder(upstreams []*Upstream) []*Upstream {
+	remaining := append([]*Upstream(nil), upstreams...)
+	ordered := make([]*Upstream, 0, len(upstreams))
+	for len(remaining) > 0 {
+		total := 0
+		for _, upstream := range remaining {
+			total += upstream.Weight
+		}
+		pick := rand.IntN(total)
+		for i, upstream := range remaining {
+			if pick < upstream.Weight {
+				ordered = append(ordered, upstream)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+			pick -= upstream.Weight
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func newMultiUpstreamRoute(t *testing.T, selection string, entries ...config.UpstreamEntry) *HubRoute {
+	t.Helper()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{ListenPort: 5000, CacheTTL: config.Duration(time.Hour)},
+		Hubs: []config.HubConfig{{
+			Name:              "npm",
+			Domain:            "npm.hub.local",
+			Type:              "npm",
+			Upstreams:         entries,
+			UpstreamSelection: selection,
+		}},
+	}
+	registry, err := NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	route, ok := registry.Lookup("npm.hub.local")
+	if !ok {
+		t.Fatalf("expected npm route")
+	}
+	return route
+}
+
+func candidateHosts(route *HubRoute) []string {
+	var hosts []string
+	for _, upstream := range route.Candidates() {
+		hosts = append(hosts, upstream.URL.Host)
+	}
+	return hosts
+}
+
+func TestCandidatesPreferHealthyUpstreamsInOrder(t *testing.T) {
+	route := newMultiUpstreamRoute(t, "",
+		config.UpstreamEntry{URL: "https://registry.npmjs.org"},
+		config.UpstreamEntry{URL: "https://registry.npmmirror.com"},
+	)
+	if got := route.PrimaryUpstream().Host; got != "registry.npmjs.org" {
+		t.Fatalf("unexpected primary upstream: %s", got)
+	}
+	if hosts := candidateHosts(route); hosts[0] != "registry.npmjs.org" || hosts[1] != "registry.npmmirror.com" {
+		t.Fatalf("priority selection should keep configured order: %v", hosts)
+	}
+
+	primary := route.Upstreams[0]
+	now := time.Now()
+	for i := 1; i < upstreamFailureThreshold; i++ {
+		if primary.MarkFailure(now).Changed() {
+			t.Fatalf("breaker must stay closed below the failure threshold")
+		}
+	}
+	if change := primary.MarkFailure(now); change.To != BreakerOpen || primary.Healthy(now) {
+		t.Fatalf("breaker should open after %d consecutive failures: %+v", upstreamFailureThreshold, change)
+	}
+	if hosts := candidateHosts(route); hosts[0] != "registry.npmmirror.com" || hosts[1] != "registry.npmjs.org" {
+		t.Fatalf("open upstream should move to the end: %v", hosts)
+	}
+	if !primary.Healthy(now.Add(upstreamCooldown)) {
+		t.Fatalf("upstream should be eligible for a probe after the cooldown")
+	}
+}
+
+func TestCandidatesWeightedSelection(t *testing.T) {
+	route := newMultiUpstreamRoute(t, config.UpstreamSelectionWeighted,
+		config.UpstreamEntry{URL: "https://a.example", Weight: 9},
+		config.UpstreamEntry{URL: "https://b.example", Weight: 1},
+	)
+	first := map[string]int{}
+	for range 1000 {
+		hosts := candidateHosts(route)
+		if len(hosts) != 2 || hosts[0] == hosts[1] {
+			t.Fatalf("weighted order must be a permutation: %v", hosts)
+		}
+		first[hosts[0]]++
+	}
+	if first["a.example"] < 800 || first["b.example"] == 0 {
+		t.Fatalf("selection should follow weights: %v", first)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -33,6 +34,9 @@ var (
 )
 
 func main() {
+	if code, ok := runSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
 	opts, err := parseCLIFlags(os.Args[1:])
 	if err != nil {
 		fmt.Fprintln(stdErr, err.Error())
@@ -41,6 +45,27 @@ func main() {
 	os.Exit(run(opts))
 }
 
+// runSubcommand 分派 `any-hub <子命令>`；首个参数不是已知子命令时返回 false，
+// 交由 parseCLIFlags 按服务模式处理。
+func runSubcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch args[0] {
+	case "cache":
+		return runCacheCommand(args[1:]), true
+	case "prefetch":
+		opts, err := parsePrefetchFlags(args[1:])
+		if err != nil {
+			fmt.Fprintln(stdErr, err.Error())
+			return 2, true
+		}
+		return runPrefetch(opts), true
+	default:
+		return 0, false
+	}
+}
+
 // run 根据解析到的 CLI 选项执行业务流程，并返回退出码，方便测试。
 func run(opts cliOptions) int {
 	if opts.showVersion {
@@ -77,11 +102,45 @@ func run(opts cliOptions) int {
 
 	// CLI 启动遵循“配置 → HubRegistry → 磁盘缓存 → Fiber server”顺序，
 	// 保证所有请求共享统一的路由与缓存实例，方便观察 cache/log 指标。
-	store, err := cache.NewStore(cfg.Global.StoragePath)
+	store, err := openCacheStore(cfg)
 	if err != nil {
 		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
 		return 1
 	}
+	// 小而热的正文（packument、simple 页面、InRelease、manifest）常驻内存，命中时不访问后端。
+	store = cache.NewMemoryStore(store, cache.MemoryOptions{MaxBytes: cfg.Global.MaxMemoryCache})
+
+	// 配置了 MaxDiskCacheSize 时启动后台淘汰器，按 LRU 回收超出配额的缓存条目。
+	// 对象存储后端不支持淘汰（配置校验已拒绝其磁盘配额），容量交由 bucket 生命周期规则管理。
+	retention := retentionPolicy(cfg)
+	if cfg.Global.StorageBackend == config.StorageBackendFS {
+		evictor, err := cache.NewEvictor(store, cache.EvictionOptions{
+			GlobalLimit: cfg.Global.MaxDiskCache,
+			HubLimits:   cfg.HubDiskCacheLimits(),
+			Retention:   retention,
+		}, logger)
+		if err != nil {
+			fmt.Fprintf(stdErr, "初始化缓存淘汰器失败: %v\n", err)
+			return 1
+		}
+		if evictor.Enabled() {
+			go evictor.Run(context.Background())
+		}
+
+		// 启动时及之后每小时清理崩溃/取消遗留的临时文件与孤立 .meta，并报告文件/目录冲突。
+		collector, err := cache.NewCollector(store, cache.GCOptions{}, logger)
+		if err != nil {
+			fmt.Fprintf(stdErr, "初始化缓存回收器失败: %v\n", err)
+			return 1
+		}
+		go collector.Run(context.Background())
+
+		// 配置了 MaxAge/MaxVersions 保留规则时每小时执行一次，固定的条目始终保留。
+		retainer := cache.NewRetainer(store, retention, cache.RetentionOptions{}, logger)
+		if retainer.Enabled() {
+			go retainer.Run(context.Background())
+		}
+	}
 
 	httpClient := server.NewUpstreamClient(cfg)
 	proxyHandler := proxy.NewHandler(httpClient, logger, store)
@@ -98,13 +157,60 @@ func run(opts cliOptions) int {
 	fields["version"] = version.Full()
 	logger.WithFields(fields).Info("配置加载完成")
 
-	if err := startHTTPServer(cfg, registry, forwarder, logger); err != nil {
+	if err := startHTTPServer(cfg, registry, forwarder, store, logger); err != nil {
 		fmt.Fprintf(stdErr, "HTTP 服务启动失败: %v\n", err)
 		return 1
 	}
 	return 0
 }
 
+// openCacheStore 根据 Global.StorageBackend 选择磁盘或 S3 兼容对象存储作为缓存后端。
+func openCacheStore(cfg *config.Config) (cache.Store, error) {
+	if cfg.Global.StorageBackend != config.StorageBackendS3 {
+		// 磁盘后端始终维护 index.db，首次启用时会从现有目录重建。
+		return cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{
+			Index:   true,
+			Layouts: cfg.HubDiskLayouts(),
+		})
+	}
+	return cache.NewS3Store(cache.S3Options{
+		Endpoint:        cfg.Global.S3Endpoint,
+		Region:          cfg.Global.S3Region,
+		Bucket:          cfg.Global.S3Bucket,
+		Prefix:          cfg.Global.S3Prefix,
+		AccessKeyID:     cfg.Global.S3AccessKeyID,
+		SecretAccessKey: cfg.Global.S3SecretAccessKey,
+	})
+}
+
+// openCommandStore 供需要写缓存的子命令使用，与服务使用同一后端；磁盘后端的 index.db 被运行中的服务
+// 锁定时不带索引打开，新条目会在服务首次命中时补录进索引。action 用于日志。
+func openCommandStore(cfg *config.Config, logger *logrus.Logger, action string) (cache.Store, error) {
+	store, err := openCacheStore(cfg)
+	if err == nil || cfg.Global.StorageBackend == config.StorageBackendS3 {
+		return store, err
+	}
+	logger.WithError(err).WithField("action", action).Warn("cache_index_unavailable")
+	return cache.NewStoreWithOptions(cfg.Global.StoragePath, cache.StoreOptions{Layouts: cfg.HubDiskLayouts()})
+}
+
+// retentionPolicy 将各 Hub 的 [[Hub.Retention]] 规则转换为缓存层的保留策略。
+func retentionPolicy(cfg *config.Config) *cache.RetentionPolicy {
+	var rules []cache.RetentionRule
+	for _, hub := range cfg.Hubs {
+		for _, rule := range hub.Retention {
+			rules = append(rules, cache.RetentionRule{
+				Hub:         hub.Name,
+				Match:       rule.Match,
+				MaxAge:      rule.MaxAge.DurationValue(),
+				MaxVersions: rule.MaxVersions,
+				Pinned:      rule.Pinned,
+			})
+		}
+	}
+	return cache.NewRetentionPolicy(rules)
+}
+
 // parseCLIFlags 解析 CLI 参数，并结合环境变量计算最终的配置路径。
 func parseCLIFlags(args []string) (cliOptions, error) {
 	fs := flag.NewFlagSet("any-hub", flag.ContinueOnError)
@@ -124,6 +230,15 @@ func parseCLIFlags(args []string) (cliOptions, error) {
 		return cliOptions{}, fmt.Errorf("解析参数失败: %w", err)
 	}
 
+	return cliOptions{
+		configPath:  resolveConfigPath(configFlag),
+		checkOnly:   checkOnly,
+		showVersion: showVer,
+	}, nil
+}
+
+// resolveConfigPath 按 --config > ANY_HUB_CONFIG > ./config.toml 的优先级确定配置路径。
+func resolveConfigPath(configFlag string) string {
 	path := os.Getenv("ANY_HUB_CONFIG")
 	if configFlag != "" {
 		path = configFlag
@@ -131,18 +246,14 @@ func parseCLIFlags(args []string) (cliOptions, error) {
 	if path == "" {
 		path = "config.toml"
 	}
-
-	return cliOptions{
-		configPath:  path,
-		checkOnly:   checkOnly,
-		showVersion: showVer,
-	}, nil
+	return path
 }
 
 func startHTTPServer(
 	cfg *config.Config,
 	registry *server.HubRegistry,
 	proxyHandler server.ProxyHandler,
+	store cache.Store,
 	logger *logrus.Logger,
 ) error {
 	port := cfg.Global.ListenPort
@@ -156,6 +267,14 @@ func startHTTPServer(
 		return err
 	}
 	routes.RegisterModuleRoutes(app, registry)
+	routes.RegisterMetricsRoutes(app)
+	routes.RegisterCacheRoutes(app, routes.CacheRouteOptions{
+		Registry:  registry,
+		Store:     store,
+		Logger:    logger,
+		Token:     cfg.Global.AdminToken,
+		Retention: retentionPolicy(cfg),
+	})
 
 	logger.WithFields(logrus.Fields{
 		"action": "listen",
@@ -81,3 +81,88 @@ func TestRegisterModuleHandlersFailsWithoutHook(t *testing.T) {
 		t.Fatalf("expected missing hook error, got %v", err)
 	}
 }
+
+func TestParseCacheGCFlags(t *testing.T) {
+	t.Setenv("ANY_HUB_CONFIG", "/tmp/env.toml")
+
+	opts, err := parseCacheGCFlags([]string{"--dry-run", "--temp-max-age", "30m", "--partial-max-age", "2h"})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !opts.dryRun || opts.tempMaxAge != 30*time.Minute || opts.partialMaxAge != 2*time.Hour || opts.configPath != "/tmp/env.toml" {
+		t.Fatalf("解析结果不符合预期: %+v", opts)
+	}
+
+	if _, err := parseCacheGCFlags([]string{"extra"}); err == nil {
+		t.Fatalf("多余参数应报错")
+	}
+}
+
+func TestParseCacheMigrateFlags(t *testing.T) {
+	opts, err := parseCacheMigrateFlags([]string{"--config", "/tmp/c.toml", "--hub", "quay", "--dry-run"})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !opts.dryRun || opts.hub != "quay" || opts.configPath != "/tmp/c.toml" {
+		t.Fatalf("解析结果不符合预期: %+v", opts)
+	}
+
+	if _, err := parseCacheMigrateFlags([]string{"extra"}); err == nil {
+		t.Fatalf("多余参数应报错")
+	}
+}
+
+func TestRunSubcommandPassesThroughServeFlags(t *testing.T) {
+	useBufferWriters(t)
+	if _, ok := runSubcommand([]string{"--config", "config.toml"}); ok {
+		t.Fatalf("服务模式参数不应被识别为子命令")
+	}
+	code, ok := runSubcommand([]string{"cache", "unknown"})
+	if !ok || code != 2 {
+		t.Fatalf("未知 cache 子命令应返回 2，得到 %d", code)
+	}
+}
+
+func TestParsePrefetchFlags(t *testing.T) {
+	opts, err := parsePrefetchFlags([]string{"--hub", "npm", "--concurrency", "8", "package-lock.json", "go.sum"})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if opts.hub != "npm" || opts.concurrency != 8 || len(opts.lockfiles) != 2 {
+		t.Fatalf("解析结果不符合预期: %+v", opts)
+	}
+
+	if _, err := parsePrefetchFlags([]string{}); err == nil {
+		t.Fatalf("缺少锁文件与镜像列表时应报错")
+	}
+	if _, err := parsePrefetchFlags([]string{"--concurrency", "0", "go.sum"}); err == nil {
+		t.Fatalf("并发数为 0 时应报错")
+	}
+}
+
+func TestParseCacheExportImportFlags(t *testing.T) {
+	opts, err := parseCacheExportFlags([]string{"--hub", "docker", "--since", "2026-01-01", "-o", "bundle.tar.zst"})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if opts.hub != "docker" || opts.output != "bundle.tar.zst" || !opts.since.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("解析结果不符合预期: %+v", opts)
+	}
+	if _, err := parseCacheExportFlags([]string{"--hub", "docker"}); err == nil {
+		t.Fatalf("缺少 -o 时应报错")
+	}
+	if _, err := parseCacheExportFlags([]string{"--since", "yesterday", "-o", "b.tar.zst"}); err == nil {
+		t.Fatalf("无法解析的 --since 应报错")
+	}
+
+	imported, err := parseCacheImportFlags([]string{"--verify-only", "bundle.tar.zst"})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if !imported.verifyOnly || imported.input != "bundle.tar.zst" {
+		t.Fatalf("解析结果不符合预期: %+v", imported)
+	}
+	if _, err := parseCacheImportFlags([]string{}); err == nil {
+		t.Fatalf("缺少 bundle 路径时应报错")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/logging"
+	"github.com/any-hub/any-hub/internal/prefetch"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+// prefetchOptions 为 `any-hub prefetch` 的解析结果。
+type prefetchOptions struct {
+	configPath  string
+	hub         string
+	concurrency int
+	images      string
+	platform    string
+	lockfiles   []string
+}
+
+func parsePrefetchFlags(args []string) (prefetchOptions, error) {
+	fs := flag.NewFlagSet("any-hub prefetch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var (
+		configFlag string
+		opts       prefetchOptions
+	)
+	fs.StringVar(&configFlag, "config", "", "配置文件路径（默认 ./config.toml，可被 ANY_HUB_CONFIG 覆盖）")
+	fs.StringVar(&opts.hub, "hub", "", "指定目标 Hub 名称（同类型 Hub 有多个时必填）")
+	fs.IntVar(&opts.concurrency, "concurrency", 4, "同时回源的条目数")
+	fs.StringVar(&opts.images, "images", "", "镜像列表文件，每行一个镜像引用")
+	fs.StringVar(&opts.platform, "platform", prefetch.DefaultPlatform, "多架构镜像选取的平台")
+
+	if err := fs.Parse(args); err != nil {
+		return prefetchOptions{}, fmt.Errorf("解析参数失败: %w", err)
+	}
+	if opts.concurrency <= 0 {
+		return prefetchOptions{}, errors.New("解析参数失败: --concurrency 必须大于 0")
+	}
+	opts.lockfiles = fs.Args()
+	if len(opts.lockfiles) == 0 && opts.images == "" {
+		return prefetchOptions{}, errors.New("用法: any-hub prefetch [--config path] [--hub name] [--concurrency 4] [--images list.txt] [lockfile...]")
+	}
+	opts.configPath = resolveConfigPath(configFlag)
+	return opts, nil
+}
+
+// loadPrefetchTargets 读取锁文件与镜像列表并转换为预热目标。
+func loadPrefetchTargets(opts prefetchOptions) ([]prefetch.Target, error) {
+	var targets []prefetch.Target
+	for _, path := range opts.lockfiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := prefetch.ParseFile(path, data)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, parsed...)
+	}
+	if opts.images != "" {
+		file, err := os.Open(opts.images)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		parsed, err := prefetch.ParseImageList(file, opts.platform)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, parsed...)
+	}
+	return targets, nil
+}
+
+// runPrefetch 按“配置 → HubRegistry → 缓存 → 代理处理链”构建与服务相同的请求路径，
+// 在本进程内回源写缓存，最后在 stdout 输出汇总；存在失败条目时返回 1。
+func runPrefetch(opts prefetchOptions) int {
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	targets, err := loadPrefetchTargets(opts)
+	if err != nil {
+		fmt.Fprintf(stdErr, "读取预热清单失败: %v\n", err)
+		return 1
+	}
+
+	logger, err := logging.InitLogger(cfg.Global)
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		fmt.Fprintf(stdErr, "构建 Hub 注册表失败: %v\n", err)
+		return 1
+	}
+	store, err := openCommandStore(cfg, logger, "prefetch")
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化缓存目录失败: %v\n", err)
+		return 1
+	}
+	if indexed, ok := store.(cache.Indexed); ok && indexed.Index() != nil {
+		defer indexed.Index().Close()
+	}
+
+	proxyHandler := proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store)
+	if err := registerModuleHandlers(proxyHandler); err != nil {
+		fmt.Fprintf(stdErr, "注册模块 handler 失败: %v\n", err)
+		return 1
+	}
+	app, err := server.NewApp(server.AppOptions{
+		Logger:     logger,
+		Registry:   registry,
+		Proxy:      proxy.NewForwarder(proxyHandler, logger),
+		ListenPort: cfg.Global.ListenPort,
+	})
+	if err != nil {
+		fmt.Fprintf(stdErr, "初始化代理处理链失败: %v\n", err)
+		return 1
+	}
+
+	prefetcher := prefetch.New(registry, app.Handler(), store, prefetch.Options{
+		Concurrency: opts.concurrency,
+		Hub:         opts.hub,
+	}, logger)
+	summary := prefetcher.Run(context.Background(), targets)
+
+	fmt.Fprintf(stdOut, "prefetch: fetched=%d cached=%d failed=%d\n", summary.Fetched, summary.Cached, len(summary.Failures))
+	for _, failure := range summary.Failures {
+		fmt.Fprintf(stdOut, "  failed hub=%s path=%s: %s\n", failure.Hub, failure.Path, failure.Reason)
+	}
+	if len(summary.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -667,3 +667,83 @@ func (s *cacheFlowStub) UpdateBody(body []byte) {
 	s.etag = fmt.Sprintf(`"etag-v%d"`, s.etagVer)
 	s.lastMod = time.Now().UTC().Add(2 * time.Second).Format(http.TimeFormat)
 }
+
+func TestCacheValidatorsSurviveRestart(t *testing.T) {
+	upstream := newCacheFlowStub(t, dockerManifestPath)
+	defer upstream.Close()
+
+	storageDir := t.TempDir()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(30 * time.Second),
+			StoragePath: storageDir,
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:     "docker",
+				Domain:   "docker.hub.local",
+				Type:     "docker",
+				Upstream: upstream.URL,
+			},
+		},
+	}
+
+	// newApp 每次构建全新的 Handler/Store，模拟进程重启后只剩磁盘上的缓存。
+	newApp := func() *fiber.App {
+		registry, err := server.NewHubRegistry(cfg)
+		if err != nil {
+			t.Fatalf("registry error: %v", err)
+		}
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		store, err := cache.NewStore(storageDir)
+		if err != nil {
+			t.Fatalf("store error: %v", err)
+		}
+		app, err := server.NewApp(server.AppOptions{
+			Logger:     logger,
+			Registry:   registry,
+			Proxy:      proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store),
+			ListenPort: 5000,
+		})
+		if err != nil {
+			t.Fatalf("app error: %v", err)
+		}
+		return app
+	}
+	doRequest := func(app *fiber.App) *http.Response {
+		req := httptest.NewRequest("GET", "http://docker.hub.local"+dockerManifestPath, nil)
+		req.Host = "docker.hub.local"
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		return resp
+	}
+
+	resp := doRequest(newApp())
+	resp.Body.Close()
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "false" {
+		t.Fatalf("expected initial miss")
+	}
+
+	resp = doRequest(newApp())
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" || string(body) != "upstream payload" {
+		t.Fatalf("expected cache hit after restart, got hit=%s body=%q", resp.Header.Get("X-Any-Hub-Cache-Hit"), body)
+	}
+	if got := upstream.lastRequest.Header.Get("If-None-Match"); strings.Trim(got, `"`) != "etag-v1" {
+		t.Fatalf("expected persisted ETag in revalidation, got %q", got)
+	}
+	if resp.Header.Get("Etag") != `"etag-v1"` {
+		t.Fatalf("expected cached ETag to be replayed, got %q", resp.Header.Get("Etag"))
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain" {
+		t.Fatalf("expected upstream content type to be replayed, got %q", ct)
+	}
+	if upstream.hits != 1 {
+		t.Fatalf("expected a single upstream GET across restarts, got %d", upstream.hits)
+	}
+}
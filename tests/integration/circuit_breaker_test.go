@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+	"github.com/any-hub/any-hub/internal/server/routes"
+)
+
+func TestCircuitBreakerFailsFastAndServesStale(t *testing.T) {
+	var down atomic.Bool
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"r1"`)
+		_, _ = w.Write([]byte("Release-body"))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5700,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.breaker.local", Type: "debian", Upstream: upstream.URL},
+		},
+	}
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	logs := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(logs)
+	store, err := cache.NewStore(cfg.Global.StoragePath)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	app := mustNewApp(t, cfg.Global.ListenPort, logger, registry, proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store))
+	routes.RegisterModuleRoutes(app, registry)
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "http://apt.breaker.local"+path, nil)
+		req.Host = "apt.breaker.local"
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		return resp
+	}
+
+	resp := get(staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected initial fetch to succeed, got %d", resp.StatusCode)
+	}
+
+	// 上游持续 503：前 5 次失败透传给客户端，随后熔断器打开。
+	down.Store(true)
+	for i := range 5 {
+		resp := get(fmt.Sprintf("/dists/suite%d/InRelease", i))
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusServiceUnavailable {
+			t.Fatalf("request %d: expected upstream 503, got %d", i, resp.StatusCode)
+		}
+	}
+	before := hits.Load()
+
+	resp = get("/dists/other/InRelease")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusServiceUnavailable || !strings.Contains(string(body), "upstream_circuit_open") {
+		t.Fatalf("expected fail-fast 503 while the breaker is open, got %d %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("fail-fast response should carry Retry-After")
+	}
+
+	// 熔断期间需要再验证的缓存条目直接回放，不访问上游；未配置 StaleIfError 也不例外。
+	resp = get(staleReleasePath)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || string(body) != "Release-body" ||
+		resp.Header.Get("X-Any-Hub-Stale") != "true" || resp.Header.Get("Warning") == "" {
+		t.Fatalf("expected stale cache while the breaker is open, got %d %q", resp.StatusCode, body)
+	}
+	if hits.Load() != before {
+		t.Fatalf("open breaker must not contact the upstream, got %d extra hits", hits.Load()-before)
+	}
+
+	if !strings.Contains(logs.String(), `"action":"circuit_breaker"`) || !strings.Contains(logs.String(), `"to":"open"`) {
+		t.Fatalf("expected circuit_breaker log, got %s", logs.String())
+	}
+
+	resp = doRequest(t, app, http.MethodGet, "/-/modules")
+	var payload struct {
+		Hubs []struct {
+			HubName   string `json:"hub_name"`
+			Upstreams []struct {
+				Healthy      bool       `json:"healthy"`
+				BreakerState string     `json:"breaker_state"`
+				OpenUntil    *time.Time `json:"open_until"`
+			} `json:"upstreams"`
+		} `json:"hubs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode diagnostics: %v", err)
+	}
+	resp.Body.Close()
+	if len(payload.Hubs) != 1 || len(payload.Hubs[0].Upstreams) != 1 {
+		t.Fatalf("unexpected diagnostics payload: %+v", payload)
+	}
+	state := payload.Hubs[0].Upstreams[0]
+	if state.BreakerState != "open" || state.Healthy || state.OpenUntil == nil {
+		t.Fatalf("diagnostics should report the open breaker: %+v", state)
+	}
+}
@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+	"github.com/any-hub/any-hub/internal/server/routes"
+)
+
+const coalesceTarballPath = "/demo/-/demo-1.0.0.tgz"
+
+func TestConcurrentMissesShareOneUpstreamFetch(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.WriteString(w, "shared tarball")
+	}))
+	defer upstream.Close()
+
+	app := newCoalesceApp(t, "coalesce-ok", upstream.URL)
+	before := coalescedCount(t, app, "coalesce-ok")
+	statuses, bodies := fireConcurrent(t, app, "coalesce-ok.hub.local", 5)
+	for i := range statuses {
+		if statuses[i] != fiber.StatusOK || bodies[i] != "shared tarball" {
+			t.Fatalf("request %d: unexpected response %d %q", i, statuses[i], bodies[i])
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected a single upstream fetch, got %d", hits.Load())
+	}
+	if delta := coalescedCount(t, app, "coalesce-ok") - before; delta != 4 {
+		t.Fatalf("expected 4 coalesced requests in metrics, got %d", delta)
+	}
+}
+
+func TestCoalescedWaitersReceiveUpstreamFailure(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	app := newCoalesceApp(t, "coalesce-fail", upstream.URL)
+	statuses, _ := fireConcurrent(t, app, "coalesce-fail.hub.local", 4)
+	for i, status := range statuses {
+		if status != fiber.StatusServiceUnavailable {
+			t.Fatalf("request %d: expected upstream failure to be shared, got %d", i, status)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected a single upstream fetch, got %d", hits.Load())
+	}
+}
+
+func newCoalesceApp(t *testing.T, hub, upstreamURL string) *fiber.App {
+	t.Helper()
+	storageDir := t.TempDir()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(30 * time.Second),
+			StoragePath: storageDir,
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:     hub,
+				Domain:   hub + ".hub.local",
+				Type:     "npm",
+				Upstream: upstreamURL,
+			},
+		},
+	}
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := cache.NewStore(storageDir)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	app, err := server.NewApp(server.AppOptions{
+		Logger:     logger,
+		Registry:   registry,
+		Proxy:      proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store),
+		ListenPort: 5000,
+	})
+	if err != nil {
+		t.Fatalf("app error: %v", err)
+	}
+	routes.RegisterMetricsRoutes(app)
+	return app
+}
+
+// coalescedCount 通过 /-/metrics 读取指定 Hub 的合并计数（计数器为进程级，需按差值断言）。
+func coalescedCount(t *testing.T, app *fiber.App, hub string) int {
+	t.Helper()
+	req := httptest.NewRequest("GET", "http://"+hub+".hub.local/-/metrics", nil)
+	req.Host = hub + ".hub.local"
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("metrics request error: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	prefix := `anyhub_coalesced_requests_total{hub="` + hub + `"} `
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, prefix); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				t.Fatalf("invalid metric line %q", line)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func fireConcurrent(t *testing.T, app *fiber.App, host string, n int) ([]int, []string) {
+	t.Helper()
+	statuses := make([]int, n)
+	bodies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "http://"+host+coalesceTarballPath, nil)
+			req.Host = host
+			resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+			if err != nil {
+				t.Errorf("app.Test error: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+			bodies[i] = string(data)
+		}(i)
+	}
+	wg.Wait()
+	return statuses, bodies
+}
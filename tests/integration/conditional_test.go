@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestCacheHitAnswersClientConditionalRequests(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == staleReleasePath {
+			w.Header().Set("ETag", `"r1"`)
+			if r.Header.Get("If-None-Match") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = w.Write([]byte("Release-body"))
+			return
+		}
+		// pool 文件不带任何校验器，由缓存条目的写入时间提供 Last-Modified。
+		_, _ = w.Write([]byte("deb-body"))
+	}))
+	defer upstream.Close()
+
+	app := newStrategyTestApp(t, &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5800,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.conditional.local", Type: "debian", Upstream: upstream.URL},
+		},
+	})
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "http://apt.conditional.local"+path, nil)
+		req.Host = "apt.conditional.local"
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	const debPath = "/pool/main/h/hello/hello_1.0_amd64.deb"
+	get(debPath, nil)
+	resp, body := get(debPath, nil)
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" || body != "deb-body" || lastModified == "" {
+		t.Fatalf("cache hit should carry Last-Modified, got hit=%s last-modified=%q", resp.Header.Get("X-Any-Hub-Cache-Hit"), lastModified)
+	}
+	upstreamHits := hits.Load()
+
+	resp, body = get(debPath, http.Header{"If-Modified-Since": {lastModified}})
+	if resp.StatusCode != fiber.StatusNotModified || body != "" {
+		t.Fatalf("expected 304 for matching If-Modified-Since, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Last-Modified") != lastModified {
+		t.Fatalf("304 should repeat the validator, got %q", resp.Header.Get("Last-Modified"))
+	}
+	if hits.Load() != upstreamHits {
+		t.Fatalf("304 for an immutable entry must not contact the upstream")
+	}
+	older := time.Now().Add(-24 * time.Hour).UTC().Format(http.TimeFormat)
+	if resp, body = get(debPath, http.Header{"If-Modified-Since": {older}}); resp.StatusCode != fiber.StatusOK || body != "deb-body" {
+		t.Fatalf("expected full body for an older If-Modified-Since, got %d %q", resp.StatusCode, body)
+	}
+
+	get(staleReleasePath, nil)
+	resp, body = get(staleReleasePath, http.Header{"If-None-Match": {`"r0", W/"r1"`}})
+	if resp.StatusCode != fiber.StatusNotModified || body != "" || resp.Header.Get("ETag") != `"r1"` {
+		t.Fatalf("expected 304 with ETag for matching If-None-Match, got %d %q etag=%q", resp.StatusCode, body, resp.Header.Get("ETag"))
+	}
+	// If-None-Match 不匹配时忽略 If-Modified-Since，返回完整正文。
+	resp, body = get(staleReleasePath, http.Header{
+		"If-None-Match":     {`"r0"`},
+		"If-Modified-Since": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+	})
+	if resp.StatusCode != fiber.StatusOK || body != "Release-body" {
+		t.Fatalf("expected full body for a stale If-None-Match, got %d %q", resp.StatusCode, body)
+	}
+}
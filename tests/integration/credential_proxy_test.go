@@ -208,11 +208,11 @@ func TestDockerProxyCachesAfterBearerRevalidation(t *testing.T) {
 	}
 	resp2.Body.Close()
 
-	if hits := stub.ManifestHits(); hits != 4 {
-		t.Fatalf("expected 4 manifest hits (2 GET + 2 HEAD), got %d", hits)
+	if hits := stub.ManifestHits(); hits != 3 {
+		t.Fatalf("expected 3 manifest hits (2 GET + 1 HEAD with cached token), got %d", hits)
 	}
-	if tokens := stub.TokenHits(); tokens != 2 {
-		t.Fatalf("expected token endpoint to be called twice, got %d", tokens)
+	if tokens := stub.TokenHits(); tokens != 1 {
+		t.Fatalf("expected cached token to be reused for revalidation, got %d token requests", tokens)
 	}
 }
 
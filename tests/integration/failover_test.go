@@ -0,0 +1,189 @@
+package integration
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestUpstreamFailoverToMirror(t *testing.T) {
+	var primaryHits, mirrorHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits.Add(1)
+		_, _ = w.Write([]byte("mirror:" + r.URL.Path))
+	}))
+	defer mirror.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:     5500,
+			CacheTTL:       config.Duration(time.Hour),
+			StoragePath:    t.TempDir(),
+			MaxRetries:     2,
+			InitialBackoff: config.Duration(time.Millisecond),
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:      "apt",
+				Domain:    "apt.failover.local",
+				Type:      "debian",
+				Upstreams: []config.UpstreamEntry{{URL: primary.URL}, {URL: mirror.URL}},
+			},
+		},
+	})
+	defer env.Close()
+
+	// 主上游失败时立即切换到镜像，不在主上游上重试；连续失败 5 次后主上游熔断，不再被请求。
+	for i := range 6 {
+		path := fmt.Sprintf("/dists/suite%d/Release", i)
+		resp := env.DoRequest(t, "apt.failover.local", path)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK || string(body) != "mirror:"+path {
+			t.Fatalf("request %d: expected mirror response, got %d %q", i, resp.StatusCode, body)
+		}
+		if got := resp.Header.Get("X-Any-Hub-Upstream"); !strings.HasPrefix(got, mirror.URL) {
+			t.Fatalf("request %d: X-Any-Hub-Upstream should name the mirror, got %q", i, got)
+		}
+	}
+	if primaryHits.Load() != 5 || mirrorHits.Load() != 6 {
+		t.Fatalf("unexpected upstream hits: primary=%d mirror=%d", primaryHits.Load(), mirrorHits.Load())
+	}
+
+	// 缓存命中回放写入时实际提供响应的镜像，而不是主上游。
+	const pkg = "/pool/main/h/hello/hello_2.10_amd64.deb"
+	for i, wantHit := range []string{"false", "true"} {
+		resp := env.DoRequest(t, "apt.failover.local", pkg)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.Header.Get("X-Any-Hub-Cache-Hit") != wantHit {
+			t.Fatalf("package request %d: expected cache hit %s, got %q", i, wantHit, resp.Header.Get("X-Any-Hub-Cache-Hit"))
+		}
+		if got := resp.Header.Get("X-Any-Hub-Upstream"); !strings.HasPrefix(got, mirror.URL) {
+			t.Fatalf("package request %d: X-Any-Hub-Upstream should name the mirror, got %q", i, got)
+		}
+	}
+	env.AssertLogContains(t, `"msg":"proxy_upstream_failover"`)
+	env.AssertLogContains(t, `"msg":"upstream_circuit_breaker"`)
+	env.AssertLogContains(t, `"to":"open"`)
+	env.AssertLogContains(t, `"next_upstream":"`+mirror.URL+`"`)
+	env.AssertLogContains(t, `"retries":0`)
+}
+
+func TestUpstreamFailoverOnThrottlingKeepsBreakerClosed(t *testing.T) {
+	var primaryHits, mirrorHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 交替返回 429 与带 Retry-After 的 503，两者都只是限流/维护提示。
+		if primaryHits.Add(1)%2 == 0 {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits.Add(1)
+		_, _ = w.Write([]byte("mirror:" + r.URL.Path))
+	}))
+	defer mirror.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5520,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:      "apt",
+				Domain:    "apt.failover.local",
+				Type:      "debian",
+				Upstreams: []config.UpstreamEntry{{URL: primary.URL}, {URL: mirror.URL}},
+			},
+		},
+	})
+	defer env.Close()
+
+	// 限流响应仍切换到镜像，但不计入熔断器：超过失败阈值后主上游依旧会被优先尝试。
+	for i := range 8 {
+		path := fmt.Sprintf("/dists/suite%d/Release", i)
+		resp := env.DoRequest(t, "apt.failover.local", path)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK || string(body) != "mirror:"+path {
+			t.Fatalf("request %d: expected mirror response, got %d %q", i, resp.StatusCode, body)
+		}
+	}
+	if primaryHits.Load() != 8 || mirrorHits.Load() != 8 {
+		t.Fatalf("throttling must not open the breaker: primary=%d mirror=%d", primaryHits.Load(), mirrorHits.Load())
+	}
+	env.AssertLogContains(t, `"msg":"proxy_upstream_failover"`)
+	if strings.Contains(env.logs.String(), `"msg":"upstream_circuit_breaker"`) {
+		t.Fatalf("throttling should not change breaker state: %s", env.logs.String())
+	}
+}
+
+func TestUpstreamFailoverSkipsClientErrors(t *testing.T) {
+	var mirrorHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHits.Add(1)
+		_, _ = w.Write([]byte("mirror"))
+	}))
+	defer mirror.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5510,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:      "apt",
+				Domain:    "apt.failover.local",
+				Type:      "debian",
+				Upstreams: []config.UpstreamEntry{{URL: primary.URL}, {URL: mirror.URL}},
+			},
+			{
+				Name:      "apt-down",
+				Domain:    "down.failover.local",
+				Type:      "debian",
+				Upstreams: []config.UpstreamEntry{{URL: unreachable.URL}, {URL: mirror.URL}},
+			},
+		},
+	})
+	defer env.Close()
+
+	resp := env.DoRequest(t, "apt.failover.local", staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusNotFound || mirrorHits.Load() != 0 {
+		t.Fatalf("404 from the primary is authoritative, got status=%d mirror hits=%d", resp.StatusCode, mirrorHits.Load())
+	}
+
+	resp = env.DoRequest(t, "down.failover.local", staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || mirrorHits.Load() != 1 {
+		t.Fatalf("connection errors should fail over, got status=%d mirror hits=%d", resp.StatusCode, mirrorHits.Load())
+	}
+}
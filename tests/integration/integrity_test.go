@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+func TestDockerBlobIntegrityMismatchReturnsBadGateway(t *testing.T) {
+	sum := sha256.Sum256([]byte("layer"))
+	blobPath := "/v2/demo/blobs/sha256:" + hex.EncodeToString(sum[:])
+
+	var body atomic.Value
+	body.Store("truncated")
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.WriteString(w, body.Load().(string))
+	}))
+	defer upstream.Close()
+
+	storageDir := t.TempDir()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(30 * time.Second),
+			StoragePath: storageDir,
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:     "docker",
+				Domain:   "docker.hub.local",
+				Type:     "docker",
+				Upstream: upstream.URL,
+			},
+		},
+	}
+
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(&logs)
+
+	store, err := cache.NewStore(storageDir)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+
+	app, err := server.NewApp(server.AppOptions{
+		Logger:     logger,
+		Registry:   registry,
+		Proxy:      proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store),
+		ListenPort: 5000,
+	})
+	if err != nil {
+		t.Fatalf("app error: %v", err)
+	}
+
+	doRequest := func() (*http.Response, string) {
+		req := httptest.NewRequest("GET", "http://docker.hub.local"+blobPath, nil)
+		req.Host = "docker.hub.local"
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(data)
+	}
+
+	resp, data := doRequest()
+	if resp.StatusCode != fiber.StatusBadGateway {
+		t.Fatalf("expected 502 on digest mismatch, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(data, "integrity_mismatch") || strings.Contains(data, "truncated") {
+		t.Fatalf("client must not receive the mismatched body: %s", data)
+	}
+	if !strings.Contains(logs.String(), `"msg":"integrity_mismatch"`) {
+		t.Fatalf("expected structured integrity_mismatch log, got %s", logs.String())
+	}
+
+	body.Store("layer")
+	resp, data = doRequest()
+	if resp.StatusCode != fiber.StatusOK || data != "layer" {
+		t.Fatalf("expected verified body after upstream recovers, got %d %q", resp.StatusCode, data)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("mismatched body must not be cached, upstream hits=%d", hits.Load())
+	}
+	resp, _ = doRequest()
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" {
+		t.Fatalf("expected verified blob to be served from cache")
+	}
+}
@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestNegativeCacheTTLReplaysNotFound(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"Not found"}`))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: upstream.URL, NegativeCacheTTL: config.Duration(time.Hour)},
+			{Name: "npm-plain", Domain: "plain.hub.local", Type: "npm", Upstream: upstream.URL},
+			{Name: "apt", Domain: "apt.hub.local", Type: "debian", Upstream: upstream.URL, NegativeCacheTTL: config.Duration(time.Hour)},
+		},
+	}
+	app := newStrategyTestApp(t, cfg)
+
+	get := func(method, host, path string) (*http.Response, string) {
+		req := httptest.NewRequest(method, "http://"+host+path, nil)
+		req.Host = host
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	resp, body := get(http.MethodGet, "npm.hub.local", "/@scope/missing")
+	if resp.StatusCode != fiber.StatusNotFound || body != `{"error":"Not found"}` || resp.Header.Get("X-Any-Hub-Cache-Hit") != "false" {
+		t.Fatalf("unexpected first response: %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	resp, body = get(http.MethodGet, "npm.hub.local", "/@scope/missing")
+	if resp.StatusCode != fiber.StatusNotFound || body != `{"error":"Not found"}` || resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" {
+		t.Fatalf("expected negative cache hit, got %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected cached content type, got %q", resp.Header.Get("Content-Type"))
+	}
+	if resp, _ := get(http.MethodHead, "npm.hub.local", "/@scope/missing"); resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected HEAD to replay 404, got %d", resp.StatusCode)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected a single upstream call within the TTL, got %d", got)
+	}
+
+	// 未配置 NegativeCacheTTL 的 Hub 每次都回源。
+	hits.Store(0)
+	get(http.MethodGet, "plain.hub.local", "/@scope/missing")
+	get(http.MethodGet, "plain.hub.local", "/@scope/missing")
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expected hub without NegativeCacheTTL to hit upstream twice, got %d", got)
+	}
+
+	// debian 模块对 /dists/ 关闭负缓存。
+	hits.Store(0)
+	get(http.MethodGet, "apt.hub.local", "/dists/trixie/InRelease")
+	get(http.MethodGet, "apt.hub.local", "/dists/trixie/InRelease")
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expected module opt-out to bypass negative cache, got %d upstream calls", got)
+	}
+}
+
+func TestNegativeCacheEntryReplacedOnceUpstreamRecovers(t *testing.T) {
+	var present atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !present.Load() {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		_, _ = w.Write([]byte("deb-bytes"))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.hub.local", Type: "debian", Upstream: upstream.URL, NegativeCacheTTL: config.Duration(50 * time.Millisecond)},
+		},
+	}
+	app := newStrategyTestApp(t, cfg)
+
+	get := func() (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://apt.hub.local/pool/main/h/hello.deb", nil)
+		req.Host = "apt.hub.local"
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	get()
+	present.Store(true)
+	if resp, _ := get(); resp.StatusCode != fiber.StatusGone {
+		t.Fatalf("expected 410 within the TTL, got %d", resp.StatusCode)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if resp, body := get(); resp.StatusCode != fiber.StatusOK || body != "deb-bytes" {
+		t.Fatalf("expected fresh fetch after TTL, got %d %q", resp.StatusCode, body)
+	}
+	if resp, body := get(); resp.StatusCode != fiber.StatusOK || body != "deb-bytes" || resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" {
+		t.Fatalf("expected positive entry to replace the marker, got %d %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/prefetch"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+func TestPrefetchWarmsLockfileAndImageEntries(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/lodash":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"lodash","versions":{}}`))
+		case "/lodash/-/lodash-4.17.21.tgz":
+			_, _ = w.Write([]byte("lodash-tarball"))
+		case "/v2/library/demo/manifests/1.0":
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			_, _ = w.Write([]byte(`{"config":{"digest":"sha256:cfg"},"layers":[{"digest":"sha256:layer"}]}`))
+		case "/v2/library/demo/blobs/sha256:cfg", "/v2/library/demo/blobs/sha256:layer":
+			_, _ = w.Write([]byte("blob-" + r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	storageDir := t.TempDir()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Minute),
+			StoragePath: storageDir,
+		},
+		Hubs: []config.HubConfig{
+			{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: upstream.URL},
+			{Name: "docker", Domain: "docker.hub.local", Type: "docker", Upstream: upstream.URL},
+		},
+	}
+	prefetcher, store := newTestPrefetcher(t, cfg, prefetch.Options{Concurrency: 2})
+
+	lock := []byte(`{"lockfileVersion":3,"packages":{
+  "node_modules/lodash":{"resolved":"https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz"},
+  "node_modules/missing":{"resolved":"https://registry.npmjs.org/missing/-/missing-1.0.0.tgz"}}}`)
+	targets, err := prefetch.ParseFile("package-lock.json", lock)
+	if err != nil {
+		t.Fatalf("parse lockfile: %v", err)
+	}
+	// 上游 Host 不是 Docker Hub 时，镜像需要显式写出 registry 才能匹配到 docker Hub。
+	images, err := prefetch.ParseImageList(strings.NewReader(strings.TrimPrefix(upstream.URL, "http://")+"/library/demo:1.0\n"), "")
+	if err != nil {
+		t.Fatalf("parse images: %v", err)
+	}
+	targets = append(targets, images...)
+
+	summary := prefetcher.Run(context.Background(), targets)
+	// lodash packument + tarball、manifest + config + layer 写入缓存；missing 的 packument 与 tarball 均失败。
+	if summary.Fetched != 5 || summary.Cached != 0 || len(summary.Failures) != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	for _, locator := range []cache.Locator{
+		{HubName: "npm", Path: "/lodash/-/lodash-4.17.21.tgz"},
+		{HubName: "docker", Path: "/v2/library/demo/blobs/sha256:layer"},
+	} {
+		result, err := store.Get(context.Background(), locator)
+		if err != nil {
+			t.Fatalf("expected %s to be cached: %v", locator.Path, err)
+		}
+		body, _ := io.ReadAll(result.Reader)
+		result.Reader.Close()
+		if len(body) <= 1 {
+			t.Fatalf("expected full body for %s, got %q", locator.Path, body)
+		}
+	}
+
+	again, _ := newTestPrefetcher(t, cfg, prefetch.Options{})
+	summary = again.Run(context.Background(), targets)
+	if summary.Fetched != 0 || summary.Cached != 5 {
+		t.Fatalf("second run should find everything cached: %+v", summary)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["/lodash/-/lodash-4.17.21.tgz"] != 1 || hits["/v2/library/demo/blobs/sha256:layer"] != 1 {
+		t.Fatalf("cached entries must not be fetched again: %v", hits)
+	}
+}
+
+func newTestPrefetcher(t *testing.T, cfg *config.Config, opts prefetch.Options) (*prefetch.Prefetcher, cache.Store) {
+	t.Helper()
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := cache.NewStore(cfg.Global.StoragePath)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	app, err := server.NewApp(server.AppOptions{
+		Logger:     logger,
+		Registry:   registry,
+		Proxy:      proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store),
+		ListenPort: cfg.Global.ListenPort,
+	})
+	if err != nil {
+		t.Fatalf("app error: %v", err)
+	}
+	return prefetch.New(registry, app.Handler(), store, opts, logger), store
+}
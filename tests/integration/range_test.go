@@ -0,0 +1,255 @@
+package integration
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/any-hub/any-hub/internal/cache"
+	"github.com/any-hub/any-hub/internal/config"
+	"github.com/any-hub/any-hub/internal/proxy"
+	"github.com/any-hub/any-hub/internal/server"
+)
+
+const (
+	rangeTarballPath = "/demo/-/demo-1.0.0.tgz"
+	rangeBody        = "0123456789abcdef"
+)
+
+func TestCacheHitServesByteRanges(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Etag", `"range-v1"`)
+		io.WriteString(w, rangeBody)
+	}))
+	defer upstream.Close()
+
+	app := newRangeApp(t, upstream.URL)
+	if resp, body := doRangeRequest(t, app, nil); resp.StatusCode != fiber.StatusOK || body != rangeBody {
+		t.Fatalf("unexpected priming response %d %q", resp.StatusCode, body)
+	}
+
+	resp, body := doRangeRequest(t, app, map[string]string{"Range": "bytes=2-5"})
+	if resp.StatusCode != fiber.StatusPartialContent || body != "2345" {
+		t.Fatalf("expected single range, got %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 2-5/16" {
+		t.Fatalf("unexpected Content-Range: %s", got)
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("expected Accept-Ranges on cache hit")
+	}
+
+	resp, body = doRangeRequest(t, app, map[string]string{"Range": "bytes=0-1,-2"})
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != fiber.StatusPartialContent || err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("expected multipart response, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("multipart error: %v", err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+"="+string(data))
+	}
+	if strings.Join(parts, ";") != "bytes 0-1/16=01;bytes 14-15/16=ef" {
+		t.Fatalf("unexpected multipart parts: %v", parts)
+	}
+
+	resp, body = doRangeRequest(t, app, map[string]string{"Range": "bytes=2-5", "If-Range": `"stale"`})
+	if resp.StatusCode != fiber.StatusOK || body != rangeBody {
+		t.Fatalf("expected full body when If-Range does not match, got %d %q", resp.StatusCode, body)
+	}
+
+	resp, _ = doRangeRequest(t, app, map[string]string{"Range": "bytes=100-"})
+	if resp.StatusCode != fiber.StatusRequestedRangeNotSatisfiable || resp.Header.Get("Content-Range") != "bytes */16" {
+		t.Fatalf("expected 416, got %d %s", resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+}
+
+func TestInterruptedDownloadResumesFromPartial(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range")+"|"+r.Header.Get("If-Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Etag", `"resume-v1"`)
+		if first {
+			// 声明完整长度但只写出前 6 个字节，模拟传输中途断线。
+			w.Header().Set("Content-Length", "16")
+			io.WriteString(w, rangeBody[:6])
+			return
+		}
+		if r.Header.Get("Range") == "bytes=6-" {
+			w.Header().Set("Content-Range", "bytes 6-15/16")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, rangeBody[6:])
+			return
+		}
+		io.WriteString(w, rangeBody)
+	}))
+	defer upstream.Close()
+
+	app := newRangeApp(t, upstream.URL)
+	if resp, _ := doRangeRequest(t, app, nil); resp.StatusCode != fiber.StatusBadGateway {
+		t.Fatalf("expected interrupted download to fail, got %d", resp.StatusCode)
+	}
+
+	resp, body := doRangeRequest(t, app, map[string]string{"Range": "bytes=6-"})
+	if resp.StatusCode != fiber.StatusPartialContent || body != rangeBody[6:] {
+		t.Fatalf("expected client range served after resume, got %d %q", resp.StatusCode, body)
+	}
+	mu.Lock()
+	second := ranges[1]
+	mu.Unlock()
+	if second != `bytes=6-|"resume-v1"` {
+		t.Fatalf("expected upstream resume from partial, got %q", second)
+	}
+
+	resp, body = doRangeRequest(t, app, nil)
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" || body != rangeBody {
+		t.Fatalf("expected complete body cached after resume, got %q", body)
+	}
+}
+
+func TestRangeMissForwardsRangeAndFillsInBackground(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Etag", `"fill-v1"`)
+		if r.Header.Get("Range") == "bytes=2-5" {
+			w.Header().Set("Content-Range", "bytes 2-5/16")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, rangeBody[2:6])
+			return
+		}
+		// 完整正文迟迟不结束时，前台的 Range 响应不应被拖住。
+		<-release
+		io.WriteString(w, rangeBody)
+	}))
+	defer upstream.Close()
+
+	app := newRangeApp(t, upstream.URL)
+	resp, body := doRangeRequest(t, app, map[string]string{"Range": "bytes=2-5"})
+	if resp.StatusCode != fiber.StatusPartialContent || body != rangeBody[2:6] || resp.Header.Get("Content-Range") != "bytes 2-5/16" {
+		t.Fatalf("expected upstream range passed through, got %d %q %q", resp.StatusCode, body, resp.Header.Get("Content-Range"))
+	}
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "false" {
+		t.Fatalf("range passthrough should be reported as a miss")
+	}
+	close(release)
+
+	// 轮询同样带 Range：未命中时只会转发范围，缓存只能由后台填充写入。
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, body = doRangeRequest(t, app, map[string]string{"Range": "bytes=2-5"})
+		if resp.Header.Get("X-Any-Hub-Cache-Hit") == "true" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected background fill to cache the full body")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if resp.StatusCode != fiber.StatusPartialContent || body != rangeBody[2:6] {
+		t.Fatalf("expected range served from cache, got %d %q", resp.StatusCode, body)
+	}
+	if resp, body = doRangeRequest(t, app, nil); resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" || body != rangeBody {
+		t.Fatalf("expected full body cached by the background fill, got %q", body)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	full := 0
+	for _, r := range ranges {
+		if r == "" {
+			full++
+		}
+	}
+	if ranges[0] != "bytes=2-5" || full != 1 {
+		t.Fatalf("expected range passthrough and exactly one full fill, got %q", ranges)
+	}
+}
+
+func newRangeApp(t *testing.T, upstreamURL string) *fiber.App {
+	t.Helper()
+	storageDir := t.TempDir()
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(30 * time.Second),
+			StoragePath: storageDir,
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:     "npm",
+				Domain:   "npm.hub.local",
+				Type:     "npm",
+				Upstream: upstreamURL,
+			},
+		},
+	}
+	registry, err := server.NewHubRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry error: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store, err := cache.NewStore(storageDir)
+	if err != nil {
+		t.Fatalf("store error: %v", err)
+	}
+	app, err := server.NewApp(server.AppOptions{
+		Logger:     logger,
+		Registry:   registry,
+		Proxy:      proxy.NewHandler(server.NewUpstreamClient(cfg), logger, store),
+		ListenPort: 5000,
+	})
+	if err != nil {
+		t.Fatalf("app error: %v", err)
+	}
+	return app
+}
+
+func doRangeRequest(t *testing.T, app *fiber.App, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "http://npm.hub.local"+rangeTarballPath, nil)
+	req.Host = "npm.hub.local"
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, string(data)
+}
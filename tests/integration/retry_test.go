@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestUpstreamRetriesTransientFailures(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch hits.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("Release-body"))
+		}
+	}))
+	defer upstream.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:     5400,
+			CacheTTL:       config.Duration(time.Hour),
+			StoragePath:    t.TempDir(),
+			MaxRetries:     3,
+			InitialBackoff: config.Duration(time.Millisecond),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.retry.local", Type: "debian", Upstream: upstream.URL},
+		},
+	})
+	defer env.Close()
+
+	resp := env.DoRequest(t, "apt.retry.local", staleReleasePath)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || string(body) != "Release-body" {
+		t.Fatalf("expected the third attempt to succeed, got %d %q", resp.StatusCode, body)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("expected 3 upstream attempts, got %d", got)
+	}
+	env.AssertLogContains(t, `"msg":"proxy_upstream_retry"`)
+	env.AssertLogContains(t, `"upstream_status":429`)
+	env.AssertLogContains(t, `"retries":2`)
+}
+
+func TestUpstreamRetriesRespectHubOverride(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	disabled := 0
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:     5410,
+			CacheTTL:       config.Duration(time.Hour),
+			StoragePath:    t.TempDir(),
+			MaxRetries:     3,
+			InitialBackoff: config.Duration(time.Millisecond),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.retry.local", Type: "debian", Upstream: upstream.URL, MaxRetries: &disabled},
+			{Name: "apt-retry", Domain: "retry.retry.local", Type: "debian", Upstream: upstream.URL},
+		},
+	})
+	defer env.Close()
+
+	resp := env.DoRequest(t, "apt.retry.local", staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusBadGateway || hits.Load() != 1 {
+		t.Fatalf("hub with MaxRetries=0 must not retry, got status=%d hits=%d", resp.StatusCode, hits.Load())
+	}
+
+	hits.Store(0)
+	resp = env.DoRequest(t, "retry.retry.local", staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusBadGateway || hits.Load() != 4 {
+		t.Fatalf("expected the last 502 after 1+3 attempts, got status=%d hits=%d", resp.StatusCode, hits.Load())
+	}
+	env.AssertLogContains(t, `"retries":3`)
+}
+
+func TestUpstreamRetryAfterBeyondLimitReturnsImmediately(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:     5420,
+			CacheTTL:       config.Duration(time.Hour),
+			StoragePath:    t.TempDir(),
+			MaxRetries:     3,
+			InitialBackoff: config.Duration(time.Millisecond),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.retry.local", Type: "debian", Upstream: upstream.URL},
+		},
+	})
+	defer env.Close()
+
+	resp := env.DoRequest(t, "apt.retry.local", staleReleasePath)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "3600" || hits.Load() != 1 {
+		t.Fatalf("Retry-After beyond the limit should be passed through, got status=%d hits=%d", resp.StatusCode, hits.Load())
+	}
+}
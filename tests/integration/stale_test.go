@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+const staleReleasePath = "/dists/bookworm/Release"
+
+func TestStaleIfErrorServesCacheWhenUpstreamFails(t *testing.T) {
+	var down atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"r1"`)
+		if r.Header.Get("If-None-Match") == `"r1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("Release-body"))
+	}))
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.hub.local", Type: "debian", Upstream: upstream.URL, StaleIfError: config.Duration(time.Hour)},
+			{Name: "apt-strict", Domain: "strict.hub.local", Type: "debian", Upstream: upstream.URL},
+		},
+	}
+	app := newStrategyTestApp(t, cfg)
+
+	get := func(host string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://"+host+staleReleasePath, nil)
+		req.Host = host
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	for _, host := range []string{"apt.hub.local", "strict.hub.local"} {
+		if resp, _ := get(host); resp.StatusCode != fiber.StatusOK || resp.Header.Get("X-Any-Hub-Stale") != "" {
+			t.Fatalf("%s: expected fresh response while upstream is up, got %d", host, resp.StatusCode)
+		}
+	}
+
+	// 再验证返回 5xx：窗口内回放缓存，并带上陈旧标记。
+	down.Store(true)
+	resp, body := get("apt.hub.local")
+	if resp.StatusCode != fiber.StatusOK || body != "Release-body" {
+		t.Fatalf("expected stale cache on upstream 503, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Any-Hub-Stale") != "true" || resp.Header.Get("Warning") != `110 - "Response is Stale"` {
+		t.Fatalf("missing stale markers: %v", resp.Header)
+	}
+	if resp, _ := get("strict.hub.local"); resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("hub without StaleIfError should pass the upstream failure through, got %d", resp.StatusCode)
+	}
+
+	// 上游彻底不可达（连接被拒绝）同样回放。
+	upstream.Close()
+	resp, body = get("apt.hub.local")
+	if resp.StatusCode != fiber.StatusOK || body != "Release-body" || resp.Header.Get("X-Any-Hub-Stale") != "true" {
+		t.Fatalf("expected stale cache when upstream is unreachable, got %d %q", resp.StatusCode, body)
+	}
+	if resp, _ := get("strict.hub.local"); resp.StatusCode != fiber.StatusBadGateway {
+		t.Fatalf("expected upstream_failed without StaleIfError, got %d", resp.StatusCode)
+	}
+}
+
+func TestStaleIfErrorWindowExpires(t *testing.T) {
+	var down atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("ETag", `"r1"`)
+		_, _ = w.Write([]byte("Release-body"))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "apt", Domain: "apt.hub.local", Type: "debian", Upstream: upstream.URL, StaleIfError: config.Duration(50 * time.Millisecond)},
+		},
+	}
+	app := newStrategyTestApp(t, cfg)
+	get := func() *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "http://apt.hub.local"+staleReleasePath, nil)
+		req.Host = "apt.hub.local"
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := get(); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected initial fetch to succeed, got %d", resp.StatusCode)
+	}
+	down.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if resp := get(); resp.StatusCode != fiber.StatusBadGateway || resp.Header.Get("X-Any-Hub-Stale") != "" {
+		t.Fatalf("entry older than StaleIfError must not be served, got %d", resp.StatusCode)
+	}
+}
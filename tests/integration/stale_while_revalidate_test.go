@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestStaleWhileRevalidateServesCacheAndRefreshesInBackground(t *testing.T) {
+	var (
+		version atomic.Int32
+		heads   atomic.Int32
+	)
+	version.Store(1)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+			// 再验证卡住时前台请求也不应等待。
+			<-release
+		}
+		v := version.Load()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v`+strconv.Itoa(int(v))+`"`)
+		w.Header().Set("Last-Modified", time.Date(2026, 1, int(v), 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"demo","v":` + strconv.Itoa(int(v)) + `}`))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5000,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: upstream.URL, StaleWhileRevalidate: config.Duration(time.Hour)},
+		},
+	}
+	app := newStrategyTestApp(t, cfg)
+
+	get := func() (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://npm.hub.local/demo", nil)
+		req.Host = "npm.hub.local"
+		resp, err := app.Test(req, fiber.TestConfig{Timeout: 2 * time.Second})
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	if resp, body := get(); resp.StatusCode != fiber.StatusOK || body != `{"name":"demo","v":1}` {
+		t.Fatalf("unexpected initial fetch: %d %q", resp.StatusCode, body)
+	}
+
+	version.Store(2)
+	for range 3 {
+		resp, body := get()
+		if resp.StatusCode != fiber.StatusOK || body != `{"name":"demo","v":1}` || resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" {
+			t.Fatalf("expected cached v1 while revalidating, got %d %q", resp.StatusCode, body)
+		}
+	}
+	// 三次命中共享同一个排队中的后台任务，上游只收到一次再验证。
+	waitFor(t, func() bool { return heads.Load() >= 1 })
+	if got := heads.Load(); got != 1 {
+		t.Fatalf("expected deduplicated background revalidation, got %d HEAD requests", got)
+	}
+	close(release)
+
+	waitFor(t, func() bool {
+		_, body := get()
+		return body == `{"name":"demo","v":2}`
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within 2s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,105 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestPyPIStreamingRewriteOfLargeSimpleJSON(t *testing.T) {
+	const files = 20000
+	var hits atomic.Int32
+	var broken atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+		w.Header().Set("ETag", `"big-1"`)
+		if strings.Contains(r.Header.Get("If-None-Match"), "big-1") {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		var body strings.Builder
+		body.WriteString(`{"meta":{"api-version":"1.1"},"name":"big","files":[`)
+		for i := range files {
+			if i > 0 {
+				body.WriteByte(',')
+			}
+			fmt.Fprintf(&body, `{"filename":"big-%d.whl","url":"https://files.pythonhosted.org/packages/big-%d.whl","hashes":{"sha256":"%064x"}}`, i, i, i)
+		}
+		if broken.Load() {
+			// 截断的 JSON：改写流以错误结束，不能被当作完整页面缓存。
+			_, _ = io.WriteString(w, body.String())
+			return
+		}
+		body.WriteString(`]}`)
+		_, _ = io.WriteString(w, body.String())
+	}))
+	defer upstream.Close()
+
+	app := newStrategyTestApp(t, &config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5900,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{Name: "pypi", Domain: "pypi.stream.local", Type: "pypi", Upstream: upstream.URL},
+		},
+	})
+
+	get := func(path string) (*http.Response, []byte) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "http://pypi.stream.local"+path, nil)
+		req.Host = "pypi.stream.local"
+		req.Header.Set("Accept", "application/vnd.pypi.simple.v1+json")
+		resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+		if err != nil {
+			t.Fatalf("app.Test error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, body
+	}
+
+	resp, body := get("/simple/big/")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var page struct {
+		Files []struct {
+			URL string `json:"url"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatalf("rewritten page is not valid JSON: %v", err)
+	}
+	if len(page.Files) != files {
+		t.Fatalf("expected %d files, got %d", files, len(page.Files))
+	}
+	if want := "https://pypi.stream.local/files/https/files.pythonhosted.org/packages/big-42.whl"; page.Files[42].URL != want {
+		t.Fatalf("expected rewritten url %s, got %s", want, page.Files[42].URL)
+	}
+
+	resp, cached := get("/simple/big/")
+	if resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" || string(cached) != string(body) {
+		t.Fatalf("expected rewritten page to be served from cache")
+	}
+
+	broken.Store(true)
+	before := hits.Load()
+	get("/simple/broken/")
+	get("/simple/broken/")
+	if got := hits.Load() - before; got != 2 {
+		t.Fatalf("truncated page must not be cached, expected 2 upstream hits, got %d", got)
+	}
+}
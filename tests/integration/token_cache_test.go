@@ -0,0 +1,180 @@
+package integration
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+func TestBearerTokenCacheReusesAndUpgradesScopes(t *testing.T) {
+	registry := newScopedRegistryStub(t, "ci-user", "ci-pass")
+	defer registry.Close()
+
+	env := newHookTestEnv(t, config.Config{
+		Global: config.GlobalConfig{
+			ListenPort:  5600,
+			CacheTTL:    config.Duration(time.Hour),
+			StoragePath: t.TempDir(),
+		},
+		Hubs: []config.HubConfig{
+			{
+				Name:     "docker",
+				Domain:   "docker.tokens.local",
+				Type:     "docker",
+				Upstream: registry.URL,
+				Username: "ci-user",
+				Password: "ci-pass",
+			},
+		},
+	})
+	defer env.Close()
+
+	fetch := func(path string) {
+		t.Helper()
+		resp := env.DoRequest(t, "docker.tokens.local", path)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: expected 200, got %d body=%s", path, resp.StatusCode, body)
+		}
+	}
+
+	// 首次请求经历 401 → 申请 token → 重试。
+	fetch("/v2/team/app/manifests/v1")
+	if registry.TokenRequests() != 1 || registry.Unauthorized() != 1 {
+		t.Fatalf("expected one challenge and one token request, got unauthorized=%d tokens=%d", registry.Unauthorized(), registry.TokenRequests())
+	}
+
+	// 同一仓库的后续请求直接携带缓存的 token，不再被质询。
+	fetch("/v2/team/app/manifests/v2")
+	if registry.TokenRequests() != 1 || registry.Unauthorized() != 1 {
+		t.Fatalf("cached token should be sent preemptively, got unauthorized=%d tokens=%d", registry.Unauthorized(), registry.TokenRequests())
+	}
+
+	// 需要额外仓库权限时按新质询申请覆盖全部 scope 的 token。
+	fetch("/v2/team/app/manifests/multi")
+	if registry.TokenRequests() != 2 {
+		t.Fatalf("scope upgrade should request a new token, got %d", registry.TokenRequests())
+	}
+	if scopes := registry.LastTokenScopes(); len(scopes) != 2 {
+		t.Fatalf("expected both scopes in the token request, got %v", scopes)
+	}
+
+	// 升级后的 token 覆盖 team/base，访问该仓库无需再次质询。
+	unauthorized := registry.Unauthorized()
+	fetch("/v2/team/base/manifests/v1")
+	if registry.TokenRequests() != 2 || registry.Unauthorized() != unauthorized {
+		t.Fatalf("upgraded token should cover team/base, got unauthorized=%d tokens=%d", registry.Unauthorized(), registry.TokenRequests())
+	}
+}
+
+// scopedRegistryStub 模拟按 scope 签发 token 的 registry：token 只能访问申请时列出的仓库。
+type scopedRegistryStub struct {
+	*httptest.Server
+	basic string
+
+	mu           sync.Mutex
+	issued       map[string][]string
+	tokenHits    int
+	unauthorized int
+	lastScopes   []string
+}
+
+func newScopedRegistryStub(t *testing.T, username, password string) *scopedRegistryStub {
+	t.Helper()
+	stub := &scopedRegistryStub{
+		basic:  "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
+		issued: make(map[string][]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", stub.handleToken)
+	mux.HandleFunc("/v2/", stub.handleManifest)
+	stub.Server = httptest.NewServer(mux)
+	return stub
+}
+
+func (s *scopedRegistryStub) handleToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != s.basic {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.tokenHits++
+	s.lastScopes = r.URL.Query()["scope"]
+	token := fmt.Sprintf("tok-%d", s.tokenHits)
+	s.issued[token] = s.lastScopes
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"token":      token,
+		"expires_in": 300,
+		"issued_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *scopedRegistryStub) handleManifest(w http.ResponseWriter, r *http.Request) {
+	repo, tag, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	required := []string{"repository:" + repo + ":pull"}
+	if tag == "multi" {
+		required = append(required, "repository:team/base:pull")
+	}
+
+	s.mu.Lock()
+	granted := s.issued[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	allowed := true
+	for _, scope := range required {
+		found := false
+		for _, g := range granted {
+			found = found || g == scope
+		}
+		allowed = allowed && found
+	}
+	if !allowed {
+		s.unauthorized++
+	}
+	s.mu.Unlock()
+
+	if !allowed {
+		w.Header().Set("Www-Authenticate", fmt.Sprintf(
+			`Bearer realm="%s/token",service="registry.test",scope="%s",error="insufficient_scope"`,
+			s.URL, strings.Join(required, " "),
+		))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", dockerManifestContentType)
+	_, _ = w.Write([]byte(`{"schemaVersion":2}`))
+}
+
+func (s *scopedRegistryStub) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenHits
+}
+
+func (s *scopedRegistryStub) Unauthorized() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unauthorized
+}
+
+func (s *scopedRegistryStub) LastTokenScopes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lastScopes...)
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/any-hub/any-hub/internal/config"
+)
+
+const npmAbbreviatedAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+
+func TestModuleVaryKeepsSeparateRepresentations(t *testing.T) {
+	var gets atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		abbreviated := strings.Contains(r.Header.Get("Accept"), "install-v1")
+		etag, body := `"full"`, `{"name":"demo","readme":"long"}`
+		if abbreviated {
+			etag, body = `"abbrev"`, `{"name":"demo"}`
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Vary", "Accept, Accept-Encoding")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+
+	app := newStrategyTestApp(t, &config.Config{
+		Global: config.GlobalConfig{ListenPort: 5000, CacheTTL: config.Duration(time.Hour), StoragePath: t.TempDir()},
+		Hubs:   []config.HubConfig{{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: upstream.URL}},
+	})
+	get := func(accept string) (*http.Response, string) {
+		return doVaryRequest(t, app, "npm.hub.local", "/demo", map[string]string{"Accept": accept})
+	}
+
+	for range 2 {
+		if _, body := get(npmAbbreviatedAccept); body != `{"name":"demo"}` {
+			t.Fatalf("abbreviated client got %q", body)
+		}
+		if _, body := get("application/json"); body != `{"name":"demo","readme":"long"}` {
+			t.Fatalf("full client got %q", body)
+		}
+	}
+	// Accept 中媒体类型顺序不同不影响变体键。
+	reordered := "*/*, application/json; q=0.8, application/vnd.npm.install-v1+json; q=1.0"
+	resp, body := get(reordered)
+	if body != `{"name":"demo"}` || resp.Header.Get("X-Any-Hub-Cache-Hit") != "true" {
+		t.Fatalf("reordered Accept should hit the abbreviated variant, got %q hit=%s", body, resp.Header.Get("X-Any-Hub-Cache-Hit"))
+	}
+	if resp.Header.Get("Vary") != "Accept, Accept-Encoding" {
+		t.Fatalf("cache hit should replay upstream Vary, got %q", resp.Header.Get("Vary"))
+	}
+	if got := gets.Load(); got != 2 {
+		t.Fatalf("expected one upstream GET per variant, got %d", got)
+	}
+}
+
+func TestCacheHitHonorsUpstreamVary(t *testing.T) {
+	var gets atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets.Add(1)
+		if strings.HasSuffix(r.URL.Path, "wild-1.0.0.tgz") {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = w.Write([]byte("tarball-" + r.Header.Get("Accept-Language")))
+	}))
+	defer upstream.Close()
+
+	app := newStrategyTestApp(t, &config.Config{
+		Global: config.GlobalConfig{ListenPort: 5000, CacheTTL: config.Duration(time.Hour), StoragePath: t.TempDir()},
+		Hubs:   []config.HubConfig{{Name: "npm", Domain: "npm.hub.local", Type: "npm", Upstream: upstream.URL}},
+	})
+	get := func(path, lang string) string {
+		_, body := doVaryRequest(t, app, "npm.hub.local", path, map[string]string{"Accept-Language": lang})
+		return body
+	}
+
+	const tarball = "/demo/-/demo-1.0.0.tgz"
+	if get(tarball, "en") != "tarball-en" || get(tarball, "en") != "tarball-en" {
+		t.Fatalf("unexpected body for en")
+	}
+	if got := gets.Load(); got != 1 {
+		t.Fatalf("matching Vary header should hit the cache, got %d upstream calls", got)
+	}
+	if body := get(tarball, "fr"); body != "tarball-fr" {
+		t.Fatalf("different Vary header value must not reuse the cached body, got %q", body)
+	}
+	if got := gets.Load(); got != 2 {
+		t.Fatalf("expected refetch for a different Accept-Language, got %d upstream calls", got)
+	}
+
+	gets.Store(0)
+	get("/wild/-/wild-1.0.0.tgz", "en")
+	get("/wild/-/wild-1.0.0.tgz", "en")
+	if got := gets.Load(); got != 2 {
+		t.Fatalf("Vary: * responses must not be cached, got %d upstream calls", got)
+	}
+}
+
+func doVaryRequest(t *testing.T, app *fiber.App, host, path string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "http://"+host+path, nil)
+	req.Host = host
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, string(body)
+}